@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bufio"
+	"dat320/lab5/paging"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	memSize   = flag.Int("mem", 256, "Size of the simulated memory in bytes. Must be a multiple of the frame size.")
+	frameSize = flag.Int("frame", 16, "Size of each physical frame in bytes. Must be a power of two.")
+	traceFile = flag.String("trace", "", "If set, every MMU operation of the session is recorded to this trace file.")
+	restore   = flag.String("restore", "", "If set, the MMU is restored from this snapshot file instead of being created empty. Overrides -mem and -frame.")
+)
+
+var (
+	errInvalidParams = errors.New("invalid parameter values provided - see 'help' for usage")
+	errNoProcess     = errors.New("no such process - create it with 'spawn <pid>' first")
+	errNegativeParam = errors.New("integer parameters must be positive")
+)
+
+var inspectorCliDoc = map[string]string{
+	"help": `With this CLI you can create processes on a simulated MMU and inspect how their memory is laid out.
+Virtual addresses and sizes are given in bytes, and may be written in decimal or hex (0x...).
+The following shorthand function names are available: help (h), spawn (s), malloc (m), hugemalloc (hm), free (f), write (w), read (r), kill (k), pagetable (pt), freelist (fl), frame (fr), usage (u), snapshot (ss).
+The following operations are supported:
+	help                      displays this prompt.`,
+	"spawn":      "\tspawn <pid>               creates process 'pid'. It has no memory until it calls malloc or write.",
+	"malloc":     "\tmalloc <pid> <n>          allocates 'n' bytes to process 'pid'.",
+	"hugemalloc": "\thugemalloc <pid> <n>      allocates 'n' bytes to process 'pid' using huge pages.",
+	"free":       "\tfree <pid> <n>            frees the last 'n' pages of process 'pid'.",
+	"write":      "\twrite <pid> <addr> <rest> writes 'rest' to the address space of process 'pid' starting at virtual address 'addr'.",
+	"read":       "\tread <pid> <addr> <n>     reads 'n' bytes from process 'pid' starting at virtual address 'addr' and prints them as a hex dump.",
+	"kill":       "\tkill <pid>                exits process 'pid' and returns all of its memory to the MMU.",
+	"pagetable":  "\tpagetable [pid]           prints the page table of process 'pid', or of all processes if no pid is given.",
+	"freelist":   "\tfreelist                  prints the free list.",
+	"frame":      "\tframe <i> [count]         prints the content of 'count' (default 1) physical frames starting at frame 'i' as a hex dump.",
+	"usage":      "\tusage                     prints the memory usage of each process, which process owns each frame and TLB statistics.",
+	"snapshot":   "\tsnapshot <file>           saves the complete state of the MMU to 'file'. Load it again with the -restore flag.",
+	"quit":       "\tquit                      stops processing commands. Synonyms: q, exit, stop",
+}
+
+func generateCLIDocumentation() string {
+	return fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n",
+		inspectorCliDoc["help"],
+		inspectorCliDoc["spawn"],
+		inspectorCliDoc["malloc"],
+		inspectorCliDoc["hugemalloc"],
+		inspectorCliDoc["free"],
+		inspectorCliDoc["write"],
+		inspectorCliDoc["read"],
+		inspectorCliDoc["kill"],
+		inspectorCliDoc["pagetable"],
+		inspectorCliDoc["freelist"],
+		inspectorCliDoc["frame"],
+		inspectorCliDoc["usage"],
+		inspectorCliDoc["snapshot"],
+		inspectorCliDoc["quit"])
+}
+
+// inspector keeps the MMU and the processes created during the session
+type inspector struct {
+	mmu       *paging.MMU
+	processes map[int]*paging.Process
+}
+
+// process returns the process with the pid given as a string
+func (in *inspector) process(pidStr string) (*paging.Process, error) {
+	pid, err := parseParameter("pid", pidStr)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := in.processes[pid]
+	if !ok {
+		return nil, errNoProcess
+	}
+	return p, nil
+}
+
+// commandProcessor takes user input to perform operations on the MMU
+func (in *inspector) commandProcessor() {
+	helpStr := generateCLIDocumentation()
+	fmt.Print(helpStr)
+
+	// helper function for determining if enough parameters were provided
+	missing := func(params []string, min int) bool {
+		if len(params) < min {
+			fmt.Println("Not enough parameters provided to perform the operation. See 'help' for usage.")
+			return true
+		}
+		return false
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	// print the prompt before the first scan
+	fmt.Print("> ")
+	// repeatedly perform operations on the MMU until the user quits
+	for scanner.Scan() {
+		// split the string into space-separated params
+		params := strings.Fields(scanner.Text())
+		if len(params) == 0 {
+			fmt.Print("> ")
+			continue
+		}
+
+		var err error
+		switch params[0] {
+		case "h", "help":
+			fmt.Print(helpStr)
+
+		case "spawn", "s":
+			if missing(params, 2) {
+				break
+			}
+			err = in.handleSpawn(params[1])
+
+		case "malloc", "m":
+			if missing(params, 3) {
+				break
+			}
+			err = in.handleMalloc(params[1], params[2], false)
+
+		case "hugemalloc", "hm":
+			if missing(params, 3) {
+				break
+			}
+			err = in.handleMalloc(params[1], params[2], true)
+
+		case "free", "f":
+			if missing(params, 3) {
+				break
+			}
+			err = in.handleFree(params[1], params[2])
+
+		case "write", "w":
+			if missing(params, 4) {
+				break
+			}
+			err = in.handleWrite(params[1], params[2], params[3:]...)
+
+		case "read", "r":
+			if missing(params, 4) {
+				break
+			}
+			err = in.handleRead(params[1], params[2], params[3])
+
+		case "kill", "k":
+			if missing(params, 2) {
+				break
+			}
+			err = in.handleKill(params[1])
+
+		case "pagetable", "pt":
+			var pidStr string
+			if len(params) > 1 {
+				pidStr = params[1]
+			}
+			err = in.handlePageTable(pidStr)
+
+		case "freelist", "fl":
+			in.handleFreeList()
+
+		case "frame", "fr":
+			if missing(params, 2) {
+				break
+			}
+			count := "1"
+			if len(params) > 2 {
+				count = params[2]
+			}
+			err = in.handleFrame(params[1], count)
+
+		case "usage", "u":
+			fmt.Print(in.mmu.Usage())
+
+		case "snapshot", "ss":
+			if missing(params, 2) {
+				break
+			}
+			err = in.handleSnapshot(params[1])
+
+		case "quit", "q", "exit", "stop":
+			fmt.Println("Quitting inspector...")
+			return
+
+		default:
+			fmt.Println("Invalid operation. See 'help' for usage.")
+		}
+
+		if err != nil {
+			fmt.Println(err)
+		}
+		// print the prompt
+		fmt.Print("> ")
+	}
+}
+
+func (in *inspector) handleSpawn(pidStr string) error {
+	pid, err := parseParameter("pid", pidStr)
+	if err != nil {
+		return err
+	}
+	if _, ok := in.processes[pid]; ok {
+		return fmt.Errorf("process %d already exists", pid)
+	}
+	in.processes[pid] = paging.NewProcess(pid, in.mmu)
+	fmt.Printf("spawned process %d\n", pid)
+	return nil
+}
+
+func (in *inspector) handleMalloc(pidStr, nStr string, huge bool) error {
+	p, err := in.process(pidStr)
+	if err != nil {
+		return err
+	}
+	n, err := parseParameter("n", nStr)
+	if err != nil {
+		return err
+	}
+	if huge {
+		err = p.MallocHuge(n)
+	} else {
+		err = p.Malloc(n)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to allocate: %w", err)
+	}
+	return in.printPageTable(p.PID())
+}
+
+func (in *inspector) handleFree(pidStr, nStr string) error {
+	p, err := in.process(pidStr)
+	if err != nil {
+		return err
+	}
+	n, err := parseParameter("n", nStr)
+	if err != nil {
+		return err
+	}
+	if err := p.Free(n); err != nil {
+		return fmt.Errorf("failed to free: %w", err)
+	}
+	return in.printPageTable(p.PID())
+}
+
+func (in *inspector) handleWrite(pidStr, addrStr string, text ...string) error {
+	p, err := in.process(pidStr)
+	if err != nil {
+		return err
+	}
+	addr, err := parseParameter("addr", addrStr)
+	if err != nil {
+		return err
+	}
+	content := []byte(strings.Join(text, " "))
+	if err := p.Write(addr, content); err != nil {
+		return fmt.Errorf("failed to write: %w", err)
+	}
+	fmt.Printf("wrote %d bytes\n", len(content))
+	return nil
+}
+
+func (in *inspector) handleRead(pidStr, addrStr, nStr string) error {
+	p, err := in.process(pidStr)
+	if err != nil {
+		return err
+	}
+	addr, err := parseParameter("addr", addrStr)
+	if err != nil {
+		return err
+	}
+	n, err := parseParameter("n", nStr)
+	if err != nil {
+		return err
+	}
+	content, err := p.Read(addr, n)
+	if err != nil {
+		return fmt.Errorf("failed to read: %w", err)
+	}
+	printHexDump(content, addr)
+	return nil
+}
+
+func (in *inspector) handleKill(pidStr string) error {
+	p, err := in.process(pidStr)
+	if err != nil {
+		return err
+	}
+	// a process that never allocated memory is unknown to the MMU, which is fine to kill
+	if err := p.Exit(); err != nil && in.hasMemory(p.PID()) {
+		return fmt.Errorf("failed to exit: %w", err)
+	}
+	delete(in.processes, p.PID())
+	fmt.Printf("process %d exited\n", p.PID())
+	return nil
+}
+
+func (in *inspector) handlePageTable(pidStr string) error {
+	if pidStr != "" {
+		p, err := in.process(pidStr)
+		if err != nil {
+			return err
+		}
+		return in.printPageTable(p.PID())
+	}
+	for _, pid := range in.mmu.PIDs() {
+		if err := in.printPageTable(pid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *inspector) handleFreeList() {
+	freeList := in.mmu.FreeList()
+	free := 0
+	for i, isFree := range freeList {
+		state := "BUSY"
+		if isFree {
+			state = "FREE"
+			free++
+		}
+		fmt.Printf("frame %4d  0x%06x  %s\n", i, i*in.mmu.FrameSize(), state)
+	}
+	fmt.Printf("%d of %d frames free\n", free, len(freeList))
+}
+
+func (in *inspector) handleFrame(iStr, countStr string) error {
+	first, err := parseParameter("i", iStr)
+	if err != nil {
+		return err
+	}
+	count, err := parseParameter("count", countStr)
+	if err != nil {
+		return err
+	}
+	owners := in.mmu.FrameOwners()
+	for i := first; i < first+count && i < in.mmu.NumFrames(); i++ {
+		content, err := in.mmu.FrameContent(i)
+		if err != nil {
+			return err
+		}
+		owner := "free"
+		if owners[i] != paging.NoEntry {
+			owner = fmt.Sprintf("pid %d", owners[i])
+		}
+		fmt.Printf("frame %d (%s):\n", i, owner)
+		printHexDump(content, i*in.mmu.FrameSize())
+	}
+	return nil
+}
+
+func (in *inspector) handleSnapshot(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %w", err)
+	}
+	if err := in.mmu.Snapshot(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %w", err)
+	}
+	fmt.Printf("saved snapshot to %s\n", path)
+	return nil
+}
+
+// printPageTable prints the translations of process pid from virtual page to physical frame
+func (in *inspector) printPageTable(pid int) error {
+	frames, err := in.mmu.FramesOf(pid)
+	if err != nil {
+		if _, ok := in.processes[pid]; ok {
+			fmt.Printf("process %d has no memory\n", pid)
+			return nil
+		}
+		return err
+	}
+	fmt.Printf("page table of process %d (%d pages, %d bytes):\n", pid, len(frames), len(frames)*in.mmu.FrameSize())
+	for vpn, frame := range frames {
+		fmt.Printf("\tvpn %4d  0x%06x -> frame %4d  0x%06x\n", vpn, vpn*in.mmu.FrameSize(), frame, frame*in.mmu.FrameSize())
+	}
+	return nil
+}
+
+// hasMemory reports whether process pid is known to the MMU
+func (in *inspector) hasMemory(pid int) bool {
+	_, err := in.mmu.FramesOf(pid)
+	return err == nil
+}
+
+// printHexDump prints b in the format of 'hexdump -C', with offsets starting at base
+func printHexDump(b []byte, base int) {
+	for _, line := range strings.SplitAfter(hex.Dump(b), "\n") {
+		if len(line) < 8 {
+			fmt.Print(line)
+			continue
+		}
+		offset, err := strconv.ParseInt(line[:8], 16, 64)
+		if err != nil {
+			fmt.Print(line)
+			continue
+		}
+		fmt.Printf("%08x%s", int(offset)+base, line[8:])
+	}
+}
+
+// parseParameter parses `val` to int, accepting both decimal and hex (0x...) values.
+// Negative `val` and conversion failure causes errors.
+func parseParameter(parName, val string) (value int, err error) {
+	if val == "" {
+		return 0, errInvalidParams
+	}
+
+	v, err := strconv.ParseInt(val, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert the '%v' parameter: %w", parName, err)
+	}
+
+	if v < 0 {
+		return 0, errNegativeParam
+	}
+
+	return int(v), nil
+}
+
+func main() {
+	flag.Parse()
+
+	if *frameSize < 1 || *frameSize&(*frameSize-1) != 0 {
+		log.Fatalf("frame size must be a power of two, got %d", *frameSize)
+	}
+	if *memSize < *frameSize || *memSize%*frameSize != 0 {
+		log.Fatalf("memory size must be a positive multiple of the frame size %d, got %d", *frameSize, *memSize)
+	}
+
+	in := &inspector{processes: make(map[int]*paging.Process)}
+	if *restore != "" {
+		f, err := os.Open(*restore)
+		if err != nil {
+			log.Fatalf("failed to open snapshot file: %v", err)
+		}
+		in.mmu, err = paging.RestoreMMU(f)
+		f.Close()
+		if err != nil {
+			log.Fatalf("failed to restore snapshot: %v", err)
+		}
+		for _, pid := range in.mmu.PIDs() {
+			in.processes[pid] = paging.NewProcess(pid, in.mmu)
+		}
+		fmt.Printf("Restored MMU with %d frames of %d bytes and %d processes from %s.\n",
+			in.mmu.NumFrames(), in.mmu.FrameSize(), len(in.processes), *restore)
+	} else {
+		in.mmu = paging.NewMMU(*memSize, *frameSize)
+		fmt.Printf("Created MMU with %d frames of %d bytes.\n", *memSize / *frameSize, *frameSize)
+	}
+
+	if *traceFile != "" {
+		f, err := os.Create(*traceFile)
+		if err != nil {
+			log.Fatalf("failed to create trace file: %v", err)
+		}
+		defer f.Close()
+		tw, err := paging.NewTraceWriter(f, in.mmu.TraceHeader())
+		if err != nil {
+			log.Fatalf("failed to start trace: %v", err)
+		}
+		in.mmu.SetTracer(tw)
+		defer func() {
+			if err := tw.Flush(); err != nil {
+				log.Printf("failed to write trace: %v", err)
+			}
+		}()
+	}
+
+	// accept user input and translate it into MMU operations
+	in.commandProcessor()
+}
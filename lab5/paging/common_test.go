@@ -113,7 +113,7 @@ var AllocTests = []struct {
 	},
 	{
 		in:        1,
-		pageTable: &PageTable{[]int{0}},
+		pageTable: &PageTable{frameIndices: []int{0}},
 		err:       nil,
 		freeList:  []bool{true},
 		memSize:   1, frameSize: 1,
@@ -121,7 +121,7 @@ var AllocTests = []struct {
 	},
 	{
 		in:        1,
-		pageTable: &PageTable{[]int{1}},
+		pageTable: &PageTable{frameIndices: []int{1}},
 		err:       nil,
 		freeList:  []bool{false, true},
 		memSize:   2, frameSize: 1,
@@ -129,7 +129,7 @@ var AllocTests = []struct {
 	},
 	{
 		in:        1,
-		pageTable: &PageTable{[]int{0}},
+		pageTable: &PageTable{frameIndices: []int{0}},
 		err:       nil,
 		freeList:  []bool{true, true},
 		memSize:   2, frameSize: 1,
@@ -137,7 +137,7 @@ var AllocTests = []struct {
 	},
 	{
 		in:        2,
-		pageTable: &PageTable{[]int{0, 1}},
+		pageTable: &PageTable{frameIndices: []int{0, 1}},
 		err:       nil,
 		freeList:  []bool{true, true},
 		memSize:   2, frameSize: 1,
@@ -153,7 +153,7 @@ var AllocTests = []struct {
 	},
 	{
 		in:        4,
-		pageTable: &PageTable{[]int{0, 1}},
+		pageTable: &PageTable{frameIndices: []int{0, 1}},
 		err:       nil,
 		freeList:  []bool{true, true},
 		memSize:   4, frameSize: 2,
@@ -161,7 +161,7 @@ var AllocTests = []struct {
 	},
 	{
 		in:        3,
-		pageTable: &PageTable{[]int{1, 2}},
+		pageTable: &PageTable{frameIndices: []int{1, 2}},
 		err:       nil,
 		freeList:  []bool{false, true, true},
 		memSize:   6, frameSize: 2,
@@ -169,7 +169,7 @@ var AllocTests = []struct {
 	},
 	{
 		in:        5,
-		pageTable: &PageTable{[]int{0, 1, 2}},
+		pageTable: &PageTable{frameIndices: []int{0, 1, 2}},
 		err:       nil,
 		freeList:  []bool{true, true, true},
 		memSize:   6, frameSize: 2,
@@ -177,7 +177,7 @@ var AllocTests = []struct {
 	},
 	{
 		in:        6,
-		pageTable: &PageTable{[]int{0, 1, 2}},
+		pageTable: &PageTable{frameIndices: []int{0, 1, 2}},
 		err:       nil,
 		freeList:  []bool{true, true, true},
 		memSize:   6, frameSize: 2,
@@ -185,7 +185,7 @@ var AllocTests = []struct {
 	},
 	{
 		in:        16,
-		pageTable: &PageTable{[]int{1, 2, 4, 5}},
+		pageTable: &PageTable{frameIndices: []int{1, 2, 4, 5}},
 		err:       nil,
 		freeList:  []bool{false, true, true, false, true, true, false, true},
 		memSize:   32, frameSize: 4,
@@ -193,7 +193,7 @@ var AllocTests = []struct {
 	},
 	{
 		in:        17,
-		pageTable: &PageTable{[]int{1, 2, 4, 5, 7}},
+		pageTable: &PageTable{frameIndices: []int{1, 2, 4, 5, 7}},
 		err:       nil,
 		freeList:  []bool{false, true, true, false, true, true, false, true},
 		memSize:   32, frameSize: 4,
@@ -232,7 +232,7 @@ var AllocMultipleTests = []struct {
 		operations: []TAllocMultipleOperation{
 			{
 				in: 1, pid: 0,
-				wantPageTable: &PageTable{[]int{0}},
+				wantPageTable: &PageTable{frameIndices: []int{0}},
 				wantFreeList:  []bool{false},
 				wantError:     nil,
 				desc:          "memory available -> allocate",
@@ -246,14 +246,14 @@ var AllocMultipleTests = []struct {
 		operations: []TAllocMultipleOperation{
 			{
 				in: 1, pid: 0,
-				wantPageTable: &PageTable{[]int{0}},
+				wantPageTable: &PageTable{frameIndices: []int{0}},
 				wantFreeList:  []bool{false},
 				wantError:     nil,
 				desc:          "valid allocation",
 			},
 			{
 				in: 1, pid: 0,
-				wantPageTable: &PageTable{[]int{0}},
+				wantPageTable: &PageTable{frameIndices: []int{0}},
 				wantFreeList:  []bool{false},
 				wantError:     errAllocNotEnoughFrames,
 				desc:          "out of memory -> error and no allocation",
@@ -267,14 +267,14 @@ var AllocMultipleTests = []struct {
 		operations: []TAllocMultipleOperation{
 			{
 				in: 1, pid: 0,
-				wantPageTable: &PageTable{[]int{0}},
+				wantPageTable: &PageTable{frameIndices: []int{0}},
 				wantFreeList:  []bool{false, true},
 				wantError:     nil,
 				desc:          "allocate several times -> add to page table",
 			},
 			{
 				in: 1, pid: 0,
-				wantPageTable: &PageTable{[]int{0, 1}},
+				wantPageTable: &PageTable{frameIndices: []int{0, 1}},
 				wantFreeList:  []bool{false, false},
 				wantError:     nil,
 				desc:          "allocate several times -> add to page table",
@@ -288,7 +288,7 @@ var AllocMultipleTests = []struct {
 		operations: []TAllocMultipleOperation{
 			{
 				in: 1, pid: 0,
-				wantPageTable: &PageTable{[]int{1}},
+				wantPageTable: &PageTable{frameIndices: []int{1}},
 				wantFreeList:  []bool{false, false},
 				wantError:     nil,
 				desc:          "allocate 2nd frame (1st frame is not free) -> page table points to 2nd frame correctly",
@@ -302,7 +302,7 @@ var AllocMultipleTests = []struct {
 		operations: []TAllocMultipleOperation{
 			{
 				in: 2, pid: 0,
-				wantPageTable: &PageTable{[]int{0, 1}},
+				wantPageTable: &PageTable{frameIndices: []int{0, 1}},
 				wantFreeList:  []bool{false, false},
 				wantError:     nil,
 				desc:          "allocate more than 1 frame",
@@ -316,7 +316,7 @@ var AllocMultipleTests = []struct {
 		operations: []TAllocMultipleOperation{
 			{
 				in: 2, pid: 0,
-				wantPageTable: &PageTable{[]int{1, 3}},
+				wantPageTable: &PageTable{frameIndices: []int{1, 3}},
 				wantFreeList:  []bool{false, false, false, false},
 				wantError:     nil,
 				desc:          "allocate 2 frames that are not contiguous in memory layout",
@@ -330,7 +330,7 @@ var AllocMultipleTests = []struct {
 		operations: []TAllocMultipleOperation{
 			{
 				in: 5, pid: 0,
-				wantPageTable: &PageTable{[]int{0}},
+				wantPageTable: &PageTable{frameIndices: []int{0}},
 				wantFreeList:  []bool{false},
 				wantError:     nil,
 				desc:          "round up requested bytes to nearest multiple of frame size",
@@ -344,7 +344,7 @@ var AllocMultipleTests = []struct {
 		operations: []TAllocMultipleOperation{
 			{
 				in: 9, pid: 0,
-				wantPageTable: &PageTable{[]int{0, 1}},
+				wantPageTable: &PageTable{frameIndices: []int{0, 1}},
 				wantFreeList:  []bool{false, false},
 				wantError:     nil,
 				desc:          "round up requested bytes to nearest multiple of frame size",
@@ -372,7 +372,7 @@ var AllocMultipleTests = []struct {
 		operations: []TAllocMultipleOperation{
 			{
 				in: 15, pid: 0,
-				wantPageTable: &PageTable{[]int{1, 3}},
+				wantPageTable: &PageTable{frameIndices: []int{1, 3}},
 				wantFreeList:  []bool{false, false, false, false},
 				wantError:     nil,
 				desc:          "allocate 2 frames that are not contiguous in memory layout",
@@ -386,56 +386,56 @@ var AllocMultipleTests = []struct {
 		operations: []TAllocMultipleOperation{ // Allocate to several processes in sequence
 			{
 				in: 12, pid: 0,
-				wantPageTable: &PageTable{[]int{0, 1}},
+				wantPageTable: &PageTable{frameIndices: []int{0, 1}},
 				wantFreeList:  []bool{false, false, true, true, true, true, true, true},
 				wantError:     nil,
 				desc:          "Allocate 2 frames to process 0",
 			},
 			{
 				in: 8, pid: 1,
-				wantPageTable: &PageTable{[]int{2}},
+				wantPageTable: &PageTable{frameIndices: []int{2}},
 				wantFreeList:  []bool{false, false, false, true, true, true, true, true},
 				wantError:     nil,
 				desc:          "Allocate 1 frame to process 1",
 			},
 			{
 				in: 1, pid: 2,
-				wantPageTable: &PageTable{[]int{3}},
+				wantPageTable: &PageTable{frameIndices: []int{3}},
 				wantFreeList:  []bool{false, false, false, false, true, true, true, true},
 				wantError:     nil,
 				desc:          "Allocate 1 frame to process 2",
 			},
 			{
 				in: 8, pid: 0,
-				wantPageTable: &PageTable{[]int{0, 1, 4}},
+				wantPageTable: &PageTable{frameIndices: []int{0, 1, 4}},
 				wantFreeList:  []bool{false, false, false, false, false, true, true, true},
 				wantError:     nil,
 				desc:          "Allocate an additional frame to process 0. The frame is not contiguous in memory to the rest of process 0's address space.",
 			},
 			{
 				in: 32, pid: 1,
-				wantPageTable: &PageTable{[]int{2}},
+				wantPageTable: &PageTable{frameIndices: []int{2}},
 				wantFreeList:  []bool{false, false, false, false, false, true, true, true},
 				wantError:     errAllocNotEnoughFrames,
 				desc:          "Process 1 tries to allocate 32 bytes (4 frames) when only 24 bytes (3 frames) are available -> error",
 			},
 			{
 				in: 16, pid: 1,
-				wantPageTable: &PageTable{[]int{2, 5, 6}},
+				wantPageTable: &PageTable{frameIndices: []int{2, 5, 6}},
 				wantFreeList:  []bool{false, false, false, false, false, false, false, true},
 				wantError:     nil,
 				desc:          "Allocate an additional frame to process 1",
 			},
 			{
 				in: 8, pid: 2,
-				wantPageTable: &PageTable{[]int{3, 7}},
+				wantPageTable: &PageTable{frameIndices: []int{3, 7}},
 				wantFreeList:  []bool{false, false, false, false, false, false, false, false},
 				wantError:     nil,
 				desc:          "Allocate the final frame to process 2",
 			},
 			{
 				in: 2, pid: 0,
-				wantPageTable: &PageTable{[]int{0, 1, 4}},
+				wantPageTable: &PageTable{frameIndices: []int{0, 1, 4}},
 				wantFreeList:  []bool{false, false, false, false, false, false, false, false},
 				wantError:     errAllocNotEnoughFrames,
 				desc:          "Process 1 tries to allocate 2 bytes (rounded up to 1 frame) when no more memory is available -> error",
@@ -477,7 +477,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x4, n: 1,
-		pageTable: &PageTable{[]int{0}},
+		pageTable: &PageTable{frameIndices: []int{0}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
@@ -489,7 +489,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x3, n: 2,
-		pageTable: &PageTable{[]int{0}},
+		pageTable: &PageTable{frameIndices: []int{0}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
@@ -501,7 +501,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x0, n: 5,
-		pageTable: &PageTable{[]int{0}},
+		pageTable: &PageTable{frameIndices: []int{0}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
@@ -513,7 +513,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x0, n: 1,
-		pageTable: &PageTable{[]int{0}},
+		pageTable: &PageTable{frameIndices: []int{0}},
 		frames: [][]byte{
 			{1, 0, 0, 0},
 			{0, 0, 0, 0},
@@ -525,7 +525,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x0, n: 1,
-		pageTable: &PageTable{[]int{1}},
+		pageTable: &PageTable{frameIndices: []int{1}},
 		frames: [][]byte{
 			{1, 0, 0, 0},
 			{2, 0, 0, 0},
@@ -537,7 +537,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x0, n: 2,
-		pageTable: &PageTable{[]int{1}},
+		pageTable: &PageTable{frameIndices: []int{1}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{1, 2, 0, 0},
@@ -549,7 +549,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x0, n: 8,
-		pageTable: &PageTable{[]int{0, 1}},
+		pageTable: &PageTable{frameIndices: []int{0, 1}},
 		frames: [][]byte{
 			{1, 0, 0, 2},
 			{3, 0, 0, 4},
@@ -561,7 +561,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x3, n: 2,
-		pageTable: &PageTable{[]int{0, 1}},
+		pageTable: &PageTable{frameIndices: []int{0, 1}},
 		frames: [][]byte{
 			{1, 0, 0, 2},
 			{3, 0, 0, 4},
@@ -573,7 +573,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x3, n: 2,
-		pageTable: &PageTable{[]int{1, 0}},
+		pageTable: &PageTable{frameIndices: []int{1, 0}},
 		frames: [][]byte{
 			{1, 0, 0, 2},
 			{3, 0, 0, 4},
@@ -585,7 +585,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x0, n: 4,
-		pageTable: &PageTable{[]int{1, 7, 3, 5}},
+		pageTable: &PageTable{frameIndices: []int{1, 7, 3, 5}},
 		frames: [][]byte{
 			{0},
 			{1},
@@ -603,7 +603,7 @@ var ReadTests = []struct {
 	},
 	{
 		addr: 0x7, n: 5,
-		pageTable: &PageTable{[]int{2, 7, 0, 3, 4, 6}},
+		pageTable: &PageTable{frameIndices: []int{2, 7, 0, 3, 4, 6}},
 		frames: [][]byte{
 			{0, 0},
 			{0, 0},
@@ -653,11 +653,11 @@ var WriteTests = []struct {
 		name:          "write_byte",
 		content:       []byte{1},
 		addr:          0x00,
-		pageTable:     &PageTable{[]int{0}},
+		pageTable:     &PageTable{frameIndices: []int{0}},
 		frames:        [][]byte{{0}},
 		freeList:      []bool{false},
 		err:           nil,
-		wantPageTable: &PageTable{[]int{0}},
+		wantPageTable: &PageTable{frameIndices: []int{0}},
 		wantFrames:    [][]byte{{1}},
 		wantFreeList:  []bool{false},
 		memSize:       1, frameSize: 1,
@@ -667,11 +667,11 @@ var WriteTests = []struct {
 		name:          "write_2_bytes",
 		content:       []byte{1, 2},
 		addr:          0x00,
-		pageTable:     &PageTable{[]int{0, 1}},
+		pageTable:     &PageTable{frameIndices: []int{0, 1}},
 		frames:        [][]byte{{0}, {0}},
 		freeList:      []bool{false, false},
 		err:           nil,
-		wantPageTable: &PageTable{[]int{0, 1}},
+		wantPageTable: &PageTable{frameIndices: []int{0, 1}},
 		wantFrames:    [][]byte{{1}, {2}},
 		wantFreeList:  []bool{false, false},
 		memSize:       2, frameSize: 1,
@@ -681,11 +681,11 @@ var WriteTests = []struct {
 		name:          "write_2_bytes_alt",
 		content:       []byte{1, 2},
 		addr:          0x00,
-		pageTable:     &PageTable{[]int{1, 0}},
+		pageTable:     &PageTable{frameIndices: []int{1, 0}},
 		frames:        [][]byte{{0}, {0}},
 		freeList:      []bool{false, false},
 		err:           nil,
-		wantPageTable: &PageTable{[]int{1, 0}},
+		wantPageTable: &PageTable{frameIndices: []int{1, 0}},
 		wantFrames:    [][]byte{{2}, {1}},
 		wantFreeList:  []bool{false, false},
 		memSize:       2, frameSize: 1,
@@ -695,14 +695,14 @@ var WriteTests = []struct {
 		name:      "write_byte_larger_mem",
 		content:   []byte{1},
 		addr:      0x00,
-		pageTable: &PageTable{[]int{0}},
+		pageTable: &PageTable{frameIndices: []int{0}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
 		},
 		freeList:      []bool{false, true},
 		err:           nil,
-		wantPageTable: &PageTable{[]int{0}},
+		wantPageTable: &PageTable{frameIndices: []int{0}},
 		wantFrames: [][]byte{
 			{1, 0, 0, 0},
 			{0, 0, 0, 0},
@@ -715,14 +715,14 @@ var WriteTests = []struct {
 		name:      "write_byte_larger_mem_with_offset",
 		content:   []byte{1},
 		addr:      0x4,
-		pageTable: &PageTable{[]int{0, 1}},
+		pageTable: &PageTable{frameIndices: []int{0, 1}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
 		},
 		freeList:      []bool{false, false},
 		err:           nil,
-		wantPageTable: &PageTable{[]int{0, 1}},
+		wantPageTable: &PageTable{frameIndices: []int{0, 1}},
 		wantFrames: [][]byte{
 			{0, 0, 0, 0},
 			{1, 0, 0, 0},
@@ -735,14 +735,14 @@ var WriteTests = []struct {
 		name:      "write_byte_middle_of_frame",
 		content:   []byte{1},
 		addr:      0x5,
-		pageTable: &PageTable{[]int{0, 1}},
+		pageTable: &PageTable{frameIndices: []int{0, 1}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
 		},
 		freeList:      []bool{false, false},
 		err:           nil,
-		wantPageTable: &PageTable{[]int{0, 1}},
+		wantPageTable: &PageTable{frameIndices: []int{0, 1}},
 		wantFrames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 1, 0, 0},
@@ -755,14 +755,14 @@ var WriteTests = []struct {
 		name:      "write_byte_offset_frame",
 		content:   []byte{1},
 		addr:      0x6,
-		pageTable: &PageTable{[]int{1, 0}},
+		pageTable: &PageTable{frameIndices: []int{1, 0}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
 		},
 		freeList:      []bool{false, false},
 		err:           nil,
-		wantPageTable: &PageTable{[]int{1, 0}},
+		wantPageTable: &PageTable{frameIndices: []int{1, 0}},
 		wantFrames: [][]byte{
 			{0, 0, 1, 0},
 			{0, 0, 0, 0},
@@ -775,14 +775,14 @@ var WriteTests = []struct {
 		name:      "write_byte_offset",
 		content:   []byte{1},
 		addr:      0x0,
-		pageTable: &PageTable{[]int{1}},
+		pageTable: &PageTable{frameIndices: []int{1}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
 		},
 		freeList:      []bool{true, false},
 		err:           nil,
-		wantPageTable: &PageTable{[]int{1}},
+		wantPageTable: &PageTable{frameIndices: []int{1}},
 		wantFrames: [][]byte{
 			{0, 0, 0, 0},
 			{1, 0, 0, 0},
@@ -795,14 +795,14 @@ var WriteTests = []struct {
 		name:      "write_5_bytes_OOM",
 		content:   []byte{1, 2, 3, 4, 5},
 		addr:      0x0,
-		pageTable: &PageTable{[]int{1}},
+		pageTable: &PageTable{frameIndices: []int{1}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
 		},
 		freeList:      []bool{false, false},
 		err:           errAllocNotEnoughFrames,
-		wantPageTable: &PageTable{[]int{1}},
+		wantPageTable: &PageTable{frameIndices: []int{1}},
 		wantFrames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
@@ -815,14 +815,14 @@ var WriteTests = []struct {
 		name:      "write_5_bytes",
 		content:   []byte{1, 2, 3, 4, 5},
 		addr:      0x0,
-		pageTable: &PageTable{[]int{1}},
+		pageTable: &PageTable{frameIndices: []int{1}},
 		frames: [][]byte{
 			{0, 0, 0, 0},
 			{0, 0, 0, 0},
 		},
 		freeList:      []bool{true, false},
 		err:           nil,
-		wantPageTable: &PageTable{[]int{1, 0}},
+		wantPageTable: &PageTable{frameIndices: []int{1, 0}},
 		wantFrames: [][]byte{
 			{5, 0, 0, 0},
 			{1, 2, 3, 4},
@@ -835,7 +835,7 @@ var WriteTests = []struct {
 		name:      "write_8_bytes",
 		content:   []byte{1, 2, 3, 4, 5, 6, 7, 8},
 		addr:      0x5,
-		pageTable: &PageTable{[]int{4, 5, 2}},
+		pageTable: &PageTable{frameIndices: []int{4, 5, 2}},
 		frames: [][]byte{
 			{0, 0},
 			{0, 0},
@@ -848,7 +848,7 @@ var WriteTests = []struct {
 		},
 		freeList:      []bool{true, false, false, true, false, false, true, true},
 		err:           nil,
-		wantPageTable: &PageTable{[]int{4, 5, 2, 0, 3, 6, 7}},
+		wantPageTable: &PageTable{frameIndices: []int{4, 5, 2, 0, 3, 6, 7}},
 		wantFrames: [][]byte{
 			{2, 3},
 			{0, 0},
@@ -913,84 +913,84 @@ var FreeTests = []struct {
 	},
 	{
 		pid: 0, n: 1, memSize: 2, frameSize: 1,
-		processes:     map[int]*PageTable{0: {[]int{}}},
+		processes:     map[int]*PageTable{0: {frameIndices: []int{}}},
 		freeList:      []bool{true, true},
 		frames:        [][]byte{{1}, {0}},
 		err:           errFreeTooManyPages,
-		wantProcesses: map[int]*PageTable{0: {[]int{}}},
+		wantProcesses: map[int]*PageTable{0: {frameIndices: []int{}}},
 		wantFreeList:  []bool{true, true},
 		wantFrames:    [][]byte{{1}, {0}},
 		desc:          "process 0 tries to free 1 page, but has 0 allocated -> error",
 	},
 	{
 		pid: 0, n: 2, memSize: 2, frameSize: 1,
-		processes:     map[int]*PageTable{0: {[]int{0}}},
+		processes:     map[int]*PageTable{0: {frameIndices: []int{0}}},
 		freeList:      []bool{false, true},
 		frames:        [][]byte{{1}, {0}},
 		err:           errFreeTooManyPages,
-		wantProcesses: map[int]*PageTable{0: {[]int{0}}},
+		wantProcesses: map[int]*PageTable{0: {frameIndices: []int{0}}},
 		wantFreeList:  []bool{false, true},
 		wantFrames:    [][]byte{{1}, {0}},
 		desc:          "process 0 tries to free 2 pages, only has 1 allocated -> error",
 	},
 	{
 		pid: 0, n: 1, memSize: 2, frameSize: 1,
-		processes:     map[int]*PageTable{0: {[]int{0}}},
+		processes:     map[int]*PageTable{0: {frameIndices: []int{0}}},
 		freeList:      []bool{false, true},
 		frames:        [][]byte{{1}, {0}},
 		err:           nil,
-		wantProcesses: map[int]*PageTable{0: {[]int{}}},
+		wantProcesses: map[int]*PageTable{0: {frameIndices: []int{}}},
 		wantFreeList:  []bool{true, true},
 		wantFrames:    [][]byte{{0}, {0}},
 		desc:          "process 0 frees 1 page (-> frame 0) -> page table and free list updated, free memory set to 0",
 	},
 	{
 		pid: 0, n: 1, memSize: 2, frameSize: 1,
-		processes:     map[int]*PageTable{0: {[]int{1}}},
+		processes:     map[int]*PageTable{0: {frameIndices: []int{1}}},
 		freeList:      []bool{true, false},
 		frames:        [][]byte{{0}, {1}},
 		err:           nil,
-		wantProcesses: map[int]*PageTable{0: {[]int{}}},
+		wantProcesses: map[int]*PageTable{0: {frameIndices: []int{}}},
 		wantFreeList:  []bool{true, true},
 		wantFrames:    [][]byte{{0}, {0}},
 		desc:          "process 0 frees 1 page (-> frame 1) -> page table and free list updated, free memory set to 0",
 	},
 	{
 		pid: 0, n: 2, memSize: 2, frameSize: 1,
-		processes:     map[int]*PageTable{0: {[]int{0, 1}}},
+		processes:     map[int]*PageTable{0: {frameIndices: []int{0, 1}}},
 		freeList:      []bool{false, false},
 		frames:        [][]byte{{1}, {2}},
 		err:           nil,
-		wantProcesses: map[int]*PageTable{0: {[]int{}}},
+		wantProcesses: map[int]*PageTable{0: {frameIndices: []int{}}},
 		wantFreeList:  []bool{true, true},
 		wantFrames:    [][]byte{{0}, {0}},
 		desc:          "process 0 frees 2 pages (-> frames 0, 1) -> page table and free list updated, free memory set to 0",
 	},
 	{
 		pid: 0, n: 2, memSize: 2, frameSize: 1,
-		processes:     map[int]*PageTable{0: {[]int{1, 0}}},
+		processes:     map[int]*PageTable{0: {frameIndices: []int{1, 0}}},
 		freeList:      []bool{false, false},
 		frames:        [][]byte{{1}, {2}},
 		err:           nil,
-		wantProcesses: map[int]*PageTable{0: {[]int{}}},
+		wantProcesses: map[int]*PageTable{0: {frameIndices: []int{}}},
 		wantFreeList:  []bool{true, true},
 		wantFrames:    [][]byte{{0}, {0}},
 		desc:          "process 0 frees 2 pages (-> frames 1, 0) -> page table and free list updated, free memory set to 0",
 	},
 	{
 		pid: 0, n: 4, memSize: 8, frameSize: 1,
-		processes:     map[int]*PageTable{0: {[]int{0, 2, 4, 6}}},
+		processes:     map[int]*PageTable{0: {frameIndices: []int{0, 2, 4, 6}}},
 		freeList:      []bool{false, true, false, true, false, true, false, true},
 		frames:        [][]byte{{1}, {2}, {3}, {4}, {5}, {6}, {7}, {8}},
 		err:           nil,
-		wantProcesses: map[int]*PageTable{0: {[]int{}}},
+		wantProcesses: map[int]*PageTable{0: {frameIndices: []int{}}},
 		wantFreeList:  []bool{true, true, true, true, true, true, true, true},
 		wantFrames:    [][]byte{{0}, {2}, {0}, {4}, {0}, {6}, {0}, {8}},
 		desc:          "process 0 frees 4 pages (-> frames 0, 2, 4, 6) -> page table and free list updated, free memory set to 0",
 	},
 	{
 		pid: 1, n: 2, memSize: 16, frameSize: 2,
-		processes: map[int]*PageTable{0: {[]int{0, 2}}, 1: {[]int{5, 3, 1}}, 2: {[]int{4, 6}}},
+		processes: map[int]*PageTable{0: {frameIndices: []int{0, 2}}, 1: {frameIndices: []int{5, 3, 1}}, 2: {frameIndices: []int{4, 6}}},
 		freeList:  []bool{false, false, false, false, false, false, false, true},
 		frames: [][]byte{
 			{0, 1},
@@ -1003,7 +1003,7 @@ var FreeTests = []struct {
 			{0, 0},
 		},
 		err:           nil,
-		wantProcesses: map[int]*PageTable{0: {[]int{0, 2}}, 1: {[]int{5}}, 2: {[]int{4, 6}}},
+		wantProcesses: map[int]*PageTable{0: {frameIndices: []int{0, 2}}, 1: {frameIndices: []int{5}}, 2: {frameIndices: []int{4, 6}}},
 		wantFreeList:  []bool{false, true, false, true, false, false, false, true},
 		wantFrames: [][]byte{
 			{0, 1},
@@ -1019,7 +1019,7 @@ var FreeTests = []struct {
 	},
 	{
 		pid: 2, n: 3, memSize: 32, frameSize: 4,
-		processes: map[int]*PageTable{0: {[]int{0, 2}}, 1: {[]int{5, 3}}, 2: {[]int{4, 1, 6, 7}}},
+		processes: map[int]*PageTable{0: {frameIndices: []int{0, 2}}, 1: {frameIndices: []int{5, 3}}, 2: {frameIndices: []int{4, 1, 6, 7}}},
 		freeList:  []bool{false, false, false, false, false, false, false, false},
 		frames: [][]byte{
 			{0, 1, 2, 3},
@@ -1032,7 +1032,7 @@ var FreeTests = []struct {
 			{212, 213, 214, 215},
 		},
 		err:           nil,
-		wantProcesses: map[int]*PageTable{0: {[]int{0, 2}}, 1: {[]int{5, 3}}, 2: {[]int{4}}},
+		wantProcesses: map[int]*PageTable{0: {frameIndices: []int{0, 2}}, 1: {frameIndices: []int{5, 3}}, 2: {frameIndices: []int{4}}},
 		wantFreeList:  []bool{false, true, false, false, false, false, true, true},
 		wantFrames: [][]byte{
 			{0, 1, 2, 3},
@@ -1114,8 +1114,8 @@ var SequenceTests = []struct {
 		},
 		wantFreeList: []bool{false, false},
 		wantProcesses: map[int]*PageTable{
-			1: {[]int{0}},
-			2: {[]int{1}},
+			1: {frameIndices: []int{0}},
+			2: {frameIndices: []int{1}},
 		},
 	},
 	{
@@ -1142,9 +1142,9 @@ var SequenceTests = []struct {
 		},
 		wantFreeList: []bool{false, false, false, false, false, false, false, false},
 		wantProcesses: map[int]*PageTable{
-			1: {[]int{0, 1}},
-			2: {[]int{3, 4}},
-			3: {[]int{2, 5, 6, 7}},
+			1: {frameIndices: []int{0, 1}},
+			2: {frameIndices: []int{3, 4}},
+			3: {frameIndices: []int{2, 5, 6, 7}},
 		},
 	},
 	{
@@ -1172,8 +1172,8 @@ var SequenceTests = []struct {
 		},
 		wantFreeList: []bool{false, false, false, false, false, false, false, false},
 		wantProcesses: map[int]*PageTable{
-			1: {[]int{0, 1, 2}},
-			2: {[]int{3, 4, 5, 6, 7}},
+			1: {frameIndices: []int{0, 1, 2}},
+			2: {frameIndices: []int{3, 4, 5, 6, 7}},
 		},
 	},
 	{
@@ -1222,11 +1222,11 @@ var SequenceTests = []struct {
 		},
 		wantFreeList: []bool{false, false, false, false, false, false, false, false, false, false, false, false, false, false, false, false},
 		wantProcesses: map[int]*PageTable{
-			1: {[]int{0, 1, 3, 4}},
-			2: {[]int{2, 5, 9}},
-			3: {[]int{6, 7}},
-			4: {[]int{8}},
-			5: {[]int{12, 13, 14, 15, 10, 11}},
+			1: {frameIndices: []int{0, 1, 3, 4}},
+			2: {frameIndices: []int{2, 5, 9}},
+			3: {frameIndices: []int{6, 7}},
+			4: {frameIndices: []int{8}},
+			5: {frameIndices: []int{12, 13, 14, 15, 10, 11}},
 		},
 	},
 }
@@ -3,8 +3,10 @@ package paging
 import "fmt"
 
 type freeList struct {
-	freeList      []bool // tracks free physical frames
-	numFreeFrames int    // number of free frames
+	freeList      []bool      // tracks free physical frames
+	numFreeFrames int         // number of free frames
+	policy        FramePolicy // decides which free frames to allocate
+	next          int         // frame where the next NextFit scan starts
 }
 
 // newFreeList creates a free list with space for numFrames frames.
@@ -31,24 +33,18 @@ func (fl freeList) calculateNumFreeFrames() int {
 // removeFrames allocates memory by removing entries (indices) from the freeList.
 // That is, sets them to false in the freeList and decrements numFreeFrames.
 func (fl *freeList) removeFrames(entries []int) error {
-	// will operate on a copy of the free list until it is determined no
-	// errors occur, in order to "atomically" update it
-	freeList := make([]bool, len(fl.freeList))
-	copy(freeList, fl.freeList)
-
-	// check the validity of each entry to be removed and modify the free list copy accordingly
-	for _, entry := range entries {
-		if entry >= len(freeList) || entry < 0 {
+	// check the validity of each entry to be removed and modify the free list accordingly
+	for i, entry := range entries {
+		if entry >= len(fl.freeList) || entry < 0 {
+			fl.rollback(entries[:i], true)
 			return fmt.Errorf("failed to remove %d from free list: %w", entry, errIndexOutOfBounds)
 		}
 		if !fl.freeList[entry] {
+			fl.rollback(entries[:i], true)
 			return errFreeListDuplicateOp
 		}
-		freeList[entry] = false
+		fl.freeList[entry] = false
 	}
-
-	// finally update the MMU with the new state
-	fl.freeList = freeList
 	fl.numFreeFrames -= len(entries)
 	return nil
 }
@@ -56,24 +52,28 @@ func (fl *freeList) removeFrames(entries []int) error {
 // addFrames frees memory by adding entries (indices) to the freeList.
 // That is, sets them to true in the freeList and increments numFreeFrames.
 func (fl *freeList) addFrames(entries []int) error {
-	// will operate on a copy of the free list until it is determined no
-	// errors occur, in order to "atomically" update it
-	freeList := make([]bool, len(fl.freeList))
-	copy(freeList, fl.freeList)
-
-	// check the validity of each entry to be added and modify the free list copy accordingly
-	for _, entry := range entries {
+	// check the validity of each entry to be added and modify the free list accordingly
+	for i, entry := range entries {
 		if entry >= len(fl.freeList) || entry < 0 {
+			fl.rollback(entries[:i], false)
 			return fmt.Errorf("failed to add %d to free list: %w", entry, errIndexOutOfBounds)
 		}
 		if fl.freeList[entry] {
+			fl.rollback(entries[:i], false)
 			return errFreeListDuplicateOp
 		}
-		freeList[entry] = true
+		fl.freeList[entry] = true
 	}
-
-	// finally update the MMU with the new state
-	fl.freeList = freeList
 	fl.numFreeFrames += len(entries)
 	return nil
 }
+
+// rollback restores entries that were already updated before an invalid entry was found,
+// so that removeFrames and addFrames either update all entries or none of them.
+// The free list is only updated while holding the MMU's free list lock,
+// so no one can observe the partially updated state.
+func (fl *freeList) rollback(entries []int, free bool) {
+	for _, entry := range entries {
+		fl.freeList[entry] = free
+	}
+}
@@ -1,22 +1,79 @@
 package paging
 
+import "fmt"
+
+// FramePolicy decides which free frames are picked when memory is allocated.
+type FramePolicy int
+
+const (
+	// LowestFirst picks the free frames with the lowest physical frame numbers.
+	LowestFirst FramePolicy = iota
+	// NextFit continues scanning from the frame after the one most recently allocated,
+	// wrapping around to the start of memory.
+	NextFit
+)
+
+// String returns the name of the policy.
+func (p FramePolicy) String() string {
+	switch p {
+	case LowestFirst:
+		return "lowest-first"
+	case NextFit:
+		return "next-fit"
+	}
+	return "unknown"
+}
+
 // findFreeFrames returns indices for n free frames.
 // If there are not enough free frames available, an error is returned.
 func (fl *freeList) findFreeFrames(n int) ([]int, error) {
 	if n > fl.numFreeFrames {
 		return nil, errOutOfMemory
 	}
+	if n <= 0 {
+		return []int{}, nil
+	}
+
+	start := 0
+	if fl.policy == NextFit {
+		start = fl.next
+	}
 
 	freeFrames := []int{}
-	for i, entry := range fl.freeList {
-		if entry {
+	for j := range fl.freeList {
+		i := (start + j) % len(fl.freeList)
+		if fl.freeList[i] {
 			freeFrames = append(freeFrames, i)
 		}
 
 		if len(freeFrames) == n {
 			// We have all the frames we need so break the loop and return the frames
+			if fl.policy == NextFit {
+				fl.next = (i + 1) % len(fl.freeList)
+			}
 			break
 		}
 	}
 	return freeFrames, nil
 }
+
+// findFreeHugeFrames returns the first frame of n free huge pages, each made up of
+// hugePageFrames free frames that are contiguous and aligned to the huge page size.
+// Huge pages are always picked lowest first.
+// If there are not enough free huge pages available, an error is returned.
+func (fl *freeList) findFreeHugeFrames(n, hugePageFrames int) ([]int, error) {
+	firstFrames := []int{}
+	for first := 0; first+hugePageFrames <= len(fl.freeList) && len(firstFrames) < n; first += hugePageFrames {
+		free := true
+		for _, entry := range fl.freeList[first : first+hugePageFrames] {
+			free = free && entry
+		}
+		if free {
+			firstFrames = append(firstFrames, first)
+		}
+	}
+	if len(firstFrames) < n {
+		return nil, fmt.Errorf("%w: not enough free huge pages", errOutOfMemory)
+	}
+	return firstFrames, nil
+}
@@ -0,0 +1,225 @@
+package paging
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAllocHuge(t *testing.T) {
+	mmu := NewMMU(64*4, 4)
+	if err := mmu.Alloc(0, 4); err != nil {
+		t.Fatal(err)
+	}
+	// frame 0 is taken, so the first free huge page starts at frame 16,
+	// and the address space is padded with frames 1-15 to align the huge page
+	if err := mmu.AllocHuge(0, 20); err != nil {
+		t.Fatalf("AllocHuge() failed: %v", err)
+	}
+
+	var wantFrames []int
+	for i := 0; i < 32; i++ {
+		wantFrames = append(wantFrames, i)
+	}
+	frames, _ := mmu.FramesOf(0)
+	if diff := cmp.Diff(wantFrames, frames); diff != "" {
+		t.Errorf("Unexpected page table after AllocHuge(); (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[int]int{16: HugePageFrames}, mmu.processes[0].huge); diff != "" {
+		t.Errorf("Unexpected huge page mappings after AllocHuge(); (-want +got):\n%s", diff)
+	}
+	if usage := mmu.Usage(); usage.HugePages[0] != 1 {
+		t.Errorf("Usage() reports %d huge pages for process 0, want 1", usage.HugePages[0])
+	}
+
+	// a new process without any pages needs no padding
+	if err := mmu.AllocHuge(1, 1); err != nil {
+		t.Fatalf("AllocHuge() failed: %v", err)
+	}
+	frames, _ = mmu.FramesOf(1)
+	if len(frames) != HugePageFrames || frames[0] != 32 {
+		t.Errorf("AllocHuge() for a new process should map frames 32-47, got %v", frames)
+	}
+
+	// frames 48-63 are free, but process 1 would need padding in addition to the huge page
+	if err := mmu.Alloc(1, 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := mmu.AllocHuge(1, 1); !errors.Is(err, errOutOfMemory) {
+		t.Errorf("AllocHuge() without room for the padding: want '%v', got '%v'", errOutOfMemory, err)
+	}
+	if n := mmu.NumFreeFrames(); n != 15 {
+		t.Errorf("a failed AllocHuge() should leave the free list unchanged; want 15 free frames, got %d", n)
+	}
+}
+
+func TestAllocHugeFragmented(t *testing.T) {
+	mmu := NewMMU(64*4, 4)
+	freeList := make([]bool, 64)
+	for i := range freeList {
+		// one busy frame in every aligned run of frames
+		freeList[i] = i%HugePageFrames != 5
+	}
+	mmu.setFreeList(freeList)
+	if err := mmu.AllocHuge(0, 1); !errors.Is(err, errOutOfMemory) {
+		t.Errorf("AllocHuge() without an aligned run of free frames: want '%v', got '%v'", errOutOfMemory, err)
+	}
+	if _, ok := mmu.processes[0]; ok {
+		t.Errorf("a failed AllocHuge() should not give the process a page table")
+	}
+	// regular pages can still use the scattered free frames
+	if err := mmu.Alloc(0, 60*4); err != nil {
+		t.Errorf("Alloc() failed: %v", err)
+	}
+}
+
+func TestAllocHugeWithoutPadding(t *testing.T) {
+	mmu := NewMMU(64*4, 4)
+	freeList := make([]bool, 64)
+	for i := range freeList {
+		freeList[i] = i != 5
+	}
+	mmu.setFreeList(freeList)
+	// a new process needs no padding, so only the huge page at frames 16-31 is taken
+	if err := mmu.AllocHuge(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if frames, _ := mmu.FramesOf(0); len(frames) != HugePageFrames || frames[0] != 16 {
+		t.Errorf("AllocHuge() for a new process should map frames 16-31, got %v", frames)
+	}
+	if n := mmu.NumFreeFrames(); n != 64-1-HugePageFrames {
+		t.Errorf("AllocHuge() without padding left %d free frames, want %d", n, 64-1-HugePageFrames)
+	}
+}
+
+func TestHugePageTranslation(t *testing.T) {
+	mmu := NewMMU(64*8, 8)
+	if err := mmu.Alloc(0, 8*3); err != nil {
+		t.Fatal(err)
+	}
+	if err := mmu.AllocHuge(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	pageTable := mmu.processes[0]
+
+	for _, addr := range []int{0, 7, 8*3 + 5, 8 * 16, 8*16 + 1, 8*17 + 3, 8*32 - 1} {
+		vpn, offset, err := mmu.translateAndCheck(0, addr)
+		if err != nil {
+			t.Fatalf("translateAndCheck(%d) failed: %v", addr, err)
+		}
+		if vpn != addr/8 || offset != addr%8 {
+			t.Errorf("translateAndCheck(%d) = (%d, %d), want (%d, %d)", addr, vpn, offset, addr/8, addr%8)
+		}
+		if _, _, huge := pageTable.hugePage(vpn, HugePageFrames); huge != (addr >= 8*16) {
+			t.Errorf("address %d should be mapped by a huge page: %t", addr, addr >= 8*16)
+		}
+	}
+	if _, _, err := mmu.translateAndCheck(0, 8*32); err == nil {
+		t.Errorf("translateAndCheck() past the huge page should fail")
+	}
+
+	// content crossing from regular pages into the huge page reads back unchanged
+	content := bytes.Repeat([]byte("huge"), 50)
+	if err := mmu.Write(0, 8*10, content); err != nil {
+		t.Fatal(err)
+	}
+	got, err := mmu.Read(0, 8*10, len(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(content, got); diff != "" {
+		t.Errorf("Unexpected content across a huge page; (-want +got):\n%s", diff)
+	}
+}
+
+func TestFreeSplitsHugePage(t *testing.T) {
+	mmu := NewMMU(64*4, 4)
+	if err := mmu.AllocHuge(0, 2*HugePageFrames*4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mmu.Read(0, 0, 2*HugePageFrames*4); err != nil {
+		t.Fatal(err)
+	}
+	if err := mmu.Free(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(map[int]int{0: HugePageFrames}, mmu.processes[0].huge); diff != "" {
+		t.Errorf("the partly freed huge page should be split into regular pages; (-want +got):\n%s", diff)
+	}
+	if s := mmu.TLBStats(); s.Entries != 0 {
+		t.Errorf("Free() should flush the TLB entries of the process, got %d entries", s.Entries)
+	}
+	if _, err := mmu.Read(0, 0, (2*HugePageFrames-1)*4); err != nil {
+		t.Errorf("the remaining pages should still be readable: %v", err)
+	}
+}
+
+func TestTLBReach(t *testing.T) {
+	const frameSize, size = 16, 8 * HugePageFrames * 16
+	mmu := NewMMU(2*size, frameSize)
+	if err := mmu.Alloc(0, size); err != nil {
+		t.Fatal(err)
+	}
+	if err := mmu.AllocHuge(1, size); err != nil {
+		t.Fatal(err)
+	}
+
+	// reading all memory twice does not fit in the TLB with regular pages,
+	// but 8 huge pages fit, so only the first read misses
+	for pid := 0; pid < 2; pid++ {
+		before := mmu.TLBStats()
+		for i := 0; i < 2; i++ {
+			if _, err := mmu.Read(pid, 0, size); err != nil {
+				t.Fatal(err)
+			}
+		}
+		after := mmu.TLBStats()
+		misses := after.Misses - before.Misses
+		wantMisses := 2 * size / frameSize
+		if pid == 1 {
+			wantMisses = size / (frameSize * HugePageFrames)
+		}
+		if misses != wantMisses {
+			t.Errorf("process %d: %d TLB misses, want %d", pid, misses, wantMisses)
+		}
+	}
+
+	s := mmu.TLBStats()
+	if s.Entries != DefaultTLBEntries || s.Reach != 8*HugePageFrames*frameSize+8*frameSize {
+		t.Errorf("TLBStats() = %+v, want %d entries reaching %d bytes", s, DefaultTLBEntries, 8*HugePageFrames*frameSize+8*frameSize)
+	}
+}
+
+func TestReplayHugePages(t *testing.T) {
+	events, err := GenerateTrace(WorkloadConfig{Pattern: Random, Processes: 2, Size: 8 * 1024, Accesses: 1000, AccessSize: 64, WriteRatio: 0.3, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteTrace(&buf, TraceHeader{MemSize: 64 * 1024, FrameSize: 64}, events); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	var stats [2]TLBStats
+	for i, huge := range []bool{false, true} {
+		tr, err := NewTraceReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := Replay(tr, ReplayConfig{HugePages: huge})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(res.Failed) != 0 {
+			t.Errorf("Replay(%v) had failing events: %v", res.Config, res.Failed)
+		}
+		stats[i] = res.Usage.TLB
+	}
+	if stats[1].HitRate() < 0.95 || stats[0].HitRate() > 0.1 {
+		t.Errorf("huge pages should make the working set fit in the TLB; hit rate with regular pages %.2f, with huge pages %.2f",
+			stats[0].HitRate(), stats[1].HitRate())
+	}
+}
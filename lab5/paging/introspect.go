@@ -0,0 +1,196 @@
+package paging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryUsage is a point-in-time view of how the MMU's memory is used.
+type MemoryUsage struct {
+	FrameSize  int           // size of each frame in bytes
+	NumFrames  int           // total number of physical frames
+	FreeFrames int           // number of frames in the free list
+	Processes  map[int]int   // number of frames allocated to each process (key=pid)
+	Owners     []int         // pid owning each physical frame (index), or NoEntry if the frame is free
+	HugePages  map[int]int   // number of huge pages mapped by each process (key=pid), if any
+	TLB        TLBStats      // statistics of the translations done so far
+	Pressure   PressureLevel // current memory pressure level
+	OOMKills   int           // number of processes killed by the OOM killer so far
+}
+
+// UsedFrames returns the number of frames allocated to processes.
+func (u MemoryUsage) UsedFrames() int {
+	return u.NumFrames - u.FreeFrames
+}
+
+// String returns a table with the memory usage of each process, followed by a map of
+// the physical frames where each frame is shown as the pid that owns it or '.' if it is free.
+func (u MemoryUsage) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "frames: %d total, %d used, %d free (frame size %d bytes)\n",
+		u.NumFrames, u.UsedFrames(), u.FreeFrames, u.FrameSize)
+
+	pids := make([]int, 0, len(u.Processes))
+	for pid := range u.Processes {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	fmt.Fprintf(&sb, "%8s %8s %10s %10s\n", "PID", "FRAMES", "BYTES", "HUGEPAGES")
+	for _, pid := range pids {
+		fmt.Fprintf(&sb, "%8d %8d %10d %10d\n", pid, u.Processes[pid], u.Processes[pid]*u.FrameSize, u.HugePages[pid])
+	}
+	fmt.Fprintf(&sb, "%v\n", u.TLB)
+	fmt.Fprintf(&sb, "memory pressure: %v, %d processes killed by the OOM killer\n", u.Pressure, u.OOMKills)
+
+	sb.WriteString("frame map:")
+	for i, pid := range u.Owners {
+		if i%16 == 0 {
+			fmt.Fprintf(&sb, "\n%6d:", i)
+		}
+		if pid == NoEntry {
+			fmt.Fprintf(&sb, " %4s", ".")
+		} else {
+			fmt.Fprintf(&sb, " %4d", pid)
+		}
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// Usage returns a consistent view of the memory usage of the MMU. Allocations and exits
+// in progress are waited for, so that every frame is either free or used by a process.
+func (mmu *MMU) Usage() MemoryUsage {
+	mmu.moving.Lock()
+	defer mmu.moving.Unlock()
+	pageTables, unlock := mmu.lockAllPageTables()
+	defer unlock()
+	mmu.freeLock.Lock()
+	defer mmu.freeLock.Unlock()
+
+	usage := MemoryUsage{
+		FrameSize:  len(mmu.frames[0]),
+		NumFrames:  len(mmu.frames),
+		FreeFrames: mmu.numFreeFrames,
+		Processes:  make(map[int]int, len(pageTables)),
+		Owners:     make([]int, len(mmu.frames)),
+		HugePages:  make(map[int]int),
+		TLB:        mmu.TLBStats(),
+	}
+	mmu.pressure.mu.Lock()
+	usage.Pressure, usage.OOMKills = mmu.pressure.level, mmu.pressure.kills
+	mmu.pressure.mu.Unlock()
+	for i := range usage.Owners {
+		usage.Owners[i] = NoEntry
+	}
+	for pid, pageTable := range pageTables {
+		usage.Processes[pid] = pageTable.Len()
+		for _, frame := range pageTable.frameIndices {
+			usage.Owners[frame] = pid
+		}
+		if len(pageTable.huge) > 0 {
+			usage.HugePages[pid] = len(pageTable.huge)
+		}
+	}
+	return usage
+}
+
+// FramesOf returns the physical frames mapped by process pid, indexed by virtual page number.
+func (mmu *MMU) FramesOf(pid int) ([]int, error) {
+	pageTable, lock, err := mmu.lookupProcess(pid)
+	if err != nil {
+		return nil, err
+	}
+	lock.RLock()
+	defer lock.RUnlock()
+	frames := make([]int, pageTable.Len())
+	copy(frames, pageTable.frameIndices)
+	return frames, nil
+}
+
+// NumFreeFrames returns the number of frames that are not allocated to any process.
+func (mmu *MMU) NumFreeFrames() int {
+	mmu.freeLock.Lock()
+	defer mmu.freeLock.Unlock()
+	return mmu.numFreeFrames
+}
+
+// FrameOwners returns the pid owning each physical frame, or NoEntry if the frame is free.
+func (mmu *MMU) FrameOwners() []int {
+	return mmu.Usage().Owners
+}
+
+// FreeList returns a copy of the free list, where entry i tells whether frame i is free.
+func (mmu *MMU) FreeList() []bool {
+	mmu.freeLock.Lock()
+	defer mmu.freeLock.Unlock()
+	freeList := make([]bool, len(mmu.freeList.freeList))
+	copy(freeList, mmu.freeList.freeList)
+	return freeList
+}
+
+// FrameSize returns the size of each physical frame in bytes.
+func (mmu *MMU) FrameSize() int {
+	return len(mmu.frames[0])
+}
+
+// NumFrames returns the number of physical frames.
+func (mmu *MMU) NumFrames() int {
+	return len(mmu.frames)
+}
+
+// FrameContent returns a copy of the content of physical frame i.
+func (mmu *MMU) FrameContent(i int) ([]byte, error) {
+	if i < 0 || i >= len(mmu.frames) {
+		return nil, fmt.Errorf("failed to read frame %d: %w", i, errIndexOutOfBounds)
+	}
+	// the frame may belong to any process, so hold all of them still while copying
+	_, unlock := mmu.lockAllPageTables()
+	defer unlock()
+	content := make([]byte, len(mmu.frames[i]))
+	copy(content, mmu.frames[i])
+	return content, nil
+}
+
+// PIDs returns the pids of all processes known to the MMU in increasing order.
+func (mmu *MMU) PIDs() []int {
+	mmu.lock.RLock()
+	defer mmu.lock.RUnlock()
+	pids := make([]int, 0, len(mmu.processes))
+	for pid := range mmu.processes {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	return pids
+}
+
+// lockAllPageTables read-locks the page tables of all processes and returns them along
+// with a function that releases the locks. The locks are taken in increasing pid order.
+func (mmu *MMU) lockAllPageTables() (pageTables map[int]*PageTable, unlock func()) {
+	mmu.lock.RLock()
+	pids := make([]int, 0, len(mmu.processes))
+	candidates := make(map[int]*PageTable, len(mmu.processes))
+	locks := make(map[int]*sync.RWMutex, len(mmu.processes))
+	for pid, pageTable := range mmu.processes {
+		pids = append(pids, pid)
+		candidates[pid] = pageTable
+		locks[pid] = mmu.ptLocks[pid]
+	}
+	mmu.lock.RUnlock()
+	sort.Ints(pids)
+
+	pageTables = make(map[int]*PageTable, len(pids))
+	for _, pid := range pids {
+		locks[pid].RLock()
+		// skip processes that exited before we got hold of their page table
+		if mmu.isCurrent(pid, candidates[pid]) {
+			pageTables[pid] = candidates[pid]
+		}
+	}
+	return pageTables, func() {
+		for _, pid := range pids {
+			locks[pid].RUnlock()
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package paging
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExit(t *testing.T) {
+	mmu := NewMMU(32, 4)
+	p0, p1 := NewProcess(0, mmu), NewProcess(1, mmu)
+	if err := p0.Malloc(8); err != nil {
+		t.Fatal(err)
+	}
+	if err := p1.Malloc(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := p0.Write(0, []byte("abcdefghij")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p0.Exit(); err != nil {
+		t.Fatalf("Exit() of process 0 failed: %v", err)
+	}
+	if _, ok := mmu.processes[0]; ok {
+		t.Errorf("process 0 should be removed from the MMU after Exit()")
+	}
+	wantFreeList := []bool{true, true, false, true, true, true, true, true}
+	if diff := cmp.Diff(wantFreeList, mmu.freeList.freeList); diff != "" {
+		t.Errorf("Unexpected free list state after Exit(); (-want +got):\n%s", diff)
+	}
+	// frame 2 belongs to process 1 after frames 0, 1 and 3 were given to process 0
+	for _, frame := range []int{0, 1, 3} {
+		if diff := cmp.Diff(make([]byte, 4), mmu.frames[frame]); diff != "" {
+			t.Errorf("frame %d should be zeroed after Exit(); (-want +got):\n%s", frame, diff)
+		}
+	}
+
+	if err := p0.Exit(); err == nil {
+		t.Errorf("Exit() of a process that has already exited should fail")
+	}
+	if err := p0.Free(1); err == nil {
+		t.Errorf("Free() of a process that has exited should fail")
+	}
+	if _, err := p0.Read(0, 1); err == nil {
+		t.Errorf("Read() of a process that has exited should fail")
+	}
+}
+
+func TestProcessFreeReportsErrors(t *testing.T) {
+	mmu := NewMMU(16, 4)
+	p := NewProcess(0, mmu)
+	if err := p.Free(1); err == nil {
+		t.Errorf("Free() of a process without memory should fail")
+	}
+	if err := p.Malloc(4); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Free(2); err == nil {
+		t.Errorf("Free() of more pages than allocated should fail")
+	}
+	if err := p.Free(1); err != nil {
+		t.Errorf("Free() of all allocated pages failed: %v", err)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	mmu := NewMMU(32, 4)
+	mmu.setFreeList([]bool{true, false, true, true, true, true, true, true})
+	for _, alloc := range []struct{ pid, n int }{{3, 8}, {1, 4}, {3, 1}} {
+		if err := mmu.Alloc(alloc.pid, alloc.n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := MemoryUsage{
+		FrameSize:  4,
+		NumFrames:  8,
+		FreeFrames: 3,
+		Processes:  map[int]int{1: 1, 3: 3},
+		Owners:     []int{3, NoEntry, 3, 1, 3, NoEntry, NoEntry, NoEntry},
+		HugePages:  map[int]int{},
+		TLB:        TLBStats{Capacity: DefaultTLBEntries},
+	}
+	got := mmu.Usage()
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected memory usage; (-want +got):\n%s", diff)
+	}
+	if got.UsedFrames() != 5 {
+		t.Errorf("UsedFrames() = %d, want 5 (including the frame that was busy from the start)", got.UsedFrames())
+	}
+
+	if diff := cmp.Diff(want.Owners, mmu.FrameOwners()); diff != "" {
+		t.Errorf("Unexpected frame owners; (-want +got):\n%s", diff)
+	}
+	if n := mmu.NumFreeFrames(); n != 3 {
+		t.Errorf("NumFreeFrames() = %d, want 3", n)
+	}
+	frames, err := mmu.FramesOf(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]int{0, 2, 4}, frames); diff != "" {
+		t.Errorf("Unexpected frames of process 3; (-want +got):\n%s", diff)
+	}
+	if _, err := mmu.FramesOf(2); err == nil {
+		t.Errorf("FramesOf() of an unknown process should fail")
+	}
+	if diff := cmp.Diff([]int{1, 3}, mmu.PIDs()); diff != "" {
+		t.Errorf("Unexpected pids; (-want +got):\n%s", diff)
+	}
+
+	str := got.String()
+	for _, want := range []string{"8 total, 5 used, 3 free", "       3        3         12          0", "     0:    3    .    3    1    3    .    .    ."} {
+		if !strings.Contains(str, want) {
+			t.Errorf("String() should contain %q, got:\n%s", want, str)
+		}
+	}
+}
+
+func TestFrameContent(t *testing.T) {
+	mmu := NewMMU(16, 4)
+	p := NewProcess(7, mmu)
+	if err := p.Malloc(6); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(2, []byte("abcd")); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range [][]byte{{0, 0, 'a', 'b'}, {'c', 'd', 0, 0}, {0, 0, 0, 0}} {
+		got, err := mmu.FrameContent(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("Unexpected content of frame %d; (-want +got):\n%s", i, diff)
+		}
+	}
+	if _, err := mmu.FrameContent(4); err == nil {
+		t.Errorf("FrameContent() of a frame out of bounds should fail")
+	}
+	if diff := cmp.Diff([]bool{false, false, true, true}, mmu.FreeList()); diff != "" {
+		t.Errorf("Unexpected free list; (-want +got):\n%s", diff)
+	}
+	if mmu.FrameSize() != 4 || mmu.NumFrames() != 4 {
+		t.Errorf("FrameSize(), NumFrames() = %d, %d, want 4, 4", mmu.FrameSize(), mmu.NumFrames())
+	}
+}
+
+func TestUsageWhileRunning(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	mmu := NewMMU(256*4, 4)
+	stop := make(chan struct{})
+	wait := runAllocExit(mmu, 4, stop)
+	defer wait()
+	defer close(stop)
+
+	for i := 0; i < 20000; i++ {
+		u := mmu.Usage()
+		owned, sum := 0, 0
+		for _, pid := range u.Owners {
+			if pid != NoEntry {
+				owned++
+			}
+		}
+		for _, frames := range u.Processes {
+			sum += frames
+		}
+		if sum != u.UsedFrames() || owned != u.UsedFrames() {
+			t.Fatalf("Usage() %d while processes allocate and exit: processes have %d frames and own %d, but %d are used",
+				i, sum, owned, u.UsedFrames())
+		}
+	}
+}
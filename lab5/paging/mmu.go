@@ -1,12 +1,46 @@
 package paging
 
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
 // MMU is the structure for the simulated memory management unit.
+//
+// The MMU is safe for concurrent use. Locks are always taken in the order
+// moving -> ptLocks[pid] -> lock -> freeLock -> pressure.mu, and lock is never held while waiting for a
+// page table lock. Only introspection holds more than one page table lock at a
+// time, and it takes them in increasing pid order.
+// A frame is only ever mapped by a single page table, so the content of a frame
+// is guarded by the lock of the page table that maps it. Ownership of a frame
+// changes hands through the free list, which is guarded by freeLock.
 type MMU struct {
 	frames    [][]byte           // contains memory content in form of frames[frameIndex][offset]
 	freeList                     // tracks free physical frames
 	processes map[int]*PageTable // contains page table for each process (key=pid)
+
+	lock     sync.RWMutex          // guards processes and ptLocks
+	ptLocks  map[int]*sync.RWMutex // guards each process's page table and the frames it maps (key=pid)
+	freeLock sync.Mutex            // guards freeList
+
+	// moving is held for reading by Alloc, AllocHuge and Exit, which move frames between
+	// the free list and a page table without holding both locks, so that a frame is
+	// briefly neither free nor mapped. It is held for writing by introspection that must
+	// see every frame either free or mapped. Write and Free hold the page table lock while
+	// they move frames, which is enough.
+	moving sync.RWMutex
+
+	tlb      *tlb         // caches translations, guarded by its own lock
+	tracer   *TraceWriter // records every operation if set, guarded by lock
+	pressure pressure     // watermarks, memory limits and the OOM killer, guarded by its own lock
 }
 
+// HugePageFrames is the number of frames in a huge page. A huge page is physically
+// contiguous and aligned to its size, both in physical memory and in the address space.
+const HugePageFrames = 16
+
 // OffsetLookupTable gives the bit mask corresponding to a virtual address's offset of length n,
 // where n is the table index. This table can be used to find the offset mask needed to extract
 // the offset from a virtual address. It supports up to 32-bit wide offset masks.
@@ -52,6 +86,8 @@ func NewMMU(memSize, frameSize int) *MMU {
 		frames:    byteSlice,
 		freeList:  newFreeList(numFrames),
 		processes: make(map[int]*PageTable),
+		ptLocks:   make(map[int]*sync.RWMutex),
+		tlb:       newTLB(DefaultTLBEntries),
 	}
 }
 
@@ -60,6 +96,7 @@ func NewMMU(memSize, frameSize int) *MMU {
 // The process is given a page table if it doesn't already have one,
 // unless an out of memory error occurred.
 func (mmu *MMU) Alloc(pid, n int) error {
+	ev := TraceEvent{Op: TraceAlloc, PID: pid, N: n}
 	// Suggested approach:
 	// - calculate #frames needed to allocate n bytes, error if not enough free frames
 	// - if process pid has no page table, create one for it
@@ -68,9 +105,162 @@ func (mmu *MMU) Alloc(pid, n int) error {
 	// - update the free list
 
 	if n < 1 {
-		return errNothingToAllocate
+		return mmu.traceFailed(ev, errNothingToAllocate)
 	}
+	defer mmu.deliverPressure()
+	return mmu.traceFailed(ev, mmu.withOOMKiller(pid, func() error { return mmu.alloc(pid, n) }))
+}
 
+// alloc does the work of Alloc, without retrying when memory is exhausted. Only
+// success is traced.
+func (mmu *MMU) alloc(pid, n int) error {
+	mmu.moving.RLock()
+	defer mmu.moving.RUnlock()
+	needed := mmu.framesNeeded(n)
+	if err := mmu.checkLimit(pid, mmu.numPages(pid)+needed); err != nil {
+		return err
+	}
+	physicalFrames, err := mmu.allocFrames(needed)
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Get the process unique page table
+		pageTable, lock := mmu.getOrCreatePageTable(pid)
+		lock.Lock()
+		if mmu.isCurrent(pid, pageTable) {
+			// the process may have grown since the limit was checked
+			if err := mmu.checkLimit(pid, pageTable.Len()+needed); err != nil {
+				lock.Unlock()
+				return errors.Join(err, mmu.releaseFrames(physicalFrames))
+			}
+			pageTable.Append(physicalFrames)
+			mmu.trace(TraceEvent{Op: TraceAlloc, PID: pid, N: n})
+			lock.Unlock()
+			return nil
+		}
+		// the process exited before we got hold of its page table; start over with a new one
+		lock.Unlock()
+	}
+}
+
+// numPages returns the number of pages in the address space of process pid,
+// or 0 if the process has no page table.
+func (mmu *MMU) numPages(pid int) int {
+	pageTable, lock, err := mmu.lookupProcess(pid)
+	if err != nil {
+		return 0
+	}
+	lock.RLock()
+	defer lock.RUnlock()
+	return pageTable.Len()
+}
+
+// AllocHuge allocates n bytes of memory for process pid using huge pages.
+// The allocation is rounded up to a whole number of huge pages. Since huge pages must be
+// aligned in the address space, the address space is first padded with regular pages up
+// to the next huge page boundary. Like Alloc, the process is given a page table if it
+// doesn't already have one, unless an out of memory error occurred.
+func (mmu *MMU) AllocHuge(pid, n int) error {
+	ev := TraceEvent{Op: TraceAllocHuge, PID: pid, N: n}
+	if n < 1 {
+		return mmu.traceFailed(ev, errNothingToAllocate)
+	}
+	defer mmu.deliverPressure()
+	return mmu.traceFailed(ev, mmu.withOOMKiller(pid, func() error { return mmu.allocHuge(pid, n) }))
+}
+
+// allocHuge does the work of AllocHuge, without retrying when memory is exhausted.
+// Only success is traced.
+func (mmu *MMU) allocHuge(pid, n int) error {
+	mmu.moving.RLock()
+	defer mmu.moving.RUnlock()
+	hugePages := mmu.framesNeeded(n)
+	hugePages = (hugePages + HugePageFrames - 1) / HugePageFrames
+
+	for {
+		// the padding depends on the current length of the address space
+		pages := mmu.numPages(pid)
+		padding := hugePagePadding(pages)
+		if err := mmu.checkLimit(pid, pages+padding+hugePages*HugePageFrames); err != nil {
+			return err
+		}
+
+		firstFrames, paddingFrames, err := mmu.allocHugeFrames(hugePages, padding)
+		if err != nil {
+			return err
+		}
+
+		pageTable, lock := mmu.getOrCreatePageTable(pid)
+		lock.Lock()
+		if mmu.isCurrent(pid, pageTable) && pageTable.Len() == pages {
+			pageTable.Append(paddingFrames)
+			for _, first := range firstFrames {
+				pages := make([]int, HugePageFrames)
+				for i := range pages {
+					pages[i] = first + i
+				}
+				pageTable.AppendHuge(pages)
+			}
+			mmu.trace(TraceEvent{Op: TraceAllocHuge, PID: pid, N: n})
+			lock.Unlock()
+			return nil
+		}
+		// the address space changed while the frames were found, which also changes the
+		// padding and the memory limit check; give them back and start over
+		lock.Unlock()
+		if err := mmu.releaseFrames(append(paddingFrames, hugeFrameRange(firstFrames)...)); err != nil {
+			return err
+		}
+	}
+}
+
+// hugePagePadding returns the number of regular pages needed to align the end of
+// an address space of the given number of pages to a huge page boundary.
+func hugePagePadding(pages int) int {
+	return (HugePageFrames - pages%HugePageFrames) % HugePageFrames
+}
+
+// hugeFrameRange returns all frames of the huge pages starting at the given frames.
+func hugeFrameRange(firstFrames []int) []int {
+	frames := make([]int, 0, len(firstFrames)*HugePageFrames)
+	for _, first := range firstFrames {
+		for i := 0; i < HugePageFrames; i++ {
+			frames = append(frames, first+i)
+		}
+	}
+	return frames
+}
+
+// allocHugeFrames finds and removes hugePages huge pages and padding regular frames
+// from the free list in a single step. It returns the first frame of each huge page.
+func (mmu *MMU) allocHugeFrames(hugePages, padding int) (firstFrames, paddingFrames []int, err error) {
+	mmu.freeLock.Lock()
+	defer mmu.freeLock.Unlock()
+
+	firstFrames, err = mmu.freeList.findFreeHugeFrames(hugePages, HugePageFrames)
+	if err != nil {
+		return nil, nil, err
+	}
+	frames := hugeFrameRange(firstFrames)
+	if err := mmu.freeList.removeFrames(frames); err != nil {
+		return nil, nil, err
+	}
+	paddingFrames, err = mmu.freeList.findFreeFrames(padding)
+	if err == nil {
+		err = mmu.freeList.removeFrames(paddingFrames)
+	}
+	if err != nil {
+		_ = mmu.freeList.addFrames(frames)
+		return nil, nil, err
+	}
+	mmu.updatePressure()
+	return firstFrames, paddingFrames, nil
+}
+
+// framesNeeded returns the number of frames needed to hold n bytes.
+func (mmu *MMU) framesNeeded(n int) int {
 	// Find requested mount of frames
 	numFrames := 0
 	// BytesPerFrame = len(mmu.frames[0]) as long as the length is constant
@@ -79,49 +269,75 @@ func (mmu *MMU) Alloc(pid, n int) error {
 		n -= bytesPerFrame
 		numFrames++
 	}
+	return numFrames
+}
 
-	physicalFrames, err := mmu.freeList.findFreeFrames(numFrames)
-	if err != nil {
-		return err
-	}
+// SetFramePolicy changes how the MMU picks free frames for future allocations.
+func (mmu *MMU) SetFramePolicy(policy FramePolicy) {
+	mmu.freeLock.Lock()
+	defer mmu.freeLock.Unlock()
+	mmu.freeList.policy = policy
+}
 
-	var pageTable *PageTable
-	// Get the process unique page table
-	if mmu.processes[pid] == nil {
-		pageTable = &PageTable{
-			frameIndices: []int{},
-		}
-		mmu.processes[pid] = pageTable
-	} else {
-		pageTable = mmu.processes[pid]
-	}
+// allocFrames finds n free frames and removes them from the free list in a single step,
+// so that no other allocation can claim the same frames in between.
+func (mmu *MMU) allocFrames(n int) ([]int, error) {
+	mmu.freeLock.Lock()
+	defer mmu.freeLock.Unlock()
 
-	pageTable.Append(physicalFrames)
+	physicalFrames, err := mmu.freeList.findFreeFrames(n)
+	if err != nil {
+		return nil, err
+	}
 	// uppdates the free list
 	err = mmu.freeList.removeFrames(physicalFrames)
 	if err != nil {
+		return nil, err
+	}
+	mmu.updatePressure()
+	return physicalFrames, nil
+}
+
+// releaseFrames re-adds the given frames to the free list.
+func (mmu *MMU) releaseFrames(frames []int) error {
+	mmu.freeLock.Lock()
+	defer mmu.freeLock.Unlock()
+	if err := mmu.freeList.addFrames(frames); err != nil {
 		return err
 	}
+	mmu.updatePressure()
 	return nil
 }
 
 //Withya
 // Write writes content to the given process's address space starting at virtualAddress.
 func (mmu *MMU) Write(pid, virtualAddress int, content []byte) error {
+	defer mmu.deliverPressure()
+	err := mmu.withOOMKiller(pid, func() error { return mmu.write(pid, virtualAddress, content) })
+	return mmu.traceFailed(TraceEvent{Op: TraceWrite, PID: pid, Addr: virtualAddress, N: len(content)}, err)
+}
+
+// write does the work of Write, without retrying when memory is exhausted. Only success
+// is traced.
+func (mmu *MMU) write(pid, virtualAddress int, content []byte) error {
 	// - check valid pid (must have a page table)
-	_, err := mmu.getPageTable(pid)
+	pageTable, lock, err := mmu.lookupProcess(pid)
 	if err != nil {
 		return err
 	}
+	// the page table may grow, so it is locked for writing during the whole operation
+	lock.Lock()
+	defer lock.Unlock()
+
 	// - translate the virtual address
-	vpn, offset, r := mmu.translateAndCheck(pid, virtualAddress)
+	vpn, offset, r := mmu.translate(pageTable, virtualAddress)
 
 	if r != nil { //illegal address
 		return r
 	}
 
 	frameSize := len(mmu.frames[0])
-	bytesLeft := (frameSize - offset) + (mmu.processes[pid].Len()-vpn-1)*frameSize //resterende bytes i nåværende minne fra start_Addressen
+	bytesLeft := (frameSize - offset) + (pageTable.Len()-vpn-1)*frameSize //resterende bytes i nåværende minne fra start_Addressen
 
 	// - check if the memory must be extended in order to write the content
 	// - attempt to allocate more memory if necessary to complete the write
@@ -129,78 +345,149 @@ func (mmu *MMU) Write(pid, virtualAddress int, content []byte) error {
 	if len(content) > bytesLeft { //trenger mer bytes enn det som er igjen i current frame
 		n := len(content) - bytesLeft // finner resterende bytes som er igjen. Må allokere mer minne
 
-		alloc_err := mmu.Alloc(pid, n)
+		if err := mmu.checkLimit(pid, pageTable.Len()+mmu.framesNeeded(n)); err != nil {
+			return fmt.Errorf("failed to extend address space of process %d: %w", pid, err)
+		}
+		physicalFrames, alloc_err := mmu.allocFrames(mmu.framesNeeded(n))
 		if alloc_err != nil {
-			return errFreeOutOfBounds
+			return fmt.Errorf("failed to extend address space of process %d: %w", pid, alloc_err)
 		}
-
+		pageTable.Append(physicalFrames)
 	}
 
-	// - sequentially write content into the known-to-be-valid address space
-	content_num := 0
-
-	for i := vpn; true; i++ { //så lenge den holder seg til samme vpn
-
-		physicalFrameIndex, errr := mmu.processes[pid].Lookup(i) // finner physical address av current vpn
-		if errr != nil {
-			return errr
-		}
-
-		for o := offset; o < frameSize; o++ { //øker offset for hver iterasjon
-			mmu.frames[physicalFrameIndex][o] = content[content_num] // setter content til current offset
-			content_num += 1                                         //går til neste byte i content lista
-
-			if content_num == len(content) {
-				return nil //break loop, no more content to write
-			}
-		}
-
-		offset = 0 //hvis offset har blitt lenger enn frameSize, må den nullstilles
-
+	// - copy content into the known-to-be-valid address space, one page at a time
+	if err := mmu.writePages(pageTable, vpn, offset, content); err != nil {
+		return err
 	}
+	mmu.trace(TraceEvent{Op: TraceWrite, PID: pid, Addr: virtualAddress, N: len(content)})
 	return nil
-
 }
 
 // Read returns content of size n bytes from the given process's address space starting at virtualAddress.
 func (mmu *MMU) Read(pid, virtualAddress, n int) (content []byte, err error) {
-	// Suggested approach:
+	ev := TraceEvent{Op: TraceRead, PID: pid, Addr: virtualAddress, N: n}
+	if n < 1 {
+		mmu.trace(ev)
+		return nil, errNothingToRead
+	}
 	// - check valid pid (must have a page table)
+	pageTable, lock, err := mmu.lookupProcess(pid)
+	if err != nil {
+		mmu.trace(ev)
+		return nil, err
+	}
+	lock.RLock()
+	defer lock.RUnlock()
+	mmu.trace(ev)
+
 	// - translate the virtual address
-	// - (optional) determine if it's possible to read the requested number
+	vpn, offset, err := mmu.translate(pageTable, virtualAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	// - determine if it's possible to read the requested number
 	//   of bytes before starting to read the memory content
+	frameSize := len(mmu.frames[0])
+	bytesLeft := (pageTable.Len()-vpn)*frameSize - offset
+	if n > bytesLeft {
+		return nil, errAddressOutOfBounds
+	}
+
 	// - read and return the requested memory content
-	if n < 1 {
-		return nil, errNothingToRead
+	content = make([]byte, n)
+	if err := mmu.readPages(pageTable, vpn, offset, content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// readAt reads up to len(b) bytes from the given process's address space starting at
+// virtualAddress, with the semantics of io.ReaderAt. It is traced as a Read of the n bytes
+// that were read, so that a replay doesn't read past the end; a read of nothing is traced
+// with len(b), and fails in the replay as well.
+func (mmu *MMU) readAt(pid int, b []byte, virtualAddress int) (n int, err error) {
+	traceRead := func() {
+		ev := TraceEvent{Op: TraceRead, PID: pid, Addr: virtualAddress, N: n}
+		if n == 0 {
+			ev.N = len(b)
+		}
+		mmu.trace(ev)
 	}
-	pageTable, err := mmu.getPageTable(pid)
+	pageTable, lock, err := mmu.lookupProcess(pid)
 	if err != nil {
-		return nil, err
+		traceRead()
+		return 0, err
+	}
+	lock.RLock()
+	defer lock.RUnlock()
+	defer traceRead()
+
+	frameSize := len(mmu.frames[0])
+	size := pageTable.Len() * frameSize
+	if virtualAddress < 0 {
+		return 0, errAddressOutOfBounds
+	}
+	if virtualAddress >= size {
+		return 0, io.EOF
+	}
+	n = len(b)
+	if n > size-virtualAddress {
+		n, err = size-virtualAddress, io.EOF
+	}
+
+	vpn, offset := extract(virtualAddress, log2(frameSize))
+	if rerr := mmu.readPages(pageTable, vpn, offset, b[:n]); rerr != nil {
+		return 0, rerr
 	}
-	for i := 0; i < n; i++ {
-		vpn, currentByte, err := mmu.translateAndCheck(pid, virtualAddress)
+	return n, err
+}
+
+// readPages fills b with the memory content starting at the given virtual page and offset.
+// Each page is translated once and copied in bulk.
+// The caller must hold the lock of the page table.
+func (mmu *MMU) readPages(pageTable *PageTable, vpn, offset int, b []byte) error {
+	for done := 0; done < len(b); vpn++ {
+		frame, err := mmu.lookup(pageTable, vpn)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		frameNumber, err := pageTable.Lookup(vpn)
+		done += copy(b[done:], mmu.frames[frame][offset:])
+		offset = 0
+	}
+	return nil
+}
+
+// writePages copies b into memory starting at the given virtual page and offset.
+// Each page is translated once and copied in bulk.
+// The caller must hold the lock of the page table.
+func (mmu *MMU) writePages(pageTable *PageTable, vpn, offset int, b []byte) error {
+	for done := 0; done < len(b); vpn++ {
+		frame, err := mmu.lookup(pageTable, vpn)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		content = append(content, mmu.frames[frameNumber][currentByte])
-		virtualAddress = virtualAddress + 1
+		done += copy(mmu.frames[frame][offset:], b[done:])
+		offset = 0
 	}
-	return content, nil
+	return nil
 }
 
 // Free is called by a process's Free() function to free some of its allocated memory.
 func (mmu *MMU) Free(pid, n int) error {
+	ev := TraceEvent{Op: TraceFree, PID: pid, N: n}
+	defer mmu.deliverPressure()
 	// Suggested approach:
 
 	// - check valid pid (must have a page table)
-	pageTable, err := mmu.getPageTable(pid)
+	pageTable, lock, err := mmu.lookupProcess(pid)
 	if err != nil {
+		mmu.trace(ev)
 		return err
 	}
+	lock.Lock()
+	defer lock.Unlock()
+	mmu.trace(ev)
 
 	// - check if there are at least n entries in the page table of pid
 	if pageTable.Len() < n {
@@ -212,22 +499,62 @@ func (mmu *MMU) Free(pid, n int) error {
 	if err != nil {
 		return err
 	}
+	mmu.tlb.flush(pageTable)
 
 	// - set all the bytes in the freed memory to the value 0
-	offset := 0
-	frameSize := len(mmu.frames[0])
+	mmu.zeroFrames(physicalFramesFreed)
 
-	for _, i := range physicalFramesFreed {
-		for o := offset; o < frameSize; o++ {
-			mmu.frames[i][o] = 0
-		}
-		offset = 0
+	// - re-add the freed frames to the free list
+	return mmu.releaseFrames(physicalFramesFreed)
+}
+
+// Exit tears down process pid, returning all of its frames to the free list.
+// The frames are zeroed before they are freed, and pid is removed from the MMU.
+func (mmu *MMU) Exit(pid int) error {
+	ev := TraceEvent{Op: TraceExit, PID: pid}
+	defer mmu.deliverPressure()
+	mmu.moving.RLock()
+	defer mmu.moving.RUnlock()
+	pageTable, lock, err := mmu.lookupProcess(pid)
+	if err != nil {
+		mmu.trace(ev)
+		return err
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	mmu.trace(ev)
+
+	mmu.lock.Lock()
+	if mmu.processes[pid] != pageTable {
+		// another Exit got here first
+		mmu.lock.Unlock()
+		return errInvalidProcess
 	}
+	delete(mmu.processes, pid)
+	delete(mmu.ptLocks, pid)
+	mmu.lock.Unlock()
+	mmu.forgetProcess(pid)
 
-	// - re-add the freed frames to the free list
-	mmu.freeList.addFrames(physicalFramesFreed)
+	if pageTable.Len() == 0 {
+		return nil
+	}
+	physicalFramesFreed, err := pageTable.Free(pageTable.Len())
+	if err != nil {
+		return err
+	}
+	mmu.tlb.flush(pageTable)
+	mmu.zeroFrames(physicalFramesFreed)
+	return mmu.releaseFrames(physicalFramesFreed)
+}
 
-	return nil
+// zeroFrames sets all the bytes in the given frames to the value 0.
+// The caller must hold the lock of the page table the frames are removed from.
+func (mmu *MMU) zeroFrames(frames []int) {
+	for _, i := range frames {
+		for o := range mmu.frames[i] {
+			mmu.frames[i][o] = 0
+		}
+	}
 }
 
 //Withya
@@ -261,29 +588,71 @@ func (mmu *MMU) translateAndCheck(pid, virtualAddress int) (vpn, offset int, err
 	// You might also find the provided log2 function useful to calculate one
 	// of the inputs to the extract function.
 
+	pageTable, lock, err := mmu.lookupProcess(pid)
+	if err != nil {
+		return 0, 0, err
+	}
+	lock.RLock()
+	defer lock.RUnlock()
+	return mmu.translate(pageTable, virtualAddress)
+}
+
+// translate does the work of translateAndCheck for an already looked up page table.
+// The caller must hold the lock of the page table.
+func (mmu *MMU) translate(pageTable *PageTable, virtualAddress int) (vpn, offset int, err error) {
 	frameSize := len(mmu.frames[0]) //finner framSize
 	n := log2(frameSize)            //antall bits for offset
 	vA_vpn, vA_offset := extract(virtualAddress, n)
 
-	pageTable, r := mmu.getPageTable(pid)
-	if r != nil {
-		return 0, 0, r
-	}
-
 	_, errr := pageTable.Lookup(vA_vpn)
 	if errr != nil {
 		return 0, 0, errr
 	}
 
+	// the pages of a huge page are also regular entries of the page table, and a huge page
+	// is aligned to its size, so it translates like regular pages
 	return vA_vpn, vA_offset, nil
 
 }
 
 func (mmu *MMU) getPageTable(pid int) (pageTable *PageTable, err error) {
+	pageTable, _, err = mmu.lookupProcess(pid)
+	return pageTable, err
+}
+
+// lookupProcess returns the page table of process pid along with the lock guarding it.
+func (mmu *MMU) lookupProcess(pid int) (pageTable *PageTable, lock *sync.RWMutex, err error) {
+	mmu.lock.RLock()
+	defer mmu.lock.RUnlock()
+	if pageTable, ok := mmu.processes[pid]; ok {
+		return pageTable, mmu.ptLocks[pid], nil
+	}
+	return nil, nil, errInvalidProcess
+}
+
+// isCurrent reports whether pageTable is still the page table of process pid,
+// i.e. the process has not exited since the page table was looked up.
+// The caller must hold the lock of the page table.
+func (mmu *MMU) isCurrent(pid int, pageTable *PageTable) bool {
+	mmu.lock.RLock()
+	defer mmu.lock.RUnlock()
+	return mmu.processes[pid] == pageTable
+}
+
+// getOrCreatePageTable returns the page table of process pid along with the lock guarding it.
+// The process is given an empty page table if it doesn't already have one.
+func (mmu *MMU) getOrCreatePageTable(pid int) (pageTable *PageTable, lock *sync.RWMutex) {
+	mmu.lock.Lock()
+	defer mmu.lock.Unlock()
 	if pageTable, ok := mmu.processes[pid]; ok {
-		return pageTable, nil
+		return pageTable, mmu.ptLocks[pid]
+	}
+	pageTable = &PageTable{
+		frameIndices: []int{},
 	}
-	return nil, errInvalidProcess
+	mmu.processes[pid] = pageTable
+	mmu.ptLocks[pid] = new(sync.RWMutex)
+	return pageTable, mmu.ptLocks[pid]
 }
 
 // log2 calculates m given n = 2^m.
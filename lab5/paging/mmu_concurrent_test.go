@@ -0,0 +1,146 @@
+package paging
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+const (
+	concurrentProcesses  = 16
+	concurrentIterations = 200
+)
+
+// runProcessWorkload lets process pid repeatedly allocate, write, read back and free memory,
+// exiting every eighth iteration.
+// Every byte written is tagged with the pid and iteration, so a read that observes memory
+// belonging to another process (or a stale write) is reported as an error.
+func runProcessWorkload(mmu *MMU, pid, iterations, frameSize int) error {
+	for i := 0; i < iterations; i++ {
+		size := frameSize * (1 + (pid+i)%3)
+		if err := mmu.Alloc(pid, size); err != nil {
+			if err == errOutOfMemory {
+				// other processes hold the memory right now; try again later
+				continue
+			}
+			return fmt.Errorf("Alloc(pid = %d, n = %d): %w", pid, size, err)
+		}
+
+		pageTable, lock, err := mmu.lookupProcess(pid)
+		if err != nil {
+			return err
+		}
+		lock.RLock()
+		numPages := pageTable.Len()
+		lock.RUnlock()
+
+		content := bytes.Repeat([]byte{byte(pid<<4 | i&0xf)}, numPages*frameSize)
+		if err := mmu.Write(pid, 0, content); err != nil {
+			return fmt.Errorf("Write(pid = %d): %w", pid, err)
+		}
+		got, err := mmu.Read(pid, 0, len(content))
+		if err != nil {
+			return fmt.Errorf("Read(pid = %d): %w", pid, err)
+		}
+		if !bytes.Equal(got, content) {
+			return fmt.Errorf("Read(pid = %d) returned memory not written by the process in iteration %d", pid, i)
+		}
+
+		if i%8 == 7 {
+			// now and then the process exits instead and starts over with a new page table
+			if err := mmu.Exit(pid); err != nil {
+				return fmt.Errorf("Exit(pid = %d): %w", pid, err)
+			}
+			continue
+		}
+		if err := mmu.Free(pid, numPages); err != nil {
+			return fmt.Errorf("Free(pid = %d, n = %d): %w", pid, numPages, err)
+		}
+	}
+	return nil
+}
+
+// TestConcurrentProcesses should be run with the race detector enabled:
+//
+//	go test -race -run TestConcurrentProcesses
+func TestConcurrentProcesses(t *testing.T) {
+	const frameSize = 8
+	// the memory is smaller than what all processes may want at the same time,
+	// so allocations regularly fail and frames are handed between processes
+	mmu := NewMMU(concurrentProcesses*frameSize*4, frameSize)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrentProcesses)
+	for pid := 0; pid < concurrentProcesses; pid++ {
+		wg.Add(1)
+		go func(pid int) {
+			defer wg.Done()
+			if err := runProcessWorkload(mmu, pid, concurrentIterations, frameSize); err != nil {
+				errs <- err
+			}
+		}(pid)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+
+	numFrames := len(mmu.frames)
+	if mmu.numFreeFrames != numFrames || mmu.calculateNumFreeFrames() != numFrames {
+		t.Errorf("all memory should be free after the workload; want %d free frames, got numFreeFrames = %d and %d free entries in the free list",
+			numFrames, mmu.numFreeFrames, mmu.calculateNumFreeFrames())
+	}
+	for i, frame := range mmu.frames {
+		if !bytes.Equal(frame, make([]byte, frameSize)) {
+			t.Errorf("frame %d was not zeroed after the workload: %v", i, frame)
+		}
+	}
+}
+
+func TestFreeListRollback(t *testing.T) {
+	fl := newFreeList(4)
+	if err := fl.removeFrames([]int{0, 1, 1}); err == nil {
+		t.Errorf("removeFrames([0 1 1]) should fail when removing the same frame twice")
+	}
+	if err := fl.removeFrames([]int{2, 4}); err == nil {
+		t.Errorf("removeFrames([2 4]) should fail when removing a frame out of bounds")
+	}
+	want := []bool{true, true, true, true}
+	for i := range want {
+		if fl.freeList[i] != want[i] {
+			t.Fatalf("free list should be unchanged after failed removeFrames; want %v, got %v", want, fl.freeList)
+		}
+	}
+	if fl.numFreeFrames != 4 {
+		t.Errorf("numFreeFrames should be unchanged after failed removeFrames; want 4, got %d", fl.numFreeFrames)
+	}
+}
+
+func BenchmarkConcurrentAccess(b *testing.B) {
+	const frameSize = 64
+	for _, procs := range []int{1, 4, 16, 64} {
+		b.Run(fmt.Sprintf("procs=%d", procs), func(b *testing.B) {
+			mmu := NewMMU(procs*frameSize*8, frameSize)
+			b.ResetTimer()
+
+			var wg sync.WaitGroup
+			for pid := 0; pid < procs; pid++ {
+				wg.Add(1)
+				go func(pid int) {
+					defer wg.Done()
+					iterations := b.N / procs
+					if pid < b.N%procs {
+						iterations++
+					}
+					if err := runProcessWorkload(mmu, pid, iterations, frameSize); err != nil {
+						b.Error(err)
+					}
+				}(pid)
+			}
+			wg.Wait()
+		})
+	}
+}
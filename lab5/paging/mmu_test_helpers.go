@@ -1,9 +1,14 @@
 package paging
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 // PrintMemory will print the contents of the memory for debugging purposes.
 func (mmu *MMU) PrintMemory() {
+	mmu.freeLock.Lock()
+	defer mmu.freeLock.Unlock()
 	frameSize := len(mmu.frames[0])
 	for i, frame := range mmu.frames {
 		fmt.Printf("[%s: ", fmt.Sprintf("0x%x", i*frameSize))
@@ -39,6 +44,10 @@ func (mmu *MMU) setFreeList(freeList []bool) {
 // to be done, you can define them here.
 func (mmu *MMU) setProcesses(processes map[int]*PageTable) {
 	mmu.processes = processes
+	mmu.ptLocks = make(map[int]*sync.RWMutex)
+	for pid := range processes {
+		mmu.ptLocks[pid] = new(sync.RWMutex)
+	}
 }
 
 // setProcesses sets the state of a single process.
@@ -46,4 +55,5 @@ func (mmu *MMU) setProcesses(processes map[int]*PageTable) {
 // to be done, you can define them here.
 func (mmu *MMU) setProcess(pid int, process *PageTable) {
 	mmu.processes[pid] = process
+	mmu.ptLocks[pid] = new(sync.RWMutex)
 }
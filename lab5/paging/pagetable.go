@@ -5,7 +5,8 @@ const NoEntry = -1
 
 // PageTable is a per-process data structure which holds translations from virtual page numbers to physical frame numbers
 type PageTable struct {
-	frameIndices []int // maps virtual page number (index) to physical frame number (content)
+	frameIndices []int       // maps virtual page number (index) to physical frame number (content)
+	huge         map[int]int // number of pages in each huge page mapping (key=first virtual page number)
 }
 
 // Append adds pages to a page table
@@ -13,7 +14,18 @@ func (pt *PageTable) Append(pages []int) {
 	pt.frameIndices = append(pt.frameIndices, pages...)
 }
 
-// Free removes the n last pages from the page table and returns the removed entries
+// AppendHuge adds a huge page mapping, made up of physically contiguous pages, to a page table.
+// Every page of the mapping is also added as a regular entry, so Lookup works for any of them.
+func (pt *PageTable) AppendHuge(pages []int) {
+	if pt.huge == nil {
+		pt.huge = make(map[int]int)
+	}
+	pt.huge[pt.Len()] = len(pages)
+	pt.Append(pages)
+}
+
+// Free removes the n last pages from the page table and returns the removed entries.
+// Huge page mappings that lose any of their pages are split into regular pages.
 func (pt *PageTable) Free(n int) ([]int, error) {
 	if n < 1 {
 		return []int{}, errNothingToAllocate
@@ -23,6 +35,11 @@ func (pt *PageTable) Free(n int) ([]int, error) {
 	}
 	removedEntries := pt.frameIndices[pt.Len()-n:]
 	pt.frameIndices = pt.frameIndices[:pt.Len()-n]
+	for first, pages := range pt.huge {
+		if first+pages > pt.Len() {
+			delete(pt.huge, first)
+		}
+	}
 	return removedEntries, nil
 }
 
@@ -38,3 +55,12 @@ func (pt *PageTable) Lookup(virtualPageNum int) (frameIndex int, err error) {
 func (pt *PageTable) Len() int {
 	return len(pt.frameIndices)
 }
+
+// hugePage returns the first virtual page number and the number of pages of the huge page
+// mapping containing virtualPageNum, given that huge pages are hugePageFrames pages long
+// and aligned to their size. ok is false if virtualPageNum is mapped by a regular page.
+func (pt *PageTable) hugePage(virtualPageNum, hugePageFrames int) (first, pages int, ok bool) {
+	first = virtualPageNum - virtualPageNum%hugePageFrames
+	pages, ok = pt.huge[first]
+	return first, pages, ok
+}
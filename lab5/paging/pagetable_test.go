@@ -8,7 +8,7 @@ import (
 
 func TestPTAppend(t *testing.T) {
 	for i, test := range PTAppendTests {
-		pageTable := PageTable{test.pageTable}
+		pageTable := PageTable{frameIndices: test.pageTable}
 		pageTable.Append(test.in)
 
 		if diff := cmp.Diff(test.want, pageTable.frameIndices); diff != "" {
@@ -19,7 +19,7 @@ func TestPTAppend(t *testing.T) {
 
 func TestPTFree(t *testing.T) {
 	for i, test := range PTFreeTests {
-		pageTable := PageTable{test.pageTable}
+		pageTable := PageTable{frameIndices: test.pageTable}
 		freed, err := pageTable.Free(test.in)
 
 		if test.want.err == nil && err != nil {
@@ -42,7 +42,7 @@ func TestPTFree(t *testing.T) {
 
 func TestPTLookup(t *testing.T) {
 	for i, test := range PTLookupTests {
-		pageTable := PageTable{test.pageTable}
+		pageTable := PageTable{frameIndices: test.pageTable}
 		frameIndex, err := pageTable.Lookup(test.in)
 
 		if test.want.err == nil && err != nil {
@@ -0,0 +1,255 @@
+package paging
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	errMemoryLimit       = errors.New("memory limit exceeded")
+	errInvalidWatermarks = errors.New("invalid watermarks: want 0 <= critical <= low <= number of frames")
+)
+
+// PressureLevel tells how close the MMU is to running out of free frames.
+type PressureLevel int
+
+// Memory pressure levels, from no pressure to almost out of memory.
+const (
+	PressureNone PressureLevel = iota
+	PressureLow
+	PressureCritical
+)
+
+// String returns the name of the level.
+func (l PressureLevel) String() string {
+	switch l {
+	case PressureNone:
+		return "none"
+	case PressureLow:
+		return "low"
+	case PressureCritical:
+		return "critical"
+	}
+	return fmt.Sprintf("PressureLevel(%d)", int(l))
+}
+
+// PressureEvent is delivered to subscribers when the memory pressure level changes,
+// and to the victim of the OOM killer.
+type PressureEvent struct {
+	Level      PressureLevel // pressure level after the change
+	FreeFrames int           // number of free frames after the change
+	NumFrames  int           // total number of physical frames
+	Killed     bool          // the subscribing process was killed by the OOM killer
+}
+
+// OOMScore rates how good a victim process pid with the given number of frames is for the
+// OOM killer. The process with the highest score is killed; a score <= 0 protects the process.
+type OOMScore func(pid, frames int) int
+
+// LargestProcess is an OOMScore that picks the process using the most frames.
+func LargestProcess(pid, frames int) int {
+	return frames
+}
+
+// delivery is a pressure event waiting to be delivered to the subscribers it was raised for.
+type delivery struct {
+	subscribers []func(PressureEvent)
+	event       PressureEvent
+}
+
+// pressure holds the memory pressure state of an MMU.
+type pressure struct {
+	mu          sync.Mutex
+	low         int                           // free frames below which pressure is low
+	critical    int                           // free frames below which pressure is critical
+	level       PressureLevel                 // level after the last free list update
+	subscribers map[int][]func(PressureEvent) // pressure callbacks of each process (key=pid)
+	pending     []delivery                    // events raised while locks were held
+	limits      map[int]int                   // maximum number of frames of each process (key=pid)
+	oomScore    OOMScore                      // picks the OOM killer's victim, nil if disabled
+	kills       int                           // number of processes killed by the OOM killer
+}
+
+// levelOf returns the pressure level when free frames are free.
+// The caller must hold p.mu.
+func (p *pressure) levelOf(free int) PressureLevel {
+	switch {
+	case free < p.critical:
+		return PressureCritical
+	case free < p.low:
+		return PressureLow
+	}
+	return PressureNone
+}
+
+// SetWatermarks sets the number of free frames below which memory pressure is low and
+// critical. Subscribers are notified whenever the level changes. Both are 0 by default,
+// which means there is never any pressure.
+func (mmu *MMU) SetWatermarks(low, critical int) error {
+	if critical < 0 || critical > low || low > len(mmu.frames) {
+		return errInvalidWatermarks
+	}
+	defer mmu.deliverPressure()
+	mmu.freeLock.Lock()
+	defer mmu.freeLock.Unlock()
+	mmu.pressure.mu.Lock()
+	mmu.pressure.low, mmu.pressure.critical = low, critical
+	mmu.pressure.mu.Unlock()
+	mmu.updatePressure()
+	return nil
+}
+
+// SubscribePressure registers fn to be called with every change of the memory pressure
+// level for as long as process pid exists. fn is called after the operation causing the
+// change has released its locks, so it may call back into the MMU, for example to free memory.
+func (mmu *MMU) SubscribePressure(pid int, fn func(PressureEvent)) {
+	mmu.pressure.mu.Lock()
+	defer mmu.pressure.mu.Unlock()
+	if mmu.pressure.subscribers == nil {
+		mmu.pressure.subscribers = make(map[int][]func(PressureEvent))
+	}
+	mmu.pressure.subscribers[pid] = append(mmu.pressure.subscribers[pid], fn)
+}
+
+// SetMemoryLimit limits process pid to at most frames frames, much like a cgroup memory limit.
+// Allocations that would take the process over its limit fail with a memory limit error, but
+// memory the process already has is not taken away. A limit of 0 removes the limit.
+func (mmu *MMU) SetMemoryLimit(pid, frames int) {
+	mmu.pressure.mu.Lock()
+	defer mmu.pressure.mu.Unlock()
+	if frames <= 0 {
+		delete(mmu.pressure.limits, pid)
+		return
+	}
+	if mmu.pressure.limits == nil {
+		mmu.pressure.limits = make(map[int]int)
+	}
+	mmu.pressure.limits[pid] = frames
+}
+
+// SetOOMKiller enables the OOM killer. When an allocation fails because memory is exhausted,
+// the process with the highest score is killed and its frames reclaimed before the allocation
+// is retried. The allocating process is never picked. A nil score disables the OOM killer.
+func (mmu *MMU) SetOOMKiller(score OOMScore) {
+	mmu.pressure.mu.Lock()
+	defer mmu.pressure.mu.Unlock()
+	mmu.pressure.oomScore = score
+}
+
+// PressureLevel returns the current memory pressure level.
+func (mmu *MMU) PressureLevel() PressureLevel {
+	mmu.pressure.mu.Lock()
+	defer mmu.pressure.mu.Unlock()
+	return mmu.pressure.level
+}
+
+// checkLimit returns an error if process pid is not allowed to have the given number of frames.
+func (mmu *MMU) checkLimit(pid, frames int) error {
+	mmu.pressure.mu.Lock()
+	defer mmu.pressure.mu.Unlock()
+	if limit, ok := mmu.pressure.limits[pid]; ok && frames > limit {
+		return fmt.Errorf("process %d needs %d frames, limit is %d: %w", pid, frames, limit, errMemoryLimit)
+	}
+	return nil
+}
+
+// updatePressure recomputes the pressure level after the free list has changed, and queues
+// an event for the subscribers if the level changed. The caller must hold freeLock.
+func (mmu *MMU) updatePressure() {
+	p := &mmu.pressure
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	level := p.levelOf(mmu.numFreeFrames)
+	if level == p.level {
+		return
+	}
+	p.level = level
+	d := delivery{event: PressureEvent{Level: level, FreeFrames: mmu.numFreeFrames, NumFrames: len(mmu.frames)}}
+	for _, fns := range p.subscribers {
+		d.subscribers = append(d.subscribers, fns...)
+	}
+	if len(d.subscribers) > 0 {
+		p.pending = append(p.pending, d)
+	}
+}
+
+// deliverPressure calls the subscribers of all queued events.
+// It must be called without holding any of the MMU's locks.
+func (mmu *MMU) deliverPressure() {
+	mmu.pressure.mu.Lock()
+	pending := mmu.pressure.pending
+	mmu.pressure.pending = nil
+	mmu.pressure.mu.Unlock()
+	for _, d := range pending {
+		for _, fn := range d.subscribers {
+			fn(d.event)
+		}
+	}
+}
+
+// forgetProcess removes the subscriptions and the memory limit of process pid.
+func (mmu *MMU) forgetProcess(pid int) {
+	mmu.pressure.mu.Lock()
+	defer mmu.pressure.mu.Unlock()
+	delete(mmu.pressure.subscribers, pid)
+	delete(mmu.pressure.limits, pid)
+}
+
+// withOOMKiller runs op on behalf of process pid. As long as op fails because memory is
+// exhausted and the OOM killer finds a victim, the victim is killed and op is run again.
+// op must not hold any locks when it returns.
+func (mmu *MMU) withOOMKiller(pid int, op func() error) error {
+	for {
+		err := op()
+		if !errors.Is(err, errOutOfMemory) || !mmu.oomKill(pid) {
+			return err
+		}
+	}
+}
+
+// oomKill kills the process with the highest OOM score other than requester, and reports
+// whether a process was killed. The caller must not hold any of the MMU's locks.
+func (mmu *MMU) oomKill(requester int) bool {
+	mmu.pressure.mu.Lock()
+	score := mmu.pressure.oomScore
+	mmu.pressure.mu.Unlock()
+	if score == nil {
+		return false
+	}
+
+	victim, best := NoEntry, 0
+	for _, pid := range mmu.PIDs() {
+		frames, err := mmu.FramesOf(pid)
+		if pid == requester || err != nil || len(frames) == 0 {
+			continue
+		}
+		if s := score(pid, len(frames)); s > best {
+			victim, best = pid, s
+		}
+	}
+	if victim == NoEntry {
+		return false
+	}
+
+	mmu.pressure.mu.Lock()
+	d := delivery{subscribers: mmu.pressure.subscribers[victim]}
+	mmu.pressure.mu.Unlock()
+	if err := mmu.Exit(victim); err != nil {
+		// the victim exited on its own in the meantime, which also freed memory
+		return true
+	}
+
+	mmu.freeLock.Lock()
+	d.event = PressureEvent{FreeFrames: mmu.numFreeFrames, NumFrames: len(mmu.frames), Killed: true}
+	mmu.pressure.mu.Lock()
+	d.event.Level = mmu.pressure.level
+	mmu.pressure.kills++
+	if len(d.subscribers) > 0 {
+		mmu.pressure.pending = append(mmu.pressure.pending, d)
+	}
+	mmu.pressure.mu.Unlock()
+	mmu.freeLock.Unlock()
+	mmu.deliverPressure()
+	return true
+}
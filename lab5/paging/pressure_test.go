@@ -0,0 +1,195 @@
+package paging
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPressureNotifications(t *testing.T) {
+	mmu := NewMMU(16*4, 4)
+	if err := mmu.SetWatermarks(2, 8); !errors.Is(err, errInvalidWatermarks) {
+		t.Errorf("SetWatermarks() with critical above low: want '%v', got '%v'", errInvalidWatermarks, err)
+	}
+	if err := mmu.SetWatermarks(8, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	p0, p1 := NewProcess(0, mmu), NewProcess(1, mmu)
+	var got []PressureEvent
+	p0.OnPressure(func(ev PressureEvent) {
+		got = append(got, ev)
+		// callbacks may call back into the MMU
+		if ev.Level == PressureCritical {
+			if err := p0.Free(2); err != nil {
+				t.Errorf("Free() from a pressure callback failed: %v", err)
+			}
+		}
+	})
+
+	steps := []func() error{
+		func() error { return p0.Malloc(6 * 4) }, // 10 free: none
+		func() error { return p1.Malloc(3 * 4) }, // 7 free: low
+		func() error { return p0.Malloc(4 * 4) }, // 3 free: critical, then 5 free: low
+		func() error { return p1.Exit() },        // 8 free: none
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d failed: %v", i, err)
+		}
+	}
+	want := []PressureEvent{
+		{Level: PressureLow, FreeFrames: 7, NumFrames: 16},
+		{Level: PressureCritical, FreeFrames: 3, NumFrames: 16},
+		{Level: PressureLow, FreeFrames: 5, NumFrames: 16},
+		{Level: PressureNone, FreeFrames: 8, NumFrames: 16},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected pressure events; (-want +got):\n%s", diff)
+	}
+	if level := mmu.PressureLevel(); level != PressureNone {
+		t.Errorf("PressureLevel() = %v, want %v", level, PressureNone)
+	}
+
+	// subscriptions end when the process exits
+	if err := p0.Exit(); err != nil {
+		t.Fatal(err)
+	}
+	got = nil
+	if err := p1.Malloc(16 * 4); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("exited process received pressure events: %v", got)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	mmu := NewMMU(64*4, 4)
+	p := NewProcess(0, mmu)
+	p.SetMemoryLimit(20)
+
+	if err := p.Malloc(16 * 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Malloc(5 * 4); !errors.Is(err, errMemoryLimit) {
+		t.Errorf("Malloc() over the limit: want '%v', got '%v'", errMemoryLimit, err)
+	}
+	if err := p.MallocHuge(1); !errors.Is(err, errMemoryLimit) {
+		t.Errorf("MallocHuge() over the limit: want '%v', got '%v'", errMemoryLimit, err)
+	}
+	if err := p.Write(16*4-1, make([]byte, 5*4)); !errors.Is(err, errMemoryLimit) {
+		t.Errorf("Write() extending over the limit: want '%v', got '%v'", errMemoryLimit, err)
+	}
+	if n := mmu.NumFreeFrames(); n != 48 {
+		t.Errorf("failed allocations should not take frames; want 48 free frames, got %d", n)
+	}
+	if err := p.Write(16*4-1, make([]byte, 4*4)); err != nil {
+		t.Errorf("Write() up to the limit failed: %v", err)
+	}
+
+	// other processes are not limited
+	if err := mmu.Alloc(1, 40*4); err != nil {
+		t.Errorf("Alloc() of a process without a limit failed: %v", err)
+	}
+	p.SetMemoryLimit(0)
+	if err := p.Malloc(4 * 4); err != nil {
+		t.Errorf("Malloc() after removing the limit failed: %v", err)
+	}
+}
+
+func TestOOMKiller(t *testing.T) {
+	mmu := NewMMU(16*4, 4)
+	for pid, frames := range []int{3, 6, 4} {
+		if err := mmu.Alloc(pid, frames*4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var killed []PressureEvent
+	for pid := 0; pid < 3; pid++ {
+		NewProcess(pid, mmu).OnPressure(func(ev PressureEvent) {
+			if ev.Killed {
+				killed = append(killed, ev)
+			}
+		})
+	}
+
+	// without an OOM killer the caller only sees the error
+	if err := mmu.Alloc(3, 8*4); !errors.Is(err, errOutOfMemory) {
+		t.Fatalf("Alloc() without enough memory: want '%v', got '%v'", errOutOfMemory, err)
+	}
+
+	mmu.SetOOMKiller(LargestProcess)
+	if err := mmu.Alloc(3, 8*4); err != nil {
+		t.Fatalf("Alloc() with the OOM killer failed: %v", err)
+	}
+	if diff := cmp.Diff([]int{0, 2, 3}, mmu.PIDs()); diff != "" {
+		t.Errorf("the OOM killer should kill the largest process; remaining pids (-want +got):\n%s", diff)
+	}
+	want := []PressureEvent{{FreeFrames: 9, NumFrames: 16, Killed: true}}
+	if diff := cmp.Diff(want, killed); diff != "" {
+		t.Errorf("Unexpected events for the victim; (-want +got):\n%s", diff)
+	}
+
+	// the allocating process is never killed, and a score of 0 protects a process
+	mmu.SetOOMKiller(func(pid, frames int) int {
+		if pid == 0 {
+			return frames
+		}
+		return 0
+	})
+	if err := mmu.Write(0, 0, make([]byte, 10*4)); !errors.Is(err, errOutOfMemory) {
+		t.Errorf("Write() when only the writer itself could be killed: want '%v', got '%v'", errOutOfMemory, err)
+	}
+	mmu.SetOOMKiller(func(pid, frames int) int {
+		if pid == 0 {
+			return 0
+		}
+		return frames
+	})
+	if err := mmu.Alloc(3, 5*4); err != nil {
+		t.Fatalf("Alloc() with the OOM killer failed: %v", err)
+	}
+	if diff := cmp.Diff([]int{0, 3}, mmu.PIDs()); diff != "" {
+		t.Errorf("the OOM killer should only kill unprotected processes; remaining pids (-want +got):\n%s", diff)
+	}
+	if usage := mmu.Usage(); usage.OOMKills != 2 {
+		t.Errorf("Usage() reports %d OOM kills, want 2", usage.OOMKills)
+	}
+}
+
+func TestOOMKillerConcurrent(t *testing.T) {
+	mmu := NewMMU(64*4, 4)
+	mmu.SetOOMKiller(LargestProcess)
+	if err := mmu.SetWatermarks(16, 4); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for pid := 0; pid < 8; pid++ {
+		wg.Add(1)
+		go func(p *Process) {
+			defer wg.Done()
+			p.OnPressure(func(ev PressureEvent) {
+				if ev.Level == PressureCritical {
+					_ = p.Free(1)
+				}
+			})
+			for i := 0; i < 100; i++ {
+				_ = p.Malloc(4 * (1 + i%8))
+				_ = p.Write(i, []byte("oom"))
+			}
+		}(NewProcess(pid, mmu))
+	}
+	wg.Wait()
+
+	usage := mmu.Usage()
+	owned := 0
+	for _, frames := range usage.Processes {
+		owned += frames
+	}
+	if owned+usage.FreeFrames != usage.NumFrames {
+		t.Errorf("frames were lost: %d owned + %d free != %d", owned, usage.FreeFrames, usage.NumFrames)
+	}
+}
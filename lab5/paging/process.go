@@ -1,5 +1,12 @@
 package paging
 
+import "io"
+
+var (
+	_ io.ReaderAt = (*Process)(nil)
+	_ io.WriterAt = (*Process)(nil)
+)
+
 // Process simulates a (highly simplified) process
 type Process struct {
 	pid int
@@ -16,11 +23,39 @@ func (p *Process) Malloc(n int) (err error) {
 	return p.mmu.Alloc(p.pid, n)
 }
 
-// Free frees n pages from p, starting from the end of its address space
-func (p *Process) Free(n int) {
-	if n > 0 {
-		_ = p.mmu.Free(p.pid, n)
+// MallocHuge requests that the MMU allocates n bytes to this process using huge pages
+func (p *Process) MallocHuge(n int) (err error) {
+	return p.mmu.AllocHuge(p.pid, n)
+}
+
+// Free frees n pages from p, starting from the end of its address space. Freeing n <= 0
+// pages does nothing, and succeeds even if p has no memory
+func (p *Process) Free(n int) error {
+	if n <= 0 {
+		return nil
 	}
+	return p.mmu.Free(p.pid, n)
+}
+
+// Exit terminates p and returns all of its memory to the MMU
+func (p *Process) Exit() error {
+	return p.mmu.Exit(p.pid)
+}
+
+// OnPressure makes the MMU call fn when the memory pressure level changes, or when p is
+// killed by the OOM killer, for as long as p exists
+func (p *Process) OnPressure(fn func(PressureEvent)) {
+	p.mmu.SubscribePressure(p.pid, fn)
+}
+
+// SetMemoryLimit limits p to at most frames frames of memory; 0 removes the limit
+func (p *Process) SetMemoryLimit(frames int) {
+	p.mmu.SetMemoryLimit(p.pid, frames)
+}
+
+// PID returns the process id of p
+func (p *Process) PID() int {
+	return p.pid
 }
 
 // Read tries to read length bytes starting from virtualAddress
@@ -32,3 +67,18 @@ func (p *Process) Read(virtualAddress, length int) (content []byte, err error) {
 func (p *Process) Write(virtualAddress int, message []byte) (err error) {
 	return p.mmu.Write(p.pid, virtualAddress, message)
 }
+
+// ReadAt reads len(b) bytes from the address space of p starting at virtual address off.
+// It implements io.ReaderAt, returning io.EOF if the end of the address space is reached.
+func (p *Process) ReadAt(b []byte, off int64) (n int, err error) {
+	return p.mmu.readAt(p.pid, b, int(off))
+}
+
+// WriteAt writes b to the address space of p starting at virtual address off.
+// It implements io.WriterAt, extending the address space like Write if needed.
+func (p *Process) WriteAt(b []byte, off int64) (n int, err error) {
+	if err := p.mmu.Write(p.pid, int(off), b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,202 @@
+package paging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestProcessReadAt(t *testing.T) {
+	mmu := NewMMU(32, 4)
+	p := NewProcess(0, mmu)
+	if err := p.Malloc(10); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Write(0, []byte("0123456789ab")); err != nil {
+		t.Fatal(err)
+	}
+
+	var readAtTests = []struct {
+		off     int64
+		n       int
+		want    string
+		wantErr error
+	}{
+		{off: 0, n: 12, want: "0123456789ab"},
+		{off: 3, n: 6, want: "345678"},
+		{off: 8, n: 8, want: "89ab", wantErr: io.EOF},
+		{off: 12, n: 1, want: "", wantErr: io.EOF},
+		{off: -1, n: 1, want: "", wantErr: errAddressOutOfBounds},
+	}
+	for _, test := range readAtTests {
+		b := make([]byte, test.n)
+		n, err := p.ReadAt(b, test.off)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("ReadAt(len = %d, off = %d): want error '%v', got '%v'", test.n, test.off, test.wantErr, err)
+		}
+		if got := string(b[:n]); got != test.want {
+			t.Errorf("ReadAt(len = %d, off = %d) = %q, want %q", test.n, test.off, got, test.want)
+		}
+	}
+
+	// the io.ReaderAt view composes with the standard library
+	got, err := io.ReadAll(io.NewSectionReader(p, 2, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]byte("23456789ab"), got); diff != "" {
+		t.Errorf("Unexpected content from io.SectionReader; (-want +got):\n%s", diff)
+	}
+}
+
+func TestProcessFree(t *testing.T) {
+	mmu := NewMMU(32, 4)
+	p := NewProcess(0, mmu)
+	// freeing nothing is allowed before p has any memory
+	for _, n := range []int{0, -1} {
+		if err := p.Free(n); err != nil {
+			t.Errorf("Free(%d) of a process without memory: %v", n, err)
+		}
+	}
+	if err := p.Malloc(12); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Free(-1); err != nil {
+		t.Errorf("Free(-1): %v", err)
+	}
+	if err := p.Free(2); err != nil {
+		t.Fatal(err)
+	}
+	if frames, err := mmu.FramesOf(0); err != nil || len(frames) != 1 {
+		t.Errorf("after Free(-1) and Free(2) of 3 pages, the process has the frames %v (%v), want 1", frames, err)
+	}
+	if err := p.Free(2); !errors.Is(err, errFreeOutOfBounds) {
+		t.Errorf("Free() of more pages than the process has: want '%v', got '%v'", errFreeOutOfBounds, err)
+	}
+}
+
+func TestProcessWriteAt(t *testing.T) {
+	mmu := NewMMU(32, 4)
+	p := NewProcess(0, mmu)
+	if err := p.Malloc(4); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := p.WriteAt([]byte("hello, world"), 2)
+	if err != nil || n != 12 {
+		t.Fatalf("WriteAt() = (%d, %v), want (12, <nil>)", n, err)
+	}
+	frames, _ := mmu.FramesOf(0)
+	if len(frames) != 4 {
+		t.Errorf("WriteAt() should extend the address space to 4 pages, got %d", len(frames))
+	}
+	if _, err := p.WriteAt([]byte("x"), 16); err == nil {
+		t.Errorf("WriteAt() outside of the address space should fail")
+	}
+
+	got, err := p.Read(0, 14)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(append([]byte{0, 0}, "hello, world"...), got); diff != "" {
+		t.Errorf("Unexpected content after WriteAt(); (-want +got):\n%s", diff)
+	}
+}
+
+// readBytewise reads n bytes the way MMU.Read used to: translating the virtual address
+// of every single byte and appending it to the result. It is kept as a benchmark baseline.
+func readBytewise(mmu *MMU, pid, virtualAddress, n int) (content []byte, err error) {
+	pageTable, err := mmu.getPageTable(pid)
+	if err != nil {
+		return nil, err
+	}
+	for i := 0; i < n; i++ {
+		vpn, offset, err := mmu.translateAndCheck(pid, virtualAddress+i)
+		if err != nil {
+			return nil, err
+		}
+		frame, _ := pageTable.Lookup(vpn)
+		content = append(content, mmu.frames[frame][offset])
+	}
+	return content, nil
+}
+
+// writeBytewise writes content the way MMU.Write used to: one byte at a time.
+func writeBytewise(mmu *MMU, pid, virtualAddress int, content []byte) error {
+	pageTable, err := mmu.getPageTable(pid)
+	if err != nil {
+		return err
+	}
+	for i, b := range content {
+		vpn, offset, err := mmu.translateAndCheck(pid, virtualAddress+i)
+		if err != nil {
+			return err
+		}
+		frame, _ := pageTable.Lookup(vpn)
+		mmu.frames[frame][offset] = b
+	}
+	return nil
+}
+
+var transferSizes = []int{1 << 20, 4 << 20}
+
+// newTransferMMU returns an MMU where process 0 has size bytes of memory filled with data.
+func newTransferMMU(b *testing.B, size int) *MMU {
+	mmu := NewMMU(size, 4096)
+	if err := mmu.Alloc(0, size); err != nil {
+		b.Fatal(err)
+	}
+	if err := mmu.Write(0, 0, bytes.Repeat([]byte("paging"), size/6)); err != nil {
+		b.Fatal(err)
+	}
+	return mmu
+}
+
+func BenchmarkRead(b *testing.B) {
+	for _, size := range transferSizes {
+		mmu := newTransferMMU(b, size)
+		b.Run(fmt.Sprintf("bulk/%dMB", size>>20), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				if _, err := mmu.Read(0, 0, size); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("bytewise/%dMB", size>>20), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				if _, err := readBytewise(mmu, 0, 0, size); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWrite(b *testing.B) {
+	for _, size := range transferSizes {
+		mmu := newTransferMMU(b, size)
+		content := bytes.Repeat([]byte("PAGING"), size/6)
+		b.Run(fmt.Sprintf("bulk/%dMB", size>>20), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				if err := mmu.Write(0, 0, content); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("bytewise/%dMB", size>>20), func(b *testing.B) {
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				if err := writeBytewise(mmu, 0, 0, content); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package paging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errReplayConfig = errors.New("invalid replay configuration")
+
+// ReplayConfig describes the MMU a trace is replayed against.
+// A zero MemSize or FrameSize means the value from the trace header is used.
+type ReplayConfig struct {
+	MemSize   int
+	FrameSize int
+	Policy    FramePolicy
+	HugePages bool // replay every Alloc as AllocHuge
+}
+
+func (cfg ReplayConfig) String() string {
+	return fmt.Sprintf("memSize=%d frameSize=%d policy=%v hugePages=%t", cfg.MemSize, cfg.FrameSize, cfg.Policy, cfg.HugePages)
+}
+
+// ReplayResult summarizes the outcome of replaying a trace.
+type ReplayResult struct {
+	Config       ReplayConfig    // the configuration that was replayed, with defaults filled in
+	Events       int             // number of events replayed
+	Failed       map[TraceOp]int // number of events of each kind that returned an error
+	OutOfMemory  int             // Alloc, AllocHuge and Write events that failed because memory was exhausted
+	PeakFrames   int             // largest number of frames in use at the same time
+	BytesRead    int             // bytes successfully read
+	BytesWritten int             // bytes successfully written
+	Usage        MemoryUsage     // memory usage and TLB statistics after the last event
+}
+
+// Replay performs every event in the trace against a new MMU configured by cfg.
+// Written content is not part of the trace, so each Write writes a filler pattern of the
+// recorded length. Free events are recorded in pages of the trace's frame size; when the
+// frame size differs, they are converted to free the same number of bytes, rounded up to
+// whole pages and limited to the pages the process has. Failing events are counted in the
+// result rather than stopping the replay; the returned error is only for a broken trace or an
+// invalid configuration.
+func Replay(tr *TraceReader, cfg ReplayConfig) (ReplayResult, error) {
+	header := tr.Header()
+	if cfg.MemSize == 0 {
+		cfg.MemSize = header.MemSize
+	}
+	if cfg.FrameSize == 0 {
+		cfg.FrameSize = header.FrameSize
+	}
+	if err := checkMemorySize(cfg.MemSize, cfg.FrameSize); err != nil {
+		return ReplayResult{Config: cfg}, fmt.Errorf("%w: %v", errReplayConfig, err)
+	}
+	if cfg.Policy != LowestFirst && cfg.Policy != NextFit {
+		return ReplayResult{Config: cfg}, fmt.Errorf("%w: unknown frame policy %v", errReplayConfig, cfg.Policy)
+	}
+	mmu := NewMMU(cfg.MemSize, cfg.FrameSize)
+	mmu.SetFramePolicy(cfg.Policy)
+
+	res := ReplayResult{Config: cfg, Failed: make(map[TraceOp]int)}
+	var filler []byte
+	for {
+		ev, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return res, err
+		}
+		res.Events++
+
+		switch ev.Op {
+		case TraceAlloc:
+			if cfg.HugePages {
+				err = mmu.AllocHuge(ev.PID, ev.N)
+			} else {
+				err = mmu.Alloc(ev.PID, ev.N)
+			}
+		case TraceAllocHuge:
+			err = mmu.AllocHuge(ev.PID, ev.N)
+		case TraceWrite:
+			for len(filler) < ev.N {
+				filler = append(filler, byte(len(filler)))
+			}
+			if err = mmu.Write(ev.PID, ev.Addr, filler[:ev.N]); err == nil {
+				res.BytesWritten += ev.N
+			}
+		case TraceRead:
+			if _, err = mmu.Read(ev.PID, ev.Addr, ev.N); err == nil {
+				res.BytesRead += ev.N
+			}
+		case TraceFree:
+			err = mmu.Free(ev.PID, replayFreePages(mmu, header, ev))
+		case TraceExit:
+			err = mmu.Exit(ev.PID)
+		}
+
+		if err != nil {
+			res.Failed[ev.Op]++
+			if errors.Is(err, errOutOfMemory) {
+				res.OutOfMemory++
+			}
+		}
+		if used := len(mmu.frames) - mmu.NumFreeFrames(); used > res.PeakFrames {
+			res.PeakFrames = used
+		}
+	}
+	res.Usage = mmu.Usage()
+	return res, nil
+}
+
+// replayFreePages returns the number of pages to free in mmu for the Free event ev,
+// which was recorded on an MMU described by header.
+func replayFreePages(mmu *MMU, header TraceHeader, ev TraceEvent) int {
+	if header.FrameSize == len(mmu.frames[0]) || ev.N < 1 {
+		return ev.N
+	}
+	n := mmu.framesNeeded(ev.N * header.FrameSize)
+	if frames, err := mmu.FramesOf(ev.PID); err == nil && n > len(frames) {
+		n = len(frames)
+	}
+	return n
+}
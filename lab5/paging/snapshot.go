@@ -0,0 +1,350 @@
+package paging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// A snapshot holds the complete state of an MMU. It starts with a header:
+//
+//	magic "MMUS" | version (1 byte) | frameSize (uvarint) | numFrames (uvarint)
+//
+// followed by the free list, the frames, the page tables and the TLB:
+//
+//	policy (uvarint) | next (uvarint) | free list (one bit per frame, least significant bit first)
+//	frames (numFrames * frameSize bytes)
+//	numProcesses (uvarint) | per process in increasing pid order:
+//		pid | numPages | frame of each page | numHuge | first page and number of pages of each huge page
+//	tlbSize | hits | misses | numEntries | per entry from least to most recently used:
+//		pid | firstVPN | pages | frame
+//	low | critical | OOM kills | numLimits | pid and limit of each limited process (since version 2)
+//
+// All numbers except the version are uvarints. The tracer, the pressure subscribers and the
+// OOM killer of the MMU are functions, so they are not part of the snapshot.
+const (
+	snapshotMagic   = "MMUS"
+	snapshotVersion = 2
+
+	// maxSnapshotMemory is the largest memory a snapshot is restored with, since all of
+	// it is allocated before the frames are read.
+	maxSnapshotMemory = 1 << 30
+)
+
+var (
+	errSnapshotMagic   = errors.New("not an MMU snapshot")
+	errSnapshotVersion = errors.New("unsupported MMU snapshot version")
+	errSnapshotCorrupt = errors.New("corrupt MMU snapshot")
+)
+
+// Snapshot writes the complete state of the MMU to w: the content of every frame,
+// the free list and its policy, every page table including its huge pages, and the TLB.
+// The state is captured consistently even while processes are running: allocations and
+// exits in progress are waited for, so that every frame is either free or mapped.
+func (mmu *MMU) Snapshot(w io.Writer) error {
+	mmu.moving.Lock()
+	defer mmu.moving.Unlock()
+	pageTables, unlock := mmu.lockAllPageTables()
+	defer unlock()
+	mmu.freeLock.Lock()
+	defer mmu.freeLock.Unlock()
+	mmu.tlb.mu.Lock()
+	defer mmu.tlb.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	buf := make([]byte, 0, binary.MaxVarintLen64)
+	put := func(v int) {
+		buf = binary.AppendUvarint(buf[:0], uint64(v))
+		bw.Write(buf)
+	}
+
+	bw.WriteString(snapshotMagic)
+	bw.WriteByte(snapshotVersion)
+	put(len(mmu.frames[0]))
+	put(len(mmu.frames))
+
+	put(int(mmu.policy))
+	put(mmu.next)
+	bits := make([]byte, (len(mmu.freeList.freeList)+7)/8)
+	for i, free := range mmu.freeList.freeList {
+		if free {
+			bits[i/8] |= 1 << (i % 8)
+		}
+	}
+	bw.Write(bits)
+	for _, frame := range mmu.frames {
+		bw.Write(frame)
+	}
+
+	pids := make([]int, 0, len(pageTables))
+	owner := make(map[*PageTable]int, len(pageTables))
+	for pid, pageTable := range pageTables {
+		pids = append(pids, pid)
+		owner[pageTable] = pid
+	}
+	sort.Ints(pids)
+	put(len(pids))
+	for _, pid := range pids {
+		pageTable := pageTables[pid]
+		put(pid)
+		put(pageTable.Len())
+		for _, frame := range pageTable.frameIndices {
+			put(frame)
+		}
+		firsts := make([]int, 0, len(pageTable.huge))
+		for first := range pageTable.huge {
+			firsts = append(firsts, first)
+		}
+		sort.Ints(firsts)
+		put(len(firsts))
+		for _, first := range firsts {
+			put(first)
+			put(pageTable.huge[first])
+		}
+	}
+
+	t := mmu.tlb
+	put(t.size)
+	put(t.hits)
+	put(t.misses)
+	put(len(t.entries))
+	for _, e := range t.entries {
+		put(owner[e.pageTable])
+		put(e.firstVPN)
+		put(e.pages)
+		put(e.frame)
+	}
+
+	mmu.pressure.mu.Lock()
+	defer mmu.pressure.mu.Unlock()
+	put(mmu.pressure.low)
+	put(mmu.pressure.critical)
+	put(mmu.pressure.kills)
+	limited := make([]int, 0, len(mmu.pressure.limits))
+	for pid := range mmu.pressure.limits {
+		limited = append(limited, pid)
+	}
+	sort.Ints(limited)
+	put(len(limited))
+	for _, pid := range limited {
+		put(pid)
+		put(mmu.pressure.limits[pid])
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed writing MMU snapshot: %w", err)
+	}
+	return nil
+}
+
+// RestoreMMU creates a new MMU from a snapshot written by Snapshot. The restored MMU
+// is identical to the one the snapshot was taken of, except that it has no tracer,
+// pressure subscribers or OOM killer. Snapshots of older versions are also accepted.
+func RestoreMMU(r io.Reader) (*MMU, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(snapshotMagic)+1)
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, fmt.Errorf("failed reading MMU snapshot: %w", err)
+	}
+	if string(magic[:len(snapshotMagic)]) != snapshotMagic {
+		return nil, errSnapshotMagic
+	}
+	version := magic[len(snapshotMagic)]
+	if version < 1 || version > snapshotVersion {
+		return nil, fmt.Errorf("%w: %d", errSnapshotVersion, version)
+	}
+
+	// the first error is kept, and all later reads return zero
+	var err error
+	next := func() int {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(br)
+		if err == nil && v > 1<<40 {
+			err = fmt.Errorf("%w: value %d out of range", errSnapshotCorrupt, v)
+		}
+		return int(v)
+	}
+	// count reads a number of elements, of which there cannot be more than max
+	count := func(what string, max int) int {
+		n := next()
+		if err == nil && n > max {
+			err = fmt.Errorf("%w: %d %s, at most %d possible", errSnapshotCorrupt, n, what, max)
+		}
+		if err != nil {
+			return 0
+		}
+		return n
+	}
+	// pids are written as uvarints of their two's complement, so a negative pid is read as a
+	// very large number
+	nextPID := func() int {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(br)
+		if err == nil && int(v) < 0 {
+			err = fmt.Errorf("%w: negative pid %d", errSnapshotCorrupt, int(v))
+		}
+		return int(v)
+	}
+	readFull := func(p []byte) {
+		if err == nil {
+			_, err = io.ReadFull(br, p)
+		}
+	}
+	fail := func(err error) (*MMU, error) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("failed reading MMU snapshot: %w", err)
+	}
+
+	frameSize := next()
+	numFrames := next()
+	if err != nil {
+		return fail(err)
+	}
+	if frameSize < 1 || frameSize&(frameSize-1) != 0 {
+		return fail(fmt.Errorf("%w: frame size %d is not a power of two", errSnapshotCorrupt, frameSize))
+	}
+	if numFrames < 1 || numFrames > maxSnapshotMemory/frameSize {
+		return fail(fmt.Errorf("%w: %d frames of %d bytes, at most %d bytes possible", errSnapshotCorrupt, numFrames, frameSize, maxSnapshotMemory))
+	}
+	mmu := NewMMU(numFrames*frameSize, frameSize)
+
+	mmu.policy = FramePolicy(next())
+	mmu.next = next()
+	bits := make([]byte, (numFrames+7)/8)
+	readFull(bits)
+	if err != nil {
+		return fail(err)
+	}
+	if mmu.policy != LowestFirst && mmu.policy != NextFit {
+		return fail(fmt.Errorf("%w: unknown frame policy %d", errSnapshotCorrupt, mmu.policy))
+	}
+	if mmu.next >= numFrames {
+		return fail(fmt.Errorf("%w: next fit cursor %d past the last frame", errSnapshotCorrupt, mmu.next))
+	}
+	mmu.numFreeFrames = 0
+	for i := range mmu.freeList.freeList {
+		mmu.freeList.freeList[i] = bits[i/8]&(1<<(i%8)) != 0
+		if mmu.freeList.freeList[i] {
+			mmu.numFreeFrames++
+		}
+	}
+	for _, frame := range mmu.frames {
+		readFull(frame)
+	}
+
+	// every frame must be either free or mapped by exactly one page
+	owned := make([]bool, numFrames)
+	copy(owned, mmu.freeList.freeList)
+	numProcesses := count("processes", numFrames)
+	for i := 0; i < numProcesses && err == nil; i++ {
+		pid := nextPID()
+		pageTable := &PageTable{frameIndices: make([]int, count("pages", numFrames))}
+		for vpn := range pageTable.frameIndices {
+			frame := next()
+			if err == nil && (frame >= numFrames || owned[frame]) {
+				err = fmt.Errorf("%w: frame %d of process %d is free or mapped twice", errSnapshotCorrupt, frame, pid)
+			}
+			if err == nil {
+				owned[frame] = true
+			}
+			pageTable.frameIndices[vpn] = frame
+		}
+		numHuge := count("huge pages", pageTable.Len()/HugePageFrames)
+		if numHuge > 0 {
+			pageTable.huge = make(map[int]int, numHuge)
+		}
+		for j := 0; j < numHuge && err == nil; j++ {
+			first, pages := next(), next()
+			if err == nil && (pages != HugePageFrames || first%HugePageFrames != 0 || first+pages > pageTable.Len()) {
+				err = fmt.Errorf("%w: huge page of %d pages at page %d is not aligned in the address space of process %d", errSnapshotCorrupt, pages, first, pid)
+			}
+			if err == nil && pageTable.huge[first] != 0 {
+				err = fmt.Errorf("%w: huge page at page %d of process %d appears twice", errSnapshotCorrupt, first, pid)
+			}
+			if err == nil && (pageTable.frameIndices[first]%HugePageFrames != 0 || !contiguous(pageTable.frameIndices[first:first+pages])) {
+				err = fmt.Errorf("%w: huge page at page %d of process %d is not aligned and contiguous in memory", errSnapshotCorrupt, first, pid)
+			}
+			pageTable.huge[first] = pages
+		}
+		if _, ok := mmu.processes[pid]; ok && err == nil {
+			err = fmt.Errorf("%w: process %d appears twice", errSnapshotCorrupt, pid)
+		}
+		mmu.processes[pid] = pageTable
+		mmu.ptLocks[pid] = new(sync.RWMutex)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	for frame, ok := range owned {
+		if !ok {
+			return fail(fmt.Errorf("%w: frame %d is neither free nor mapped", errSnapshotCorrupt, frame))
+		}
+	}
+
+	tlbSize := count("TLB entries", 1<<20)
+	if err == nil && tlbSize < 1 {
+		err = fmt.Errorf("%w: TLB without entries", errSnapshotCorrupt)
+	}
+	if err != nil {
+		return fail(err)
+	}
+	mmu.tlb = newTLB(tlbSize)
+	mmu.tlb.hits = next()
+	mmu.tlb.misses = next()
+	numEntries := count("TLB entries", mmu.tlb.size)
+	for i := 0; i < numEntries && err == nil; i++ {
+		e := tlbEntry{pageTable: mmu.processes[nextPID()], firstVPN: next(), pages: next(), frame: next()}
+		// the TLB translates every page of an entry relative to its first frame
+		if err == nil && (e.pageTable == nil || e.pages < 1 || e.firstVPN+e.pages > e.pageTable.Len() ||
+			e.frame != e.pageTable.frameIndices[e.firstVPN] || !contiguous(e.pageTable.frameIndices[e.firstVPN:e.firstVPN+e.pages])) {
+			err = fmt.Errorf("%w: TLB entry %d does not match any page table", errSnapshotCorrupt, i)
+		}
+		mmu.tlb.entries = append(mmu.tlb.entries, e)
+	}
+	if err != nil {
+		return fail(err)
+	}
+
+	if version >= 2 {
+		p := &mmu.pressure
+		p.low, p.critical, p.kills = next(), next(), next()
+		if err == nil && (p.critical > p.low || p.low > numFrames) {
+			err = fmt.Errorf("%w: %v", errSnapshotCorrupt, errInvalidWatermarks)
+		}
+		numLimits := count("memory limits", 1<<20)
+		if numLimits > 0 {
+			p.limits = make(map[int]int, numLimits)
+		}
+		for i := 0; i < numLimits && err == nil; i++ {
+			pid := nextPID()
+			p.limits[pid] = next()
+		}
+		if err != nil {
+			return fail(err)
+		}
+		p.level = p.levelOf(mmu.numFreeFrames)
+	}
+	return mmu, nil
+}
+
+// contiguous reports whether frames are consecutive physical frames.
+func contiguous(frames []int) bool {
+	for i, frame := range frames {
+		if frame != frames[0]+i {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,219 @@
+package paging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/rand"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// randomWorkload runs a randomized mix of operations against mmu, ignoring their errors.
+func randomWorkload(mmu *MMU, rng *rand.Rand, ops int) {
+	for i := 0; i < ops; i++ {
+		pid := rng.Intn(4)
+		switch rng.Intn(6) {
+		case 0:
+			_ = mmu.Alloc(pid, 1+rng.Intn(64))
+		case 1:
+			_ = mmu.AllocHuge(pid, 1+rng.Intn(64))
+		case 2:
+			content := make([]byte, 1+rng.Intn(32))
+			rng.Read(content)
+			_ = mmu.Write(pid, rng.Intn(256), content)
+		case 3:
+			_, _ = mmu.Read(pid, rng.Intn(256), 1+rng.Intn(32))
+		case 4:
+			_ = mmu.Free(pid, 1+rng.Intn(8))
+		case 5:
+			_ = mmu.Exit(pid)
+		}
+	}
+}
+
+func snapshot(t *testing.T, mmu *MMU) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := mmu.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	mmu := NewMMU(128*4, 4)
+	mmu.SetFramePolicy(NextFit)
+	randomWorkload(mmu, rng, 500)
+	data := snapshot(t, mmu)
+
+	restored, err := RestoreMMU(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("RestoreMMU() failed: %v", err)
+	}
+	if diff := cmp.Diff(mmu.Usage(), restored.Usage()); diff != "" {
+		t.Errorf("Unexpected memory usage after RestoreMMU(); (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(mmu.processes, restored.processes, cmp.AllowUnexported(PageTable{})); diff != "" {
+		t.Errorf("Unexpected page tables after RestoreMMU(); (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(mmu.frames, restored.frames); diff != "" {
+		t.Errorf("Unexpected frames after RestoreMMU(); (-want +got):\n%s", diff)
+	}
+	if mmu.policy != restored.policy || mmu.next != restored.next {
+		t.Errorf("RestoreMMU() gave policy %v at frame %d, want %v at frame %d", restored.policy, restored.next, mmu.policy, mmu.next)
+	}
+	if !bytes.Equal(data, snapshot(t, restored)) {
+		t.Errorf("snapshot of the restored MMU differs from the original snapshot")
+	}
+
+	// the same operations lead to the same state in both MMUs
+	randomWorkload(mmu, rand.New(rand.NewSource(2)), 500)
+	randomWorkload(restored, rand.New(rand.NewSource(2)), 500)
+	if !bytes.Equal(snapshot(t, mmu), snapshot(t, restored)) {
+		t.Errorf("the restored MMU behaves differently from the original")
+	}
+}
+
+// runAllocExit lets goroutines allocate memory and exit until stop is closed, and
+// returns a function that waits for them to return.
+func runAllocExit(mmu *MMU, goroutines int, stop <-chan struct{}) (wait func()) {
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(g)))
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// each goroutine has its own pids, so that it can exit them
+				pid := g*4 + i%4
+				switch rng.Intn(3) {
+				case 0:
+					_ = mmu.Alloc(pid, 1+rng.Intn(8*mmu.FrameSize()))
+				case 1:
+					_ = mmu.AllocHuge(pid, 1)
+				default:
+					_ = mmu.Exit(pid)
+				}
+			}
+		}(g)
+	}
+	return wg.Wait
+}
+
+func TestSnapshotWhileRunning(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	mmu := NewMMU(256*4, 4)
+	stop := make(chan struct{})
+	wait := runAllocExit(mmu, 4, stop)
+	defer wait()
+	defer close(stop)
+
+	for i := 0; i < 500; i++ {
+		if _, err := RestoreMMU(bytes.NewReader(snapshot(t, mmu))); err != nil {
+			t.Fatalf("RestoreMMU() of snapshot %d taken while processes allocate and exit: %v", i, err)
+		}
+	}
+}
+
+func TestRestoreInconsistentSnapshot(t *testing.T) {
+	// each test breaks the state of an MMU by hand before it is snapshotted
+	tests := []struct {
+		name       string
+		breakState func(mmu *MMU, pageTable *PageTable)
+	}{
+		{"frame policy", func(mmu *MMU, _ *PageTable) { mmu.policy = NextFit + 1 }},
+		{"pid", func(mmu *MMU, pageTable *PageTable) {
+			mmu.processes[-1], mmu.ptLocks[-1] = pageTable, mmu.ptLocks[0]
+			delete(mmu.processes, 0)
+		}},
+		{"huge page size", func(_ *MMU, pageTable *PageTable) { pageTable.huge[HugePageFrames] = HugePageFrames - 1 }},
+		{"huge page alignment", func(_ *MMU, pageTable *PageTable) {
+			delete(pageTable.huge, HugePageFrames)
+			pageTable.huge[1] = HugePageFrames
+		}},
+		{"huge page frames", func(_ *MMU, pageTable *PageTable) {
+			f := pageTable.frameIndices
+			f[HugePageFrames], f[HugePageFrames+1] = f[HugePageFrames+1], f[HugePageFrames]
+		}},
+		{"TLB entry", func(mmu *MMU, pageTable *PageTable) {
+			f := pageTable.frameIndices
+			f[0], f[1] = f[1], f[0]
+			mmu.tlb.entries = append(mmu.tlb.entries, tlbEntry{pageTable: pageTable, firstVPN: 0, pages: 2, frame: f[0]})
+		}},
+	}
+	for _, test := range tests {
+		mmu := NewMMU(64*4, 4)
+		if err := mmu.Alloc(0, 2*4); err != nil {
+			t.Fatal(err)
+		}
+		if err := mmu.AllocHuge(0, HugePageFrames*4); err != nil {
+			t.Fatal(err)
+		}
+		test.breakState(mmu, mmu.processes[0])
+		if _, err := RestoreMMU(bytes.NewReader(snapshot(t, mmu))); !errors.Is(err, errSnapshotCorrupt) {
+			t.Errorf("RestoreMMU() of a snapshot with a bad %s: want '%v', got '%v'", test.name, errSnapshotCorrupt, err)
+		}
+	}
+}
+
+func TestRestoreInvalidSnapshot(t *testing.T) {
+	mmu := NewMMU(16*4, 4)
+	if err := mmu.Alloc(0, 8); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mmu.Read(0, 0, 8); err != nil {
+		t.Fatal(err)
+	}
+	data := snapshot(t, mmu)
+
+	// the header is followed by policy, next and two bytes of free list bits
+	corruptFreeList := append([]byte(nil), data...)
+	corruptFreeList[len(snapshotMagic)+5] |= 1 // mark frame 0 free while process 0 maps it
+
+	// a header that asks for more memory than is restored, or an odd frame size
+	header := func(frameSize, numFrames uint64) []byte {
+		b := append([]byte(snapshotMagic), snapshotVersion)
+		b = binary.AppendUvarint(b, frameSize)
+		return binary.AppendUvarint(b, numFrames)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"magic", append([]byte("MMUX"), data[4:]...), errSnapshotMagic},
+		{"version", append([]byte{'M', 'M', 'U', 'S', snapshotVersion + 1}, data[5:]...), errSnapshotVersion},
+		{"truncated", data[:len(data)-3], io.ErrUnexpectedEOF},
+		{"free list", corruptFreeList, errSnapshotCorrupt},
+		{"memory size", header(4096, 1<<28), errSnapshotCorrupt},
+		{"frame size", header(12, 16), errSnapshotCorrupt},
+	}
+	for _, test := range tests {
+		if _, err := RestoreMMU(bytes.NewReader(test.data)); !errors.Is(err, test.want) {
+			t.Errorf("RestoreMMU() of a snapshot with a bad %s: want '%v', got '%v'", test.name, test.want, err)
+		}
+	}
+
+	// a version 1 snapshot is the same without the memory pressure section,
+	// which is four zero bytes when there are no watermarks, kills or limits
+	v1 := append([]byte("MMUS\x01"), data[5:len(data)-4]...)
+	restored, err := RestoreMMU(bytes.NewReader(v1))
+	if err != nil {
+		t.Fatalf("RestoreMMU() of a version 1 snapshot failed: %v", err)
+	}
+	if !bytes.Equal(data, snapshot(t, restored)) {
+		t.Errorf("MMU restored from a version 1 snapshot differs from the original")
+	}
+}
@@ -0,0 +1,122 @@
+package paging
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DefaultTLBEntries is the number of translations cached by the TLB of a new MMU.
+const DefaultTLBEntries = 16
+
+// TLBStats describes how well the TLB has served translations.
+type TLBStats struct {
+	Capacity int // maximum number of cached translations
+	Entries  int // number of cached translations
+	Hits     int // translations served from the TLB
+	Misses   int // translations that required a page table walk
+	Reach    int // bytes of memory covered by the cached translations
+}
+
+// HitRate returns the fraction of translations served from the TLB.
+func (s TLBStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (s TLBStats) String() string {
+	return fmt.Sprintf("TLB: %d/%d entries, reach %d bytes, %d hits, %d misses (hit rate %.2f)",
+		s.Entries, s.Capacity, s.Reach, s.Hits, s.Misses, s.HitRate())
+}
+
+// tlbEntry caches the translation of one mapping, which is either a single regular page
+// or a whole huge page.
+type tlbEntry struct {
+	pageTable *PageTable // address space of the mapping, like an address space identifier
+	firstVPN  int        // first virtual page number of the mapping
+	pages     int        // number of pages in the mapping
+	frame     int        // physical frame of the first page of the mapping
+}
+
+// tlb is a fully associative translation lookaside buffer with LRU replacement.
+// Since one entry covers a whole huge page, huge pages let the TLB reach more memory.
+type tlb struct {
+	mu      sync.Mutex
+	entries []tlbEntry // least recently used first
+	size    int
+	hits    int
+	misses  int
+}
+
+func newTLB(size int) *tlb {
+	return &tlb{entries: make([]tlbEntry, 0, size), size: size}
+}
+
+// lookup returns the physical frame of virtual page vpn in pageTable, walking the
+// page table and caching the translation of the mapping on a miss.
+// The caller must hold the lock of the page table.
+func (mmu *MMU) lookup(pageTable *PageTable, vpn int) (frameIndex int, err error) {
+	t := mmu.tlb
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, e := range t.entries {
+		if e.pageTable == pageTable && vpn >= e.firstVPN && vpn < e.firstVPN+e.pages {
+			t.hits++
+			// move the entry to the most recently used position
+			copy(t.entries[i:], t.entries[i+1:])
+			t.entries[len(t.entries)-1] = e
+			return e.frame + vpn - e.firstVPN, nil
+		}
+	}
+
+	t.misses++
+	frameIndex, err = pageTable.Lookup(vpn)
+	if err != nil {
+		return NoEntry, err
+	}
+	e := tlbEntry{pageTable: pageTable, firstVPN: vpn, pages: 1, frame: frameIndex}
+	if first, pages, ok := pageTable.hugePage(vpn, HugePageFrames); ok {
+		e.firstVPN, e.pages = first, pages
+		e.frame = pageTable.frameIndices[first]
+	}
+	if len(t.entries) == t.size {
+		t.entries = append(t.entries[:0], t.entries[1:]...)
+	}
+	t.entries = append(t.entries, e)
+	return frameIndex, nil
+}
+
+// flush removes all cached translations for pageTable. It must be called whenever
+// pages are removed from a page table. The caller must hold the lock of the page table.
+func (t *tlb) flush(pageTable *PageTable) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	kept := t.entries[:0]
+	for _, e := range t.entries {
+		if e.pageTable != pageTable {
+			kept = append(kept, e)
+		}
+	}
+	for i := len(kept); i < len(t.entries); i++ {
+		t.entries[i] = tlbEntry{} // don't keep removed page tables alive
+	}
+	t.entries = kept
+}
+
+// stats returns the TLB statistics, given that frames are frameSize bytes long.
+func (t *tlb) stats(frameSize int) TLBStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := TLBStats{Capacity: t.size, Entries: len(t.entries), Hits: t.hits, Misses: t.misses}
+	for _, e := range t.entries {
+		s.Reach += e.pages * frameSize
+	}
+	return s
+}
+
+// TLBStats returns statistics about the translations done by the MMU.
+func (mmu *MMU) TLBStats() TLBStats {
+	return mmu.tlb.stats(len(mmu.frames[0]))
+}
@@ -0,0 +1,306 @@
+package paging
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// A trace file starts with a header:
+//
+//	magic "MMUT" | version (1 byte) | memSize (uvarint) | frameSize (uvarint)
+//
+// followed by one record per operation:
+//
+//	op (1 byte) | pid (uvarint) | addr (uvarint, Read/Write only) | n (uvarint, all but Exit)
+const (
+	traceMagic   = "MMUT"
+	traceVersion = 1
+
+	// maxTraceMemory is the largest memory a trace is replayed on, since all of it is
+	// allocated when the MMU is created.
+	maxTraceMemory = 1 << 30
+)
+
+var (
+	errTraceMagic   = errors.New("not a trace file")
+	errTraceVersion = errors.New("unsupported trace file version")
+	errTraceOp      = errors.New("unknown trace operation")
+	errTraceHeader  = errors.New("invalid trace header")
+)
+
+// TraceOp identifies an MMU operation in a trace.
+type TraceOp byte
+
+// Operations that can be recorded in a trace.
+const (
+	TraceAlloc TraceOp = iota + 1
+	TraceWrite
+	TraceRead
+	TraceFree
+	TraceExit
+	TraceAllocHuge
+)
+
+// String returns the name of the MMU method corresponding to op.
+func (op TraceOp) String() string {
+	switch op {
+	case TraceAlloc:
+		return "Alloc"
+	case TraceWrite:
+		return "Write"
+	case TraceRead:
+		return "Read"
+	case TraceFree:
+		return "Free"
+	case TraceExit:
+		return "Exit"
+	case TraceAllocHuge:
+		return "AllocHuge"
+	}
+	return fmt.Sprintf("TraceOp(%d)", byte(op))
+}
+
+// TraceEvent is a single MMU operation recorded in a trace.
+// Only the length of written content is recorded, not the content itself.
+type TraceEvent struct {
+	Op   TraceOp
+	PID  int
+	Addr int // virtual address of Read and Write
+	N    int // bytes for Alloc, AllocHuge, Read and Write; pages for Free
+}
+
+func (ev TraceEvent) String() string {
+	switch ev.Op {
+	case TraceRead, TraceWrite:
+		return fmt.Sprintf("%v(pid = %d, addr = %d, n = %d)", ev.Op, ev.PID, ev.Addr, ev.N)
+	case TraceExit:
+		return fmt.Sprintf("%v(pid = %d)", ev.Op, ev.PID)
+	}
+	return fmt.Sprintf("%v(pid = %d, n = %d)", ev.Op, ev.PID, ev.N)
+}
+
+// TraceHeader describes the MMU a trace was recorded on.
+type TraceHeader struct {
+	MemSize   int
+	FrameSize int
+}
+
+// TraceWriter encodes trace events to an underlying writer.
+// It is safe for concurrent use; events are written in the order Record is called.
+type TraceWriter struct {
+	mu  sync.Mutex
+	w   *bufio.Writer
+	buf []byte
+	err error
+}
+
+// NewTraceWriter writes the trace header to w and returns a TraceWriter for the events.
+// Flush must be called when done recording.
+func NewTraceWriter(w io.Writer, header TraceHeader) (*TraceWriter, error) {
+	tw := &TraceWriter{w: bufio.NewWriter(w), buf: make([]byte, 0, 1+3*binary.MaxVarintLen64)}
+	tw.buf = append(tw.buf, traceMagic...)
+	tw.buf = append(tw.buf, traceVersion)
+	tw.buf = binary.AppendUvarint(tw.buf, uint64(header.MemSize))
+	tw.buf = binary.AppendUvarint(tw.buf, uint64(header.FrameSize))
+	if _, err := tw.w.Write(tw.buf); err != nil {
+		return nil, fmt.Errorf("failed writing trace header: %w", err)
+	}
+	return tw, nil
+}
+
+// Record appends ev to the trace. Once writing has failed, all later calls return the same error.
+func (tw *TraceWriter) Record(ev TraceEvent) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.err != nil {
+		return tw.err
+	}
+
+	tw.buf = append(tw.buf[:0], byte(ev.Op))
+	tw.buf = binary.AppendUvarint(tw.buf, uint64(ev.PID))
+	switch ev.Op {
+	case TraceRead, TraceWrite:
+		tw.buf = binary.AppendUvarint(tw.buf, uint64(ev.Addr))
+		tw.buf = binary.AppendUvarint(tw.buf, uint64(ev.N))
+	case TraceAlloc, TraceAllocHuge, TraceFree:
+		tw.buf = binary.AppendUvarint(tw.buf, uint64(ev.N))
+	case TraceExit:
+	default:
+		return fmt.Errorf("failed recording %v: %w", ev, errTraceOp)
+	}
+	if _, err := tw.w.Write(tw.buf); err != nil {
+		tw.err = fmt.Errorf("failed writing trace event: %w", err)
+	}
+	return tw.err
+}
+
+// Flush writes any buffered events to the underlying writer.
+func (tw *TraceWriter) Flush() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.err != nil {
+		return tw.err
+	}
+	return tw.w.Flush()
+}
+
+// TraceReader decodes trace events from an underlying reader.
+type TraceReader struct {
+	r      *bufio.Reader
+	header TraceHeader
+}
+
+// NewTraceReader reads the trace header from r and returns a TraceReader for the events.
+func NewTraceReader(r io.Reader) (*TraceReader, error) {
+	tr := &TraceReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(traceMagic)+1)
+	if _, err := io.ReadFull(tr.r, magic); err != nil {
+		return nil, fmt.Errorf("failed reading trace header: %w", err)
+	}
+	if string(magic[:len(traceMagic)]) != traceMagic {
+		return nil, errTraceMagic
+	}
+	if magic[len(traceMagic)] != traceVersion {
+		return nil, fmt.Errorf("%w: %d", errTraceVersion, magic[len(traceMagic)])
+	}
+	memSize, err := binary.ReadUvarint(tr.r)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading trace header: %w", err)
+	}
+	frameSize, err := binary.ReadUvarint(tr.r)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading trace header: %w", err)
+	}
+	if memSize > maxTraceMemory || frameSize > maxTraceMemory {
+		return nil, fmt.Errorf("%w: memory size %d and frame size %d, at most %d bytes possible", errTraceHeader, memSize, frameSize, maxTraceMemory)
+	}
+	tr.header = TraceHeader{MemSize: int(memSize), FrameSize: int(frameSize)}
+	if err := checkMemorySize(tr.header.MemSize, tr.header.FrameSize); err != nil {
+		return nil, fmt.Errorf("%w: %v", errTraceHeader, err)
+	}
+	return tr, nil
+}
+
+// checkMemorySize returns an error unless memSize and frameSize describe an MMU that can be
+// created: the frame size is a power of two, and the memory holds at least one frame and no
+// more than maxTraceMemory bytes.
+func checkMemorySize(memSize, frameSize int) error {
+	if frameSize < 1 || frameSize&(frameSize-1) != 0 {
+		return fmt.Errorf("frame size %d is not a power of two", frameSize)
+	}
+	if memSize < frameSize || memSize > maxTraceMemory {
+		return fmt.Errorf("memory size %d is not between one frame of %d bytes and %d bytes", memSize, frameSize, maxTraceMemory)
+	}
+	return nil
+}
+
+// Header returns the header of the trace.
+func (tr *TraceReader) Header() TraceHeader {
+	return tr.header
+}
+
+// Next returns the next event in the trace, or io.EOF when there are no more events.
+func (tr *TraceReader) Next() (ev TraceEvent, err error) {
+	op, err := tr.r.ReadByte()
+	if err != nil {
+		return ev, err
+	}
+	ev.Op = TraceOp(op)
+
+	// a trace that ends within a record is truncated
+	next := func() int {
+		if err != nil {
+			return 0
+		}
+		var v uint64
+		v, err = binary.ReadUvarint(tr.r)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return int(v)
+	}
+	ev.PID = next()
+	switch ev.Op {
+	case TraceRead, TraceWrite:
+		ev.Addr = next()
+		ev.N = next()
+	case TraceAlloc, TraceAllocHuge, TraceFree:
+		ev.N = next()
+	case TraceExit:
+	default:
+		return ev, fmt.Errorf("%w: %d", errTraceOp, op)
+	}
+	if err != nil {
+		return ev, fmt.Errorf("failed reading trace event: %w", err)
+	}
+	return ev, nil
+}
+
+// ReadAll returns all remaining events in the trace.
+func (tr *TraceReader) ReadAll() ([]TraceEvent, error) {
+	var events []TraceEvent
+	for {
+		ev, err := tr.Next()
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, ev)
+	}
+}
+
+// WriteTrace writes a complete trace with the given header and events to w.
+func WriteTrace(w io.Writer, header TraceHeader, events []TraceEvent) error {
+	tw, err := NewTraceWriter(w, header)
+	if err != nil {
+		return err
+	}
+	for _, ev := range events {
+		if err := tw.Record(ev); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// SetTracer makes the MMU record every Alloc, AllocHuge, Read, Write, Free and Exit call to tw,
+// whether the call succeeds or not. A call is recorded while it holds the lock of the
+// process's page table, so that the calls of a process are recorded in the order they took
+// effect. A nil tw stops the recording.
+func (mmu *MMU) SetTracer(tw *TraceWriter) {
+	mmu.lock.Lock()
+	defer mmu.lock.Unlock()
+	mmu.tracer = tw
+}
+
+// TraceHeader returns the trace header describing this MMU.
+func (mmu *MMU) TraceHeader() TraceHeader {
+	return TraceHeader{MemSize: len(mmu.frames) * len(mmu.frames[0]), FrameSize: len(mmu.frames[0])}
+}
+
+// trace records ev if the MMU has a tracer. Recording errors are kept by the tracer
+// and reported by its Flush method, so they don't interfere with the operation itself.
+func (mmu *MMU) trace(ev TraceEvent) {
+	mmu.lock.RLock()
+	tw := mmu.tracer
+	mmu.lock.RUnlock()
+	if tw != nil {
+		_ = tw.Record(ev)
+	}
+}
+
+// traceFailed records ev if err is not nil, and returns err. It is used by operations that
+// are retried by the OOM killer, which only record a successful attempt while they hold the
+// page table lock, and record a failure once they give up.
+func (mmu *MMU) traceFailed(ev TraceEvent, err error) error {
+	if err != nil {
+		mmu.trace(ev)
+	}
+	return err
+}
@@ -0,0 +1,382 @@
+package paging
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var traceEvents = []TraceEvent{
+	{Op: TraceAlloc, PID: 0, N: 40},
+	{Op: TraceWrite, PID: 0, Addr: 3, N: 60},
+	{Op: TraceRead, PID: 0, Addr: 10, N: 20},
+	{Op: TraceAlloc, PID: 1000, N: 1},
+	{Op: TraceFree, PID: 0, N: 2},
+	{Op: TraceRead, PID: 1000, Addr: 1 << 20, N: 1},
+	{Op: TraceExit, PID: 1000},
+}
+
+func TestTraceRoundTrip(t *testing.T) {
+	header := TraceHeader{MemSize: 1 << 16, FrameSize: 16}
+	var buf bytes.Buffer
+	if err := WriteTrace(&buf, header, traceEvents); err != nil {
+		t.Fatal(err)
+	}
+	// the records are varint encoded, so small values take a single byte each
+	if buf.Len() > 64 {
+		t.Errorf("trace of %d events should be compact; got %d bytes", len(traceEvents), buf.Len())
+	}
+	data := buf.Bytes()
+
+	tr, err := NewTraceReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(header, tr.Header()); diff != "" {
+		t.Errorf("Unexpected trace header; (-want +got):\n%s", diff)
+	}
+	events, err := tr.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(traceEvents, events); diff != "" {
+		t.Errorf("Unexpected trace events; (-want +got):\n%s", diff)
+	}
+
+	if _, err := NewTraceReader(bytes.NewReader([]byte("MMUX\x01\x10\x10"))); !errors.Is(err, errTraceMagic) {
+		t.Errorf("NewTraceReader() of a file with the wrong magic: want '%v', got '%v'", errTraceMagic, err)
+	}
+	if _, err := NewTraceReader(bytes.NewReader([]byte("MMUT\x02\x10\x10"))); !errors.Is(err, errTraceVersion) {
+		t.Errorf("NewTraceReader() of a newer trace file: want '%v', got '%v'", errTraceVersion, err)
+	}
+
+	// cut the trace in the middle of the last Read record
+	tr, err = NewTraceReader(bytes.NewReader(data[:len(data)-4]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.ReadAll(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadAll() of a truncated trace: want '%v', got '%v'", io.ErrUnexpectedEOF, err)
+	}
+}
+
+func TestTraceInvalidHeader(t *testing.T) {
+	for _, header := range []TraceHeader{
+		{MemSize: 1024, FrameSize: 0},
+		{MemSize: 1024, FrameSize: 12},
+		{MemSize: 8, FrameSize: 16},
+		{MemSize: 1 << 40, FrameSize: 16},
+		{MemSize: 1 << 40, FrameSize: 1 << 40},
+	} {
+		var buf bytes.Buffer
+		if err := WriteTrace(&buf, header, traceEvents); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := NewTraceReader(&buf); !errors.Is(err, errTraceHeader) {
+			t.Errorf("NewTraceReader() of a trace with header %+v: want '%v', got '%v'", header, errTraceHeader, err)
+		}
+	}
+	// a frame size that does not fit in an int
+	if _, err := NewTraceReader(bytes.NewReader([]byte("MMUT\x01\x10\xff\xff\xff\xff\xff\xff\xff\xff\xff\x01"))); !errors.Is(err, errTraceHeader) {
+		t.Errorf("NewTraceReader() of a trace with a huge frame size: want '%v', got '%v'", errTraceHeader, err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTrace(&buf, TraceHeader{MemSize: 1024, FrameSize: 16}, traceEvents); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	for _, cfg := range []ReplayConfig{
+		{FrameSize: 12},
+		{FrameSize: 2048},
+		{MemSize: 1 << 40},
+		{Policy: NextFit + 1},
+	} {
+		tr, err := NewTraceReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Replay(tr, cfg); !errors.Is(err, errReplayConfig) {
+			t.Errorf("Replay(%v): want '%v', got '%v'", cfg, errReplayConfig, err)
+		}
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	mmu := NewMMU(128, 8)
+	var buf bytes.Buffer
+	tw, err := NewTraceWriter(&buf, mmu.TraceHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mmu.SetTracer(tw)
+
+	p0, p1, p2 := NewProcess(0, mmu), NewProcess(1, mmu), NewProcess(2, mmu)
+	_ = p0.Malloc(20)
+	_ = p1.Malloc(8)
+	_ = p0.Write(16, make([]byte, 30))
+	_, _ = p0.Read(0, 40)
+	_ = p2.Malloc(16)
+	_ = p1.Exit()
+	_ = p0.Free(2)
+	_ = p2.Malloc(1000) // out of memory
+	_, _ = p2.Read(100, 1)
+	mmu.SetTracer(nil)
+	_ = p2.Malloc(8) // not recorded
+	if err := tw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTraceReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Replay(tr, ReplayConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Events != 9 {
+		t.Errorf("Replay() replayed %d events, want 9", res.Events)
+	}
+	if diff := cmp.Diff(map[TraceOp]int{TraceAlloc: 1, TraceRead: 1}, res.Failed); diff != "" {
+		t.Errorf("Unexpected failed events; (-want +got):\n%s", diff)
+	}
+	if res.OutOfMemory != 1 || res.BytesRead != 40 || res.BytesWritten != 30 || res.PeakFrames != 9 {
+		t.Errorf("Replay() = {OutOfMemory: %d, BytesRead: %d, BytesWritten: %d, PeakFrames: %d}, want {1, 40, 30, 9}",
+			res.OutOfMemory, res.BytesRead, res.BytesWritten, res.PeakFrames)
+	}
+
+	// the replay ends in the same state as the recorded MMU was in when recording stopped
+	_ = p2.Free(1)
+	if diff := cmp.Diff(mmu.Usage(), res.Usage); diff != "" {
+		t.Errorf("Unexpected memory usage after replay; (-want +got):\n%s", diff)
+	}
+}
+
+func TestRecordOOMKill(t *testing.T) {
+	mmu := NewMMU(16*4, 4)
+	mmu.SetOOMKiller(LargestProcess)
+	var buf bytes.Buffer
+	tw, err := NewTraceWriter(&buf, mmu.TraceHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mmu.SetTracer(tw)
+	for pid, frames := range []int{3, 6, 4, 8} {
+		if err := mmu.Alloc(pid, frames*4); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// the victim's exit is recorded before the allocation that needed its memory
+	tr, err := NewTraceReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := tr.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TraceEvent{
+		{Op: TraceAlloc, PID: 0, N: 12},
+		{Op: TraceAlloc, PID: 1, N: 24},
+		{Op: TraceAlloc, PID: 2, N: 16},
+		{Op: TraceExit, PID: 1},
+		{Op: TraceAlloc, PID: 3, N: 32},
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("Unexpected trace events; (-want +got):\n%s", diff)
+	}
+
+	tr, err = NewTraceReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Replay(tr, ReplayConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Failed) != 0 {
+		t.Errorf("Replay() of a trace with an OOM kill had failed events: %v", res.Failed)
+	}
+}
+
+func TestRecordReadAt(t *testing.T) {
+	mmu := NewMMU(128, 8)
+	var buf bytes.Buffer
+	tw, err := NewTraceWriter(&buf, mmu.TraceHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mmu.SetTracer(tw)
+	p := NewProcess(0, mmu)
+	_ = p.Malloc(16)
+	// a short read at the end, and reads at and past the end
+	if n, err := p.ReadAt(make([]byte, 10), 10); n != 6 || err != io.EOF {
+		t.Fatalf("ReadAt() at the end = %d, %v, want 6, %v", n, err, io.EOF)
+	}
+	_, _ = p.ReadAt(make([]byte, 4), 16)
+	_, _ = p.ReadAt(make([]byte, 4), -1)
+	if err := tw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTraceReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := tr.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []TraceEvent{
+		{Op: TraceAlloc, PID: 0, N: 16},
+		{Op: TraceRead, PID: 0, Addr: 10, N: 6},
+		{Op: TraceRead, PID: 0, Addr: 16, N: 4},
+		{Op: TraceRead, PID: 0, Addr: -1, N: 4},
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("Unexpected trace events; (-want +got):\n%s", diff)
+	}
+
+	tr, err = NewTraceReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Replay(tr, ReplayConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Failed[TraceRead] != 2 || res.BytesRead != 6 {
+		t.Errorf("Replay() = {Failed reads: %d, BytesRead: %d}, want {2, 6}", res.Failed[TraceRead], res.BytesRead)
+	}
+}
+
+func TestReplayConfigs(t *testing.T) {
+	events, err := GenerateTrace(WorkloadConfig{Pattern: Random, Processes: 4, Size: 256, Accesses: 100, AccessSize: 8, WriteRatio: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteTrace(&buf, TraceHeader{MemSize: 1024, FrameSize: 16}, events); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	var replayConfigTests = []struct {
+		cfg            ReplayConfig
+		wantOOM        int
+		wantPeakFrames int
+	}{
+		{cfg: ReplayConfig{}, wantOOM: 0, wantPeakFrames: 64},
+		{cfg: ReplayConfig{FrameSize: 64}, wantOOM: 0, wantPeakFrames: 16},
+		{cfg: ReplayConfig{MemSize: 512, Policy: NextFit}, wantOOM: 2, wantPeakFrames: 32},
+	}
+	for _, test := range replayConfigTests {
+		tr, err := NewTraceReader(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := Replay(tr, test.cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Events != len(events) {
+			t.Errorf("Replay(%v) replayed %d events, want %d", res.Config, res.Events, len(events))
+		}
+		if res.OutOfMemory != test.wantOOM || res.PeakFrames != test.wantPeakFrames {
+			t.Errorf("Replay(%v) = {OutOfMemory: %d, PeakFrames: %d}, want {%d, %d}",
+				res.Config, res.OutOfMemory, res.PeakFrames, test.wantOOM, test.wantPeakFrames)
+		}
+		if res.Usage.FreeFrames != res.Usage.NumFrames {
+			t.Errorf("Replay(%v): all processes exit at the end, but %d frames are still used", res.Config, res.Usage.UsedFrames())
+		}
+	}
+}
+
+func TestGenerateTrace(t *testing.T) {
+	const size, accessSize, accesses = 1000, 10, 2000
+	for _, pattern := range Patterns {
+		cfg := WorkloadConfig{Pattern: pattern, Processes: 2, Size: size, Accesses: accesses, AccessSize: accessSize, WriteRatio: 0.25, Seed: 1}
+		events, err := GenerateTrace(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(events) != 2*(accesses+2) {
+			t.Fatalf("GenerateTrace(%v) returned %d events, want %d", pattern, len(events), 2*(accesses+2))
+		}
+		again, _ := GenerateTrace(cfg)
+		if diff := cmp.Diff(events, again); diff != "" {
+			t.Errorf("GenerateTrace(%v) should be deterministic for a given seed; (-want +got):\n%s", pattern, diff)
+		}
+
+		var writes, hot, maxAddr int
+		for _, ev := range events[2 : len(events)-2] {
+			if ev.Addr%accessSize != 0 || ev.Addr < 0 || ev.Addr+ev.N > size {
+				t.Fatalf("GenerateTrace(%v): access %v is outside the working set or not aligned", pattern, ev)
+			}
+			if ev.Op == TraceWrite {
+				writes++
+			}
+			if ev.Addr < size/5 {
+				hot++
+			}
+			maxAddr = max(maxAddr, ev.Addr)
+		}
+		if ratio := float64(writes) / (2 * accesses); ratio < 0.2 || ratio > 0.3 {
+			t.Errorf("GenerateTrace(%v): %.2f of the accesses are writes, want about 0.25", pattern, ratio)
+		}
+
+		hotRatio := float64(hot) / (2 * accesses)
+		switch pattern {
+		case Sequential:
+			if maxAddr != size-accessSize || hotRatio != 0.2 {
+				t.Errorf("GenerateTrace(%v) should walk the whole working set evenly; highest address %d, %.2f of the accesses in the first fifth", pattern, maxAddr, hotRatio)
+			}
+		case Looping:
+			if maxAddr != size/2-accessSize {
+				t.Errorf("GenerateTrace(%v) should loop over the first half of the working set; highest address %d", pattern, maxAddr)
+			}
+		case Hotspot:
+			if hotRatio < 0.75 || hotRatio > 0.85 {
+				t.Errorf("GenerateTrace(%v): %.2f of the accesses are in the hot fifth of the working set, want about 0.8", pattern, hotRatio)
+			}
+		case Random:
+			if hotRatio < 0.15 || hotRatio > 0.25 {
+				t.Errorf("GenerateTrace(%v): %.2f of the accesses are in the first fifth of the working set, want about 0.2", pattern, hotRatio)
+			}
+		}
+	}
+
+	if _, err := GenerateTrace(WorkloadConfig{}); !errors.Is(err, errInvalidWorkload) {
+		t.Errorf("GenerateTrace() of an empty workload: want '%v', got '%v'", errInvalidWorkload, err)
+	}
+}
+
+func TestNextFitPolicy(t *testing.T) {
+	mmu := NewMMU(32, 4)
+	mmu.SetFramePolicy(NextFit)
+	for _, n := range []int{8, 4} {
+		if err := mmu.Alloc(0, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mmu.Free(0, 3); err != nil {
+		t.Fatal(err)
+	}
+	// next-fit continues after frame 2 instead of reusing frame 0
+	if err := mmu.Alloc(1, 24); err != nil {
+		t.Fatal(err)
+	}
+	frames, _ := mmu.FramesOf(1)
+	if diff := cmp.Diff([]int{3, 4, 5, 6, 7, 0}, frames); diff != "" {
+		t.Errorf("Unexpected frames allocated by next-fit; (-want +got):\n%s", diff)
+	}
+}
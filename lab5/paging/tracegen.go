@@ -0,0 +1,108 @@
+package paging
+
+import (
+	"errors"
+	"math/rand"
+)
+
+var errInvalidWorkload = errors.New("invalid workload: processes, size and accesses must be greater than 0")
+
+// Pattern is the memory access pattern of a synthetic workload.
+type Pattern int
+
+const (
+	// Sequential walks the working set front to back, wrapping around at the end.
+	Sequential Pattern = iota
+	// Random accesses uniformly random addresses in the working set.
+	Random
+	// Looping repeatedly walks a loop that covers only part of the working set.
+	Looping
+	// Hotspot sends 80% of the accesses to 20% of the working set.
+	Hotspot
+)
+
+// Patterns lists all access patterns.
+var Patterns = []Pattern{Sequential, Random, Looping, Hotspot}
+
+// String returns the name of the pattern.
+func (p Pattern) String() string {
+	switch p {
+	case Sequential:
+		return "sequential"
+	case Random:
+		return "random"
+	case Looping:
+		return "looping"
+	case Hotspot:
+		return "80/20"
+	}
+	return "unknown"
+}
+
+// WorkloadConfig describes a synthetic workload.
+type WorkloadConfig struct {
+	Pattern    Pattern
+	Processes  int     // number of processes, with pids 0 to Processes-1
+	Size       int     // bytes allocated by each process (its working set)
+	Accesses   int     // reads and writes done by each process
+	AccessSize int     // bytes per read or write; defaults to 1
+	WriteRatio float64 // fraction of the accesses that are writes
+	LoopSize   int     // bytes covered by the Looping pattern; defaults to half the working set
+	Seed       int64   // seed for the random choices, so a workload can be regenerated
+}
+
+// GenerateTrace returns the events of a synthetic workload. Every process first allocates
+// its working set, then the processes take turns doing one access at a time, and finally
+// every process exits. Accesses are aligned to AccessSize.
+func GenerateTrace(cfg WorkloadConfig) ([]TraceEvent, error) {
+	if cfg.Processes < 1 || cfg.Size < 1 || cfg.Accesses < 1 {
+		return nil, errInvalidWorkload
+	}
+	if cfg.AccessSize < 1 {
+		cfg.AccessSize = 1
+	}
+	if cfg.AccessSize > cfg.Size {
+		cfg.AccessSize = cfg.Size
+	}
+	if cfg.LoopSize < 1 || cfg.LoopSize > cfg.Size {
+		cfg.LoopSize = cfg.Size / 2
+	}
+
+	rnd := rand.New(rand.NewSource(cfg.Seed))
+	slots := cfg.Size / cfg.AccessSize
+	loopSlots := max(cfg.LoopSize/cfg.AccessSize, 1)
+	hotSlots := max(slots/5, 1)
+
+	nextSlot := func(i int) int {
+		switch cfg.Pattern {
+		case Random:
+			return rnd.Intn(slots)
+		case Looping:
+			return i % loopSlots
+		case Hotspot:
+			if rnd.Float64() < 0.8 || hotSlots == slots {
+				return rnd.Intn(hotSlots)
+			}
+			return hotSlots + rnd.Intn(slots-hotSlots)
+		}
+		return i % slots
+	}
+
+	events := make([]TraceEvent, 0, cfg.Processes*(cfg.Accesses+2))
+	for pid := 0; pid < cfg.Processes; pid++ {
+		events = append(events, TraceEvent{Op: TraceAlloc, PID: pid, N: cfg.Size})
+	}
+	for i := 0; i < cfg.Accesses; i++ {
+		for pid := 0; pid < cfg.Processes; pid++ {
+			op := TraceRead
+			if rnd.Float64() < cfg.WriteRatio {
+				op = TraceWrite
+			}
+			events = append(events, TraceEvent{Op: op, PID: pid, Addr: nextSlot(i) * cfg.AccessSize, N: cfg.AccessSize})
+		}
+	}
+	for pid := 0; pid < cfg.Processes; pid++ {
+		events = append(events, TraceEvent{Op: TraceExit, PID: pid})
+	}
+	return events, nil
+}
@@ -0,0 +1,61 @@
+package stack
+
+import "sync"
+
+// cspServer is a goroutine that owns the state of a CSP data structure. Operations are
+// sent to it as functions over a channel and run one at a time by the goroutine, so the
+// state is never shared. The goroutine runs until close is called.
+type cspServer struct {
+	ops       chan func()
+	closeOnce sync.Once
+	done      chan struct{} // closed by close to stop the goroutine
+	stopped   chan struct{} // closed by the goroutine when it has stopped
+}
+
+// newCspServer starts a cspServer.
+func newCspServer() *cspServer {
+	s := &cspServer{
+		ops:     make(chan func()),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// do runs op on the server goroutine and waits for it to finish.
+// It returns ErrClosed without running op if the server is closed.
+func (s *cspServer) do(op func()) error {
+	finished := make(chan struct{})
+	select {
+	case s.ops <- func() { op(); close(finished) }:
+		<-finished
+		return nil
+	case <-s.done:
+		return ErrClosed
+	}
+}
+
+// close stops the server goroutine and waits for it to finish.
+// Closing a closed server returns ErrClosed.
+func (s *cspServer) close() error {
+	err := ErrClosed
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = nil
+	})
+	<-s.stopped
+	return err
+}
+
+func (s *cspServer) run() {
+	defer close(s.stopped)
+	for {
+		select {
+		case <-s.done:
+			return
+		case op := <-s.ops:
+			op()
+		}
+	}
+}
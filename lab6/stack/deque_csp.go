@@ -0,0 +1,50 @@
+package stack
+
+// CspDeque is a Deque owned by a single goroutine, which runs the operations sent to it.
+// The goroutine runs until Close is called.
+type CspDeque struct {
+	server *cspServer
+	items  ring // only accessed by the server goroutine
+}
+
+// NewCspDeque returns an empty CspDeque.
+// Close must be called when the deque is no longer used, to stop its goroutine.
+func NewCspDeque() *CspDeque {
+	return &CspDeque{server: newCspServer()}
+}
+
+// Size returns the size of the deque, or 0 if the deque is closed.
+func (cd *CspDeque) Size() (size int) {
+	_ = cd.server.do(func() { size = cd.items.len() })
+	return size
+}
+
+// PushFront adds value to the front of the deque. The value is dropped if the deque is closed.
+func (cd *CspDeque) PushFront(value interface{}) {
+	_ = cd.server.do(func() { cd.items.pushFront(value) })
+}
+
+// PushBack adds value to the back of the deque. The value is dropped if the deque is closed.
+func (cd *CspDeque) PushBack(value interface{}) {
+	_ = cd.server.do(func() { cd.items.pushBack(value) })
+}
+
+// PopFront removes the value at the front of the deque and returns it.
+// It returns nil if the deque is empty or closed.
+func (cd *CspDeque) PopFront() (value interface{}) {
+	_ = cd.server.do(func() { value = cd.items.popFront() })
+	return value
+}
+
+// PopBack removes the value at the back of the deque and returns it.
+// It returns nil if the deque is empty or closed.
+func (cd *CspDeque) PopBack() (value interface{}) {
+	_ = cd.server.do(func() { value = cd.items.popBack() })
+	return value
+}
+
+// Close stops the goroutine serving the deque and waits for it to finish.
+// Closing a closed deque returns ErrClosed.
+func (cd *CspDeque) Close() error {
+	return cd.server.close()
+}
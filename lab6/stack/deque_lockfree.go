@@ -0,0 +1,128 @@
+package stack
+
+import "sync/atomic"
+
+// LockFreeDeque is a Deque whose whole content is an immutable value, replaced with
+// compare-and-swap on every change. An operation that loses the race simply retries on
+// the new content, so no goroutine ever waits for another.
+//
+// The content is a pair of immutable linked lists: the front list holds the front values
+// from the front, and the back list holds the back values from the back. When one of them
+// runs empty, half of the other is reversed into it. Unchanged list tails are shared
+// between versions, so most operations allocate a single node.
+type LockFreeDeque struct {
+	state atomic.Pointer[dequeState] // nil means empty
+}
+
+type dequeState struct {
+	front, back       *listNode
+	frontLen, backLen int
+}
+
+type listNode struct {
+	value interface{}
+	next  *listNode
+}
+
+// Size returns the size of the deque.
+func (d *LockFreeDeque) Size() int {
+	s := d.state.Load()
+	if s == nil {
+		return 0
+	}
+	return s.frontLen + s.backLen
+}
+
+// PushFront adds value to the front of the deque.
+func (d *LockFreeDeque) PushFront(value interface{}) {
+	d.update(func(s dequeState) (dequeState, interface{}) {
+		s.front = &listNode{value, s.front}
+		s.frontLen++
+		return s, nil
+	})
+}
+
+// PushBack adds value to the back of the deque.
+func (d *LockFreeDeque) PushBack(value interface{}) {
+	d.update(func(s dequeState) (dequeState, interface{}) {
+		s.back = &listNode{value, s.back}
+		s.backLen++
+		return s, nil
+	})
+}
+
+// PopFront removes the value at the front of the deque and returns it.
+func (d *LockFreeDeque) PopFront() interface{} {
+	return d.update(func(s dequeState) (dequeState, interface{}) {
+		if s.front == nil {
+			s.back, s.backLen, s.front, s.frontLen = rebalance(s.back, s.backLen)
+		}
+		if s.front == nil {
+			return s, nil
+		}
+		value := s.front.value
+		s.front = s.front.next
+		s.frontLen--
+		return s, value
+	})
+}
+
+// PopBack removes the value at the back of the deque and returns it.
+func (d *LockFreeDeque) PopBack() interface{} {
+	return d.update(func(s dequeState) (dequeState, interface{}) {
+		if s.back == nil {
+			s.front, s.frontLen, s.back, s.backLen = rebalance(s.front, s.frontLen)
+		}
+		if s.back == nil {
+			return s, nil
+		}
+		value := s.back.value
+		s.back = s.back.next
+		s.backLen--
+		return s, value
+	})
+}
+
+// update applies op to the current content until the result can be installed with
+// compare-and-swap, and returns the value returned by op.
+func (d *LockFreeDeque) update(op func(dequeState) (dequeState, interface{})) interface{} {
+	for {
+		old := d.state.Load()
+		var s dequeState
+		if old != nil {
+			s = *old
+		}
+		next, value := op(s)
+		if next == s {
+			// nothing changed, which only happens when popping from an empty deque
+			return value
+		}
+		if d.state.CompareAndSwap(old, &next) {
+			return value
+		}
+	}
+}
+
+// rebalance splits list, which holds n values, when the list at the other end of the
+// deque has run empty. The first half stays in kept, and the second half is reversed
+// into moved, which becomes the other end's list.
+func rebalance(list *listNode, n int) (kept *listNode, keptLen int, moved *listNode, movedLen int) {
+	keptLen = n / 2
+	// the kept half must be copied, since its last node changes
+	var keptTail *listNode
+	for i := 0; i < keptLen; i++ {
+		node := &listNode{value: list.value}
+		if keptTail == nil {
+			kept = node
+		} else {
+			keptTail.next = node
+		}
+		keptTail = node
+		list = list.next
+	}
+	for ; list != nil; list = list.next {
+		moved = &listNode{list.value, moved}
+		movedLen++
+	}
+	return kept, keptLen, moved, movedLen
+}
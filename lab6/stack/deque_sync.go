@@ -0,0 +1,44 @@
+package stack
+
+import "sync"
+
+// SafeDeque is a Deque protected by a mutex.
+type SafeDeque struct {
+	mu    sync.RWMutex // guards items
+	items ring
+}
+
+// Size returns the size of the deque.
+func (sd *SafeDeque) Size() int {
+	sd.mu.RLock()
+	defer sd.mu.RUnlock()
+	return sd.items.len()
+}
+
+// PushFront adds value to the front of the deque.
+func (sd *SafeDeque) PushFront(value interface{}) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.items.pushFront(value)
+}
+
+// PushBack adds value to the back of the deque.
+func (sd *SafeDeque) PushBack(value interface{}) {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	sd.items.pushBack(value)
+}
+
+// PopFront removes the value at the front of the deque and returns it.
+func (sd *SafeDeque) PopFront() interface{} {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.items.popFront()
+}
+
+// PopBack removes the value at the back of the deque and returns it.
+func (sd *SafeDeque) PopBack() interface{} {
+	sd.mu.Lock()
+	defer sd.mu.Unlock()
+	return sd.items.popBack()
+}
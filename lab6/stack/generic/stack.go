@@ -0,0 +1,47 @@
+// Package generic provides type-parameterised versions of the stacks in package stack.
+// They have the same semantics, but store values of type T directly instead of as
+// interface{}, so pushing a value doesn't box it and popping needs no type assertion.
+package generic
+
+// Stack interface has methods for interacting with a stack of values of type T.
+type Stack[T any] interface {
+	// Size returns the size of the stack.
+	Size() int
+	// Push pushes value onto the stack.
+	Push(value T)
+	// Pop pops the value at the top of the stack and returns it.
+	// It returns the zero value of T if the stack is empty.
+	Pop() T
+}
+
+// element is the element to be held in the linked list stacks.
+type element[T any] struct {
+	value T
+	next  *element[T]
+}
+
+// UnsafeStack is a stack that is not safe for concurrent use.
+type UnsafeStack[T any] struct {
+	top  *element[T]
+	size int
+}
+
+// Size returns the size of the stack.
+func (us *UnsafeStack[T]) Size() int {
+	return us.size
+}
+
+// Push pushes value onto the stack.
+func (us *UnsafeStack[T]) Push(value T) {
+	us.top = &element[T]{value, us.top}
+	us.size++
+}
+
+// Pop pops the value at the top of the stack and returns it.
+func (us *UnsafeStack[T]) Pop() (value T) {
+	if us.size > 0 {
+		value, us.top = us.top.value, us.top.next
+		us.size--
+	}
+	return value
+}
@@ -0,0 +1,148 @@
+package generic
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned by operations on a CspStack after it has been closed.
+var ErrClosed = errors.New("stack is closed")
+
+// CspStack is a stack owned by a single goroutine, which serves requests sent over channels.
+// The goroutine runs until Close is called.
+type CspStack[T any] struct {
+	pushChan       chan T
+	popStartChan   chan struct{}
+	popReturnChan  chan T
+	lenChan        chan struct{}
+	lenReturnChan  chan int
+	pushAllChan    chan []T
+	popNChan       chan int
+	popNReturnChan chan []T
+	stack          []T
+
+	closeOnce sync.Once
+	done      chan struct{} // closed by Close to stop the goroutine
+	stopped   chan struct{} // closed by the goroutine when it has stopped
+}
+
+// NewCspStack returns an empty CspStack.
+// Close must be called when the stack is no longer used, to stop its goroutine.
+func NewCspStack[T any]() *CspStack[T] {
+	cspStack := &CspStack[T]{
+		pushChan:       make(chan T),
+		popStartChan:   make(chan struct{}),
+		popReturnChan:  make(chan T),
+		lenChan:        make(chan struct{}),
+		lenReturnChan:  make(chan int),
+		pushAllChan:    make(chan []T),
+		popNChan:       make(chan int),
+		popNReturnChan: make(chan []T),
+		done:           make(chan struct{}),
+		stopped:        make(chan struct{}),
+	}
+	go cspStack.run()
+	return cspStack
+}
+
+// Size returns the size of the stack, or 0 if the stack is closed.
+func (cs *CspStack[T]) Size() int {
+	// Signal that we want to know the size
+	select {
+	case cs.lenChan <- struct{}{}:
+		return <-cs.lenReturnChan
+	case <-cs.done:
+		return 0
+	}
+}
+
+// Push pushes value onto the stack. The value is dropped if the stack is closed;
+// use PushAll to detect that.
+func (cs *CspStack[T]) Push(value T) {
+	select {
+	case cs.pushChan <- value:
+	case <-cs.done:
+	}
+}
+
+// Pop pops the value at the top of the stack and returns it. It returns the zero
+// value if the stack is empty or closed; use PopN to tell the two apart.
+func (cs *CspStack[T]) Pop() (value T) {
+	// Signal that we want to pop of the stack
+	select {
+	case cs.popStartChan <- struct{}{}:
+		return <-cs.popReturnChan
+	case <-cs.done:
+		return value
+	}
+}
+
+// PushAll pushes all values onto the stack in a single round trip, in order,
+// so the last value ends up on top. It returns ErrClosed if the stack is closed.
+func (cs *CspStack[T]) PushAll(values ...T) error {
+	select {
+	case cs.pushAllChan <- values:
+		return nil
+	case <-cs.done:
+		return ErrClosed
+	}
+}
+
+// PopN pops up to n values from the stack in a single round trip and returns them,
+// the top value first. It returns ErrClosed if the stack is closed.
+func (cs *CspStack[T]) PopN(n int) ([]T, error) {
+	select {
+	case cs.popNChan <- n:
+		return <-cs.popNReturnChan, nil
+	case <-cs.done:
+		return nil, ErrClosed
+	}
+}
+
+// Close stops the goroutine serving the stack and waits for it to finish.
+// Values left on the stack are discarded. Closing a closed stack returns ErrClosed.
+func (cs *CspStack[T]) Close() error {
+	err := ErrClosed
+	cs.closeOnce.Do(func() {
+		close(cs.done)
+		err = nil
+	})
+	<-cs.stopped
+	return err
+}
+
+func (cs *CspStack[T]) run() {
+	defer close(cs.stopped)
+	var zero T
+	for {
+		select {
+		case <-cs.done:
+			cs.stack = nil
+			return
+		case <-cs.lenChan:
+			cs.lenReturnChan <- len(cs.stack)
+		case value := <-cs.pushChan:
+			cs.stack = append(cs.stack, value)
+		case <-cs.popStartChan:
+			value := zero
+			if n := len(cs.stack); n > 0 {
+				value = cs.stack[n-1]
+				cs.stack[n-1] = zero // don't keep the popped value alive
+				cs.stack = cs.stack[:n-1]
+			}
+			// If there are no elements to pop: return the zero value
+			cs.popReturnChan <- value
+		case values := <-cs.pushAllChan:
+			cs.stack = append(cs.stack, values...)
+		case n := <-cs.popNChan:
+			n = max(0, min(n, len(cs.stack)))
+			values := make([]T, n)
+			for i := range values {
+				values[i] = cs.stack[len(cs.stack)-1-i]
+				cs.stack[len(cs.stack)-1-i] = zero
+			}
+			cs.stack = cs.stack[:len(cs.stack)-n]
+			cs.popNReturnChan <- values
+		}
+	}
+}
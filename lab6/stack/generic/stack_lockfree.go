@@ -0,0 +1,47 @@
+package generic
+
+import "sync/atomic"
+
+// LockFreeStack is a Treiber stack, like stack.LockFreeStack. Elements are never
+// recycled, which keeps the garbage collector from reusing the memory of an element
+// a delayed Pop still references, and so avoids the ABA problem.
+type LockFreeStack[T any] struct {
+	top  atomic.Pointer[element[T]]
+	size atomic.Int64
+}
+
+// Size returns the size of the stack. While other goroutines push and pop,
+// the size is only approximate, since it is updated after the top has changed.
+func (ls *LockFreeStack[T]) Size() int {
+	if size := ls.size.Load(); size > 0 {
+		return int(size)
+	}
+	return 0
+}
+
+// Push pushes value onto the stack.
+func (ls *LockFreeStack[T]) Push(value T) {
+	e := &element[T]{value: value}
+	for {
+		e.next = ls.top.Load()
+		if ls.top.CompareAndSwap(e.next, e) {
+			ls.size.Add(1)
+			return
+		}
+	}
+}
+
+// Pop pops the value at the top of the stack and returns it.
+func (ls *LockFreeStack[T]) Pop() (value T) {
+	for {
+		top := ls.top.Load()
+		if top == nil {
+			return value
+		}
+		// top.next never changes once top is on the stack, so it is safe to read
+		if ls.top.CompareAndSwap(top, top.next) {
+			ls.size.Add(-1)
+			return top.value
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package generic
+
+import "sync"
+
+// DefaultCap is the default stack capacity.
+const DefaultCap = 10
+
+// SliceStack is a stack backed by a slice.
+// Each stack has its own lock, so unrelated stacks never contend with each other.
+type SliceStack[T any] struct {
+	mu    sync.RWMutex // guards slice
+	slice []T
+}
+
+// NewSliceStack returns an empty SliceStack.
+func NewSliceStack[T any]() *SliceStack[T] {
+	return &SliceStack[T]{slice: make([]T, 0, DefaultCap)}
+}
+
+// Size returns the size of the stack.
+func (ss *SliceStack[T]) Size() int {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return len(ss.slice)
+}
+
+// Push pushes value onto the stack.
+func (ss *SliceStack[T]) Push(value T) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.slice = append(ss.slice, value)
+}
+
+// Pop pops the value at the top of the stack and returns it.
+func (ss *SliceStack[T]) Pop() (value T) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if n := len(ss.slice); n > 0 {
+		value = ss.slice[n-1]
+		var zero T
+		ss.slice[n-1] = zero // don't keep the popped value alive
+		ss.slice = ss.slice[:n-1]
+	}
+	return value
+}
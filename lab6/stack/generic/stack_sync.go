@@ -0,0 +1,37 @@
+package generic
+
+import "sync"
+
+// SafeStack holds the top element of the stack and its size.
+// Each stack has its own lock, so unrelated stacks never contend with each other.
+type SafeStack[T any] struct {
+	mu   sync.RWMutex // guards top and size
+	top  *element[T]
+	size int
+}
+
+// Size returns the size of the stack.
+func (ss *SafeStack[T]) Size() int {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return ss.size
+}
+
+// Push pushes value onto the stack.
+func (ss *SafeStack[T]) Push(value T) {
+	ss.mu.Lock()
+	ss.top = &element[T]{value, ss.top}
+	ss.size++
+	ss.mu.Unlock()
+}
+
+// Pop pops the value at the top of the stack and returns it.
+func (ss *SafeStack[T]) Pop() (value T) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	if ss.size > 0 {
+		value, ss.top = ss.top.value, ss.top.next
+		ss.size--
+	}
+	return value
+}
@@ -0,0 +1,211 @@
+package generic
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"dat320/lab6/stack"
+)
+
+// stacks returns a new instance of each thread-safe generic stack. The CspStack is
+// closed when the test or benchmark ends.
+func stacks[T any](tb testing.TB) map[string]Stack[T] {
+	cspStack := NewCspStack[T]()
+	tb.Cleanup(func() { cspStack.Close() })
+	return map[string]Stack[T]{
+		"SafeStack":     new(SafeStack[T]),
+		"SliceStack":    NewSliceStack[T](),
+		"CspStack":      cspStack,
+		"LockFreeStack": new(LockFreeStack[T]),
+	}
+}
+
+func TestConcurrentStackAccess(t *testing.T) {
+	runtime.GOMAXPROCS(runtime.NumCPU())
+	for name, s := range stacks[string](t) {
+		t.Run(name, func(t *testing.T) {
+			testConcurrentStackAccess(s)
+			for s.Size() > 0 {
+				if v := s.Pop(); v == "" {
+					t.Fatalf("Pop() on a non-empty stack returned the zero value")
+				}
+			}
+		})
+	}
+}
+
+func TestStackOperations(t *testing.T) {
+	all := stacks[int](t)
+	all["UnsafeStack"] = new(UnsafeStack[int])
+	for name, s := range all {
+		t.Run(name, func(t *testing.T) {
+			testStackOperations(s, t)
+		})
+	}
+}
+
+func TestStackOfStructs(t *testing.T) {
+	type point struct{ x, y int }
+	for name, s := range stacks[point](t) {
+		s.Push(point{1, 2})
+		s.Push(point{3, 4})
+		if got := s.Pop(); got != (point{3, 4}) {
+			t.Errorf("%s: Pop() = %v, want %v", name, got, point{3, 4})
+		}
+		if got := s.Pop(); got != (point{1, 2}) {
+			t.Errorf("%s: Pop() = %v, want %v", name, got, point{1, 2})
+		}
+		if got := s.Pop(); got != (point{}) {
+			t.Errorf("%s: Pop() on empty stack = %v, want the zero value", name, got)
+		}
+	}
+}
+
+// BenchmarkStacks compares the generic stacks with the interface{} versions in
+// package stack. Run with -benchmem to see the allocations saved by not boxing values.
+func BenchmarkStacks(b *testing.B) {
+	cspStack, genericCspStack := stack.NewCspStack(), NewCspStack[int]()
+	b.Cleanup(func() {
+		cspStack.Close()
+		genericCspStack.Close()
+	})
+	benchmarks := []struct {
+		name       string
+		interfaced stack.Stack
+		generic    Stack[int]
+	}{
+		{"SafeStack", new(stack.SafeStack), new(SafeStack[int])},
+		{"SliceStack", stack.NewSliceStack(), NewSliceStack[int]()},
+		{"CspStack", cspStack, genericCspStack},
+		{"LockFreeStack", new(stack.LockFreeStack), new(LockFreeStack[int])},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name+"/interface", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchInterfaceStackOperations(bm.interfaced)
+			}
+		})
+		b.Run(bm.name+"/generic", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				benchStackOperations(bm.generic)
+			}
+		})
+	}
+}
+
+const (
+	numGoroutines = 4
+	numOperations = 10
+	benchOps      = 1000
+)
+
+const (
+	Len = iota
+	Push
+	Pop
+)
+
+func testConcurrentStackAccess(s Stack[string]) {
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < numOperations; j++ {
+				switch rand.Intn(3) {
+				case Len:
+					s.Size()
+				case Push:
+					s.Push("Data" + strconv.Itoa(i) + strconv.Itoa(j))
+				case Pop:
+					_ = s.Pop()
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func testStackOperations(s Stack[int], t *testing.T) {
+	if length := s.Size(); length != 0 {
+		t.Errorf("Size() of a new stack = %d, want 0", length)
+	}
+	if v := s.Pop(); v != 0 {
+		t.Errorf("Pop() of a new stack = %d, want 0", v)
+	}
+
+	const size = 200
+	for i := 1; i <= size; i++ {
+		s.Push(i)
+	}
+	if length := s.Size(); length != size {
+		t.Errorf("Size() after %d pushes = %d, want %d", size, length, size)
+	}
+	for j := size; j >= 1; j-- {
+		if x := s.Pop(); x != j {
+			t.Errorf("Pop() = %d, want %d", x, j)
+			break
+		}
+	}
+	if length := s.Size(); length != 0 {
+		t.Errorf("Size() of an emptied stack = %d, want 0", length)
+	}
+	if v := s.Pop(); v != 0 {
+		t.Errorf("Pop() of an emptied stack = %d, want 0", v)
+	}
+}
+
+func benchStackOperations(s Stack[int]) {
+	// values above 255 are not preallocated by the runtime, so boxing them allocates
+	for i := 0; i < benchOps; i++ {
+		s.Push(1000 + i)
+	}
+	for j := 0; j < benchOps; j++ {
+		s.Pop()
+	}
+}
+
+func benchInterfaceStackOperations(s stack.Stack) {
+	for i := 0; i < benchOps; i++ {
+		s.Push(1000 + i)
+	}
+	for j := 0; j < benchOps; j++ {
+		s.Pop()
+	}
+}
+
+func ExampleSliceStack() {
+	s := NewSliceStack[string]()
+	s.Push("hello")
+	s.Push("world")
+	fmt.Println(s.Pop(), s.Pop(), s.Size())
+	// Output: world hello 0
+}
+
+func TestCspStackClose(t *testing.T) {
+	s := NewCspStack[int]()
+	if err := s.PushAll(1, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.PopN(2); err != nil || len(got) != 2 || got[0] != 3 || got[1] != 2 {
+		t.Errorf("PopN(2) = (%v, %v), want ([3 2], <nil>)", got, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PushAll(4); !errors.Is(err, ErrClosed) {
+		t.Errorf("PushAll() after Close(): want '%v', got '%v'", ErrClosed, err)
+	}
+	if v := s.Pop(); v != 0 {
+		t.Errorf("Pop() after Close() = %d, want 0", v)
+	}
+}
@@ -0,0 +1,301 @@
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNotLinearizable is returned by CheckStack for a history that no sequential stack
+// could have produced.
+var ErrNotLinearizable = errors.New("history is not linearizable")
+
+// Op is an operation on a Stack.
+type Op int
+
+const (
+	OpSize Op = iota
+	OpPush
+	OpPop
+)
+
+func (op Op) String() string {
+	switch op {
+	case OpSize:
+		return "Size"
+	case OpPush:
+		return "Push"
+	case OpPop:
+		return "Pop"
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// Event is the invocation or the return of an operation on a stack.
+type Event struct {
+	Return    bool // false for the invocation
+	ID        int  // the invocation and the return of an operation have the same ID
+	Goroutine int
+	Op        Op
+	// Value is the value pushed, in the invocation of Push, and the value popped or
+	// the size returned, in the return of Pop or Size.
+	Value interface{}
+}
+
+// History is a sequence of events, in the order they happened.
+type History []Event
+
+// Recorder records a History of the operations that goroutines perform on a stack.
+type Recorder struct {
+	clock atomic.Int64 // orders the events of all goroutines
+	mu    sync.Mutex
+	logs  []*[]timedEvent
+}
+
+type timedEvent struct {
+	time int64
+	Event
+}
+
+// Stack returns a Stack that performs its operations on stack and records them as done
+// by goroutine. Every goroutine must use a Stack of its own, so that recording an event
+// doesn't synchronize the goroutines more than stack itself does.
+func (r *Recorder) Stack(goroutine int, stack Stack) Stack {
+	log := new([]timedEvent)
+	r.mu.Lock()
+	r.logs = append(r.logs, log)
+	r.mu.Unlock()
+	return &recordingStack{clock: &r.clock, log: log, goroutine: goroutine, stack: stack}
+}
+
+// History returns the recorded events. It must not be called while goroutines are
+// still using the stacks returned by Stack.
+func (r *Recorder) History() History {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []timedEvent
+	for _, log := range r.logs {
+		events = append(events, *log...)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].time < events[j].time })
+	history := make(History, len(events))
+	for i, e := range events {
+		history[i] = e.Event
+	}
+	return history
+}
+
+type recordingStack struct {
+	clock     *atomic.Int64
+	log       *[]timedEvent
+	goroutine int
+	stack     Stack
+}
+
+// record appends an event to the goroutine's log and returns the time it happened.
+// The invocation of an operation is timed before the operation starts, and its
+// return after the operation has finished.
+func (rs *recordingStack) record(id int, ret bool, op Op, value interface{}) int {
+	now := rs.clock.Add(1)
+	if !ret {
+		id = int(now)
+	}
+	*rs.log = append(*rs.log, timedEvent{now, Event{ret, id, rs.goroutine, op, value}})
+	return id
+}
+
+func (rs *recordingStack) Size() int {
+	id := rs.record(0, false, OpSize, nil)
+	size := rs.stack.Size()
+	rs.record(id, true, OpSize, size)
+	return size
+}
+
+func (rs *recordingStack) Push(value interface{}) {
+	id := rs.record(0, false, OpPush, value)
+	rs.stack.Push(value)
+	rs.record(id, true, OpPush, nil)
+}
+
+func (rs *recordingStack) Pop() interface{} {
+	id := rs.record(0, false, OpPop, nil)
+	value := rs.stack.Pop()
+	rs.record(id, true, OpPop, value)
+	return value
+}
+
+// CheckStack returns nil if history is linearizable with respect to a sequential stack:
+// if there is an order of its operations that respects the order of operations that
+// didn't overlap in time, and in which every operation returns what it did in history.
+// The pushed values must be comparable, and should be distinct and not nil for the
+// check to be meaningful.
+//
+// The check is the algorithm of Wing and Gong, which searches for a linearization by
+// backtracking, with Lowe's improvement of never exploring the same set of linearized
+// operations and stack contents twice, as in Porcupine. To keep long histories cheap,
+// the sets and contents that have been explored are remembered by their hashes only.
+func CheckStack(history History) error {
+	head, n, err := entries(history)
+	if err != nil {
+		return err
+	}
+
+	type call struct {
+		entry *entry
+		state *stackState // before the operation
+	}
+	var (
+		calls      []call
+		linearized uint64 // hash of the set of linearized operations
+		seen       = make(map[[2]uint64]bool)
+		state      *stackState
+		longest    int
+	)
+	e := head.next
+	for head.next != nil {
+		if e.match != nil {
+			// try to linearize the operation here
+			if next, ok := state.apply(e.op); ok {
+				key := [2]uint64{linearized ^ e.op.hash, next.hash()}
+				if !seen[key] {
+					seen[key] = true
+					calls = append(calls, call{e, state})
+					longest = max(longest, len(calls))
+					linearized, state = key[0], next
+					e.lift()
+					e = head.next
+					continue
+				}
+			}
+			e = e.next
+			continue
+		}
+		// an operation returned before it could be linearized; undo the last one
+		if len(calls) == 0 {
+			return fmt.Errorf("%w: at most %d of %d operations can be ordered", ErrNotLinearizable, longest, n)
+		}
+		last := calls[len(calls)-1]
+		calls = calls[:len(calls)-1]
+		state = last.state
+		linearized ^= last.entry.op.hash
+		last.entry.unlift()
+		e = last.entry.next
+	}
+	return nil
+}
+
+// operation is a completed operation in a history.
+type operation struct {
+	hash          uint64 // random; a set of operations hashes to the xor of theirs
+	op            Op
+	input, output interface{}
+}
+
+// entry is the invocation or return of an operation, in a doubly linked list.
+type entry struct {
+	op         *operation
+	match      *entry // the return, if this is an invocation
+	prev, next *entry
+}
+
+// entries returns a list of the events in history, after an empty head entry,
+// and the number of operations in it.
+func entries(history History) (*entry, int, error) {
+	head := &entry{}
+	tail := head
+	invocations := make(map[int]*entry)
+	rng := rand.New(rand.NewSource(1))
+	n := 0
+	for _, event := range history {
+		e := &entry{prev: tail}
+		tail.next, tail = e, e
+		if !event.Return {
+			if _, ok := invocations[event.ID]; ok {
+				return nil, 0, fmt.Errorf("operation %d is invoked twice", event.ID)
+			}
+			e.op = &operation{hash: rng.Uint64(), op: event.Op, input: event.Value}
+			invocations[event.ID] = e
+			n++
+			continue
+		}
+		inv, ok := invocations[event.ID]
+		if !ok || inv.match != nil {
+			return nil, 0, fmt.Errorf("operation %d returns without being invoked", event.ID)
+		}
+		inv.match, e.op = e, inv.op
+		e.op.output = event.Value
+	}
+	for id, inv := range invocations {
+		if inv.match == nil {
+			return nil, 0, fmt.Errorf("operation %d (%v) never returns", id, inv.op.op)
+		}
+	}
+	return head, n, nil
+}
+
+// lift removes the invocation e and its return from the list.
+func (e *entry) lift() {
+	for _, x := range []*entry{e, e.match} {
+		x.prev.next = x.next
+		if x.next != nil {
+			x.next.prev = x.prev
+		}
+	}
+}
+
+// unlift puts the invocation e and its return back where lift removed them from.
+func (e *entry) unlift() {
+	for _, x := range []*entry{e.match, e} {
+		x.prev.next = x
+		if x.next != nil {
+			x.next.prev = x
+		}
+	}
+}
+
+// stackState is the content of a sequential stack, as an immutable linked list,
+// so that earlier states can be kept while backtracking. The empty stack is nil.
+type stackState struct {
+	value interface{}
+	size  int
+	// contents hashes the Push operations of the values on the stack. Since the same
+	// Push always pushes the same value, it identifies the contents of the stack.
+	contents uint64
+	next     *stackState
+}
+
+func (s *stackState) len() int {
+	if s == nil {
+		return 0
+	}
+	return s.size
+}
+
+func (s *stackState) hash() uint64 {
+	if s == nil {
+		return 0
+	}
+	return s.contents
+}
+
+// apply performs op on a sequential stack in state s, and returns the new state and
+// whether op would return the same as it did in the history.
+func (s *stackState) apply(op *operation) (*stackState, bool) {
+	switch op.op {
+	case OpSize:
+		size, ok := op.output.(int)
+		return s, ok && size == s.len()
+	case OpPush:
+		// multiplying by an odd constant makes the hash depend on the order of the values
+		return &stackState{op.input, s.len() + 1, s.hash()*0x9e3779b97f4a7c15 + op.hash, s}, true
+	case OpPop:
+		if s == nil {
+			return nil, op.output == nil
+		}
+		return s.next, s.value == op.output
+	}
+	return s, false
+}
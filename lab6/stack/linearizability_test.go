@@ -0,0 +1,183 @@
+package stack
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func invoke(id int, op Op, value interface{}) Event { return Event{false, id, 0, op, value} }
+func ret(id int, op Op, value interface{}) Event    { return Event{true, id, 0, op, value} }
+
+func TestCheckStack(t *testing.T) {
+	tests := []struct {
+		name    string
+		history History
+		want    error
+	}{
+		{"sequential", History{
+			invoke(1, OpPush, "a"), ret(1, OpPush, nil),
+			invoke(2, OpPush, "b"), ret(2, OpPush, nil),
+			invoke(3, OpPop, nil), ret(3, OpPop, "b"),
+			invoke(4, OpSize, nil), ret(4, OpSize, 1),
+			invoke(5, OpPop, nil), ret(5, OpPop, "a"),
+			invoke(6, OpPop, nil), ret(6, OpPop, nil),
+		}, nil},
+		{"pop during push", History{
+			invoke(1, OpPush, "a"),
+			invoke(2, OpPop, nil), ret(2, OpPop, "a"),
+			ret(1, OpPush, nil),
+		}, nil},
+		{"concurrent pushes", History{
+			invoke(1, OpPush, "a"), invoke(2, OpPush, "b"),
+			ret(2, OpPush, nil), ret(1, OpPush, nil),
+			invoke(3, OpPop, nil), ret(3, OpPop, "a"),
+			invoke(4, OpPop, nil), ret(4, OpPop, "b"),
+		}, nil},
+		{"lost push returning last", History{
+			invoke(1, OpPush, "a"), invoke(2, OpPush, "b"),
+			ret(2, OpPush, nil), ret(1, OpPush, nil),
+			invoke(3, OpPop, nil), ret(3, OpPop, "b"),
+			invoke(4, OpPop, nil), ret(4, OpPop, nil),
+		}, ErrNotLinearizable},
+		{"popped twice", History{
+			invoke(1, OpPush, "a"), ret(1, OpPush, nil),
+			invoke(2, OpPop, nil), invoke(3, OpPop, nil),
+			ret(2, OpPop, "a"), ret(3, OpPop, "a"),
+		}, ErrNotLinearizable},
+		{"first in first out", History{
+			invoke(1, OpPush, "a"), ret(1, OpPush, nil),
+			invoke(2, OpPush, "b"), ret(2, OpPush, nil),
+			invoke(3, OpPop, nil), ret(3, OpPop, "a"),
+		}, ErrNotLinearizable},
+		// what UnsafeStack does when two pushes both read the old top before either
+		// sets the new one: the first push is lost
+		{"lost push returning first", History{
+			invoke(1, OpPush, "a"), invoke(2, OpPush, "b"),
+			ret(1, OpPush, nil), ret(2, OpPush, nil),
+			invoke(3, OpPop, nil), ret(3, OpPop, "b"),
+			invoke(4, OpPop, nil), ret(4, OpPop, nil),
+		}, ErrNotLinearizable},
+		{"wrong size", History{
+			invoke(1, OpPush, "a"), ret(1, OpPush, nil),
+			invoke(2, OpSize, nil), ret(2, OpSize, 0),
+		}, ErrNotLinearizable},
+	}
+	for _, test := range tests {
+		if err := CheckStack(test.history); !errors.Is(err, test.want) {
+			t.Errorf("CheckStack() of the %s history: want '%v', got '%v'", test.name, test.want, err)
+		}
+	}
+
+	// incomplete histories can't be checked
+	for _, history := range []History{
+		{invoke(1, OpPush, "a")},
+		{ret(1, OpPush, nil)},
+		{invoke(1, OpPush, "a"), invoke(1, OpPush, "b"), ret(1, OpPush, nil)},
+	} {
+		if err := CheckStack(history); err == nil || errors.Is(err, ErrNotLinearizable) {
+			t.Errorf("CheckStack(%v): want an invalid history error, got '%v'", history, err)
+		}
+	}
+}
+
+// recordStackAccess lets goroutines perform operations on stack at the same time, and
+// returns the history of what they did. Size is only called if sizes is true. If an
+// operation panics, the history is not returned, and the panic is returned as an error.
+func recordStackAccess(stack Stack, goroutines, operations int, sizes bool) (History, error) {
+	var r Recorder
+	var wg sync.WaitGroup
+	var once sync.Once
+	var panicked error
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(i int, stack Stack) {
+			defer wg.Done()
+			defer func() {
+				if v := recover(); v != nil {
+					once.Do(func() { panicked = fmt.Errorf("goroutine %d panicked: %v", i, v) })
+				}
+			}()
+			for j := 0; j < operations; j++ {
+				switch {
+				case sizes && j%5 == 4:
+					stack.Size()
+				case (i+j)%2 == 0:
+					stack.Push("Data" + strconv.Itoa(i) + "-" + strconv.Itoa(j))
+				default:
+					stack.Pop()
+				}
+			}
+		}(i, r.Stack(i, stack))
+	}
+	wg.Wait()
+	if panicked != nil {
+		return nil, panicked
+	}
+	return r.History(), nil
+}
+
+func TestLinearizableStacks(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+	cspStack := NewCspStack()
+	defer cspStack.Close()
+	stacks := []struct {
+		name  string
+		stack Stack
+		sizes bool
+	}{
+		{"SafeStack", new(SafeStack), true},
+		{"SliceStack", NewSliceStack(), true},
+		{"CspStack", cspStack, true},
+		// the size of a LockFreeStack is only approximate while it is used
+		{"LockFreeStack", new(LockFreeStack), false},
+	}
+	for _, s := range stacks {
+		history, err := recordStackAccess(s.stack, 4, 20000, s.sizes)
+		if err != nil {
+			t.Fatalf("%s: %v", s.name, err)
+		}
+		if err := CheckStack(history); err != nil {
+			t.Errorf("%s: %v", s.name, err)
+		}
+	}
+}
+
+func TestUnsafeStackNotLinearizable(t *testing.T) {
+	var r Recorder
+	us := new(UnsafeStack)
+	r.Stack(0, us).Push("x")
+
+	// both pushes read the old top before either of them sets the new one, so the
+	// push that sets it first is lost
+	var readTop sync.WaitGroup
+	readTop.Add(2)
+	unsafePushHook = func() {
+		readTop.Done()
+		readTop.Wait()
+	}
+	defer func() { unsafePushHook = nil }()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	for i, value := range []string{"a", "b"} {
+		go func(stack Stack, value string) {
+			defer wg.Done()
+			stack.Push(value)
+		}(r.Stack(i+1, us), value)
+	}
+	wg.Wait()
+	unsafePushHook = nil
+
+	// the lost push leaves the size one larger than the list, so only pop the elements
+	// that are there
+	popper := r.Stack(3, us)
+	for i := 0; i < 2; i++ {
+		popper.Pop()
+	}
+	if err := CheckStack(r.History()); !errors.Is(err, ErrNotLinearizable) {
+		t.Errorf("CheckStack() of two interleaved pushes on an UnsafeStack: want '%v', got '%v'", ErrNotLinearizable, err)
+	}
+}
@@ -0,0 +1,48 @@
+package stack
+
+import "container/heap"
+
+// CspPriorityQueue is a PriorityQueue owned by a single goroutine, which runs the
+// operations sent to it. The goroutine runs until Close is called.
+type CspPriorityQueue struct {
+	server *cspServer
+	items  pqHeap // only accessed by the server goroutine
+	seq    uint64 // only accessed by the server goroutine
+}
+
+// NewCspPriorityQueue returns an empty CspPriorityQueue.
+// Close must be called when the queue is no longer used, to stop its goroutine.
+func NewCspPriorityQueue() *CspPriorityQueue {
+	return &CspPriorityQueue{server: newCspServer()}
+}
+
+// Size returns the size of the priority queue, or 0 if the queue is closed.
+func (pq *CspPriorityQueue) Size() (size int) {
+	_ = pq.server.do(func() { size = pq.items.Len() })
+	return size
+}
+
+// Push adds value to the queue with the given priority. The value is dropped if the queue is closed.
+func (pq *CspPriorityQueue) Push(value interface{}, priority int) {
+	_ = pq.server.do(func() {
+		pq.seq++
+		heap.Push(&pq.items, pqItem{value, priority, pq.seq})
+	})
+}
+
+// Pop removes the value with the highest priority and returns it.
+// It returns nil if the queue is empty or closed.
+func (pq *CspPriorityQueue) Pop() (value interface{}) {
+	_ = pq.server.do(func() {
+		if pq.items.Len() > 0 {
+			value = heap.Pop(&pq.items).(pqItem).value
+		}
+	})
+	return value
+}
+
+// Close stops the goroutine serving the queue and waits for it to finish.
+// Closing a closed queue returns ErrClosed.
+func (pq *CspPriorityQueue) Close() error {
+	return pq.server.close()
+}
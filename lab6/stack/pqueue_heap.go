@@ -0,0 +1,37 @@
+package stack
+
+// pqItem is a value in a priority queue. seq records the order values were pushed in,
+// so values with the same priority come out first in, first out.
+type pqItem struct {
+	value    interface{}
+	priority int
+	seq      uint64
+}
+
+// before reports whether a should be popped before b.
+func (a pqItem) before(b pqItem) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+
+// pqHeap implements heap.Interface for the priority queues that are protected by a
+// mutex or owned by a single goroutine.
+type pqHeap []pqItem
+
+func (h pqHeap) Len() int           { return len(h) }
+func (h pqHeap) Less(i, j int) bool { return h[i].before(h[j]) }
+func (h pqHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *pqHeap) Push(x interface{}) {
+	*h = append(*h, x.(pqItem))
+}
+
+func (h *pqHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	old[len(old)-1] = pqItem{} // don't keep the popped value alive
+	*h = old[:len(old)-1]
+	return item
+}
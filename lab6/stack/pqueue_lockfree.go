@@ -0,0 +1,81 @@
+package stack
+
+import "sync/atomic"
+
+// LockFreePriorityQueue is a PriorityQueue whose whole content is an immutable leftist
+// heap, replaced with compare-and-swap on every change. An operation that loses the race
+// simply retries on the new heap, so no goroutine ever waits for another.
+// Push and Pop allocate O(log n) new nodes each, and share the rest with the old heap.
+type LockFreePriorityQueue struct {
+	root atomic.Pointer[heapNode] // nil means empty
+	seq  atomic.Uint64
+}
+
+// heapNode is a node in an immutable leftist heap. rank is the length of the path to the
+// nearest missing child, which is never shorter on the left, so the right path is short.
+type heapNode struct {
+	item        pqItem
+	rank, size  int
+	left, right *heapNode
+}
+
+// Size returns the size of the priority queue.
+func (pq *LockFreePriorityQueue) Size() int {
+	return pq.root.Load().len()
+}
+
+// Push adds value to the queue with the given priority.
+func (pq *LockFreePriorityQueue) Push(value interface{}, priority int) {
+	n := &heapNode{item: pqItem{value, priority, pq.seq.Add(1)}, rank: 1, size: 1}
+	for {
+		root := pq.root.Load()
+		if pq.root.CompareAndSwap(root, merge(root, n)) {
+			return
+		}
+	}
+}
+
+// Pop removes the value with the highest priority and returns it.
+func (pq *LockFreePriorityQueue) Pop() interface{} {
+	for {
+		root := pq.root.Load()
+		if root == nil {
+			return nil
+		}
+		if pq.root.CompareAndSwap(root, merge(root.left, root.right)) {
+			return root.item.value
+		}
+	}
+}
+
+func (h *heapNode) len() int {
+	if h == nil {
+		return 0
+	}
+	return h.size
+}
+
+func (h *heapNode) rankOf() int {
+	if h == nil {
+		return 0
+	}
+	return h.rank
+}
+
+// merge returns a new heap with the values of both a and b, which are left unchanged.
+func merge(a, b *heapNode) *heapNode {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	if b.item.before(a.item) {
+		a, b = b, a
+	}
+	left, right := a.left, merge(a.right, b)
+	if left.rankOf() < right.rankOf() {
+		left, right = right, left
+	}
+	return &heapNode{item: a.item, rank: right.rankOf() + 1, size: a.size + b.size, left: left, right: right}
+}
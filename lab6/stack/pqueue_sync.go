@@ -0,0 +1,38 @@
+package stack
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// SafePriorityQueue is a PriorityQueue protected by a mutex.
+type SafePriorityQueue struct {
+	mu    sync.RWMutex // guards items and seq
+	items pqHeap
+	seq   uint64
+}
+
+// Size returns the size of the priority queue.
+func (pq *SafePriorityQueue) Size() int {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	return pq.items.Len()
+}
+
+// Push adds value to the queue with the given priority.
+func (pq *SafePriorityQueue) Push(value interface{}, priority int) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	pq.seq++
+	heap.Push(&pq.items, pqItem{value, priority, pq.seq})
+}
+
+// Pop removes the value with the highest priority and returns it.
+func (pq *SafePriorityQueue) Pop() interface{} {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	if pq.items.Len() == 0 {
+		return nil
+	}
+	return heap.Pop(&pq.items).(pqItem).value
+}
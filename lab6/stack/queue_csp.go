@@ -0,0 +1,38 @@
+package stack
+
+// CspQueue is a Queue owned by a single goroutine, which runs the operations sent to it.
+// The goroutine runs until Close is called.
+type CspQueue struct {
+	server *cspServer
+	items  ring // only accessed by the server goroutine
+}
+
+// NewCspQueue returns an empty CspQueue.
+// Close must be called when the queue is no longer used, to stop its goroutine.
+func NewCspQueue() *CspQueue {
+	return &CspQueue{server: newCspServer()}
+}
+
+// Size returns the size of the queue, or 0 if the queue is closed.
+func (cq *CspQueue) Size() (size int) {
+	_ = cq.server.do(func() { size = cq.items.len() })
+	return size
+}
+
+// Enqueue adds value to the back of the queue. The value is dropped if the queue is closed.
+func (cq *CspQueue) Enqueue(value interface{}) {
+	_ = cq.server.do(func() { cq.items.pushBack(value) })
+}
+
+// Dequeue removes the value at the front of the queue and returns it.
+// It returns nil if the queue is empty or closed.
+func (cq *CspQueue) Dequeue() (value interface{}) {
+	_ = cq.server.do(func() { value = cq.items.popFront() })
+	return value
+}
+
+// Close stops the goroutine serving the queue and waits for it to finish.
+// Closing a closed queue returns ErrClosed.
+func (cq *CspQueue) Close() error {
+	return cq.server.close()
+}
@@ -0,0 +1,40 @@
+package stack
+
+// Queue interface has methods for interacting with a FIFO queue.
+type Queue interface {
+	// Size returns the size of the queue.
+	Size() int
+	// Enqueue adds value to the back of the queue.
+	Enqueue(value interface{})
+	// Dequeue removes the value at the front of the queue and returns it.
+	// It returns nil if the queue is empty.
+	Dequeue() interface{}
+}
+
+// Deque interface has methods for interacting with a double-ended queue.
+type Deque interface {
+	// Size returns the size of the deque.
+	Size() int
+	// PushFront adds value to the front of the deque.
+	PushFront(value interface{})
+	// PushBack adds value to the back of the deque.
+	PushBack(value interface{})
+	// PopFront removes the value at the front of the deque and returns it.
+	// It returns nil if the deque is empty.
+	PopFront() interface{}
+	// PopBack removes the value at the back of the deque and returns it.
+	// It returns nil if the deque is empty.
+	PopBack() interface{}
+}
+
+// PriorityQueue interface has methods for interacting with a priority queue.
+type PriorityQueue interface {
+	// Size returns the size of the priority queue.
+	Size() int
+	// Push adds value to the queue with the given priority.
+	Push(value interface{}, priority int)
+	// Pop removes the value with the highest priority and returns it. Values with the
+	// same priority are popped in the order they were pushed. It returns nil if the
+	// queue is empty.
+	Pop() interface{}
+}
@@ -0,0 +1,84 @@
+package stack
+
+import "sync/atomic"
+
+// LockFreeQueue is a Michael-Scott queue: a linked list with a dummy node at the
+// front, where head and tail are only ever changed with compare-and-swap.
+// A goroutine that finds tail lagging behind the last node helps move it forward
+// instead of waiting for the goroutine that appended the node.
+// Like LockFreeStack, nodes are never recycled, which avoids the ABA problem.
+type LockFreeQueue struct {
+	head atomic.Pointer[queueNode] // dummy node; the front value is in head.next
+	tail atomic.Pointer[queueNode] // last node, or the one before it
+	size atomic.Int64
+}
+
+type queueNode struct {
+	value interface{}
+	next  atomic.Pointer[queueNode]
+}
+
+// NewLockFreeQueue returns an empty LockFreeQueue.
+func NewLockFreeQueue() *LockFreeQueue {
+	q := new(LockFreeQueue)
+	dummy := new(queueNode)
+	q.head.Store(dummy)
+	q.tail.Store(dummy)
+	return q
+}
+
+// Size returns the size of the queue. While other goroutines enqueue and dequeue,
+// the size is only approximate, since it is updated after the list has changed.
+func (q *LockFreeQueue) Size() int {
+	if size := q.size.Load(); size > 0 {
+		return int(size)
+	}
+	return 0
+}
+
+// Enqueue adds value to the back of the queue.
+func (q *LockFreeQueue) Enqueue(value interface{}) {
+	n := &queueNode{value: value}
+	for {
+		tail := q.tail.Load()
+		next := tail.next.Load()
+		if tail != q.tail.Load() {
+			continue
+		}
+		if next != nil {
+			// another Enqueue appended a node but hasn't moved tail yet; help it
+			q.tail.CompareAndSwap(tail, next)
+			continue
+		}
+		if tail.next.CompareAndSwap(nil, n) {
+			q.tail.CompareAndSwap(tail, n)
+			q.size.Add(1)
+			return
+		}
+	}
+}
+
+// Dequeue removes the value at the front of the queue and returns it.
+func (q *LockFreeQueue) Dequeue() interface{} {
+	for {
+		head := q.head.Load()
+		tail := q.tail.Load()
+		next := head.next.Load()
+		if head != q.head.Load() {
+			continue
+		}
+		if next == nil {
+			return nil
+		}
+		if head == tail {
+			// tail lags behind the node that was just appended; help move it
+			q.tail.CompareAndSwap(tail, next)
+			continue
+		}
+		// next becomes the new dummy node; its value never changes, so it is safe to read
+		if q.head.CompareAndSwap(head, next) {
+			q.size.Add(-1)
+			return next.value
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package stack
+
+import "sync"
+
+// SafeQueue is a Queue protected by a mutex.
+type SafeQueue struct {
+	mu    sync.RWMutex // guards items
+	items ring
+}
+
+// Size returns the size of the queue.
+func (sq *SafeQueue) Size() int {
+	sq.mu.RLock()
+	defer sq.mu.RUnlock()
+	return sq.items.len()
+}
+
+// Enqueue adds value to the back of the queue.
+func (sq *SafeQueue) Enqueue(value interface{}) {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	sq.items.pushBack(value)
+}
+
+// Dequeue removes the value at the front of the queue and returns it.
+func (sq *SafeQueue) Dequeue() interface{} {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+	return sq.items.popFront()
+}
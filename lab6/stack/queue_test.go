@@ -0,0 +1,305 @@
+package stack
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+var (
+	_ = []Queue{(*SafeQueue)(nil), (*LockFreeQueue)(nil), (*CspQueue)(nil)}
+	_ = []Deque{(*SafeDeque)(nil), (*LockFreeDeque)(nil), (*CspDeque)(nil)}
+	_ = []PriorityQueue{(*SafePriorityQueue)(nil), (*LockFreePriorityQueue)(nil), (*CspPriorityQueue)(nil)}
+)
+
+// closer is implemented by the CSP data structures.
+type closer interface {
+	Close() error
+}
+
+// closeWhenDone closes v when the test or benchmark ends, if it is a CSP data structure.
+func closeWhenDone[T any](tb testing.TB, v T) T {
+	if c, ok := any(v).(closer); ok {
+		tb.Cleanup(func() { c.Close() })
+	}
+	return v
+}
+
+func queues(tb testing.TB) map[string]Queue {
+	return map[string]Queue{
+		"SafeQueue":     new(SafeQueue),
+		"LockFreeQueue": NewLockFreeQueue(),
+		"CspQueue":      closeWhenDone(tb, NewCspQueue()),
+	}
+}
+
+func deques(tb testing.TB) map[string]Deque {
+	return map[string]Deque{
+		"SafeDeque":     new(SafeDeque),
+		"LockFreeDeque": new(LockFreeDeque),
+		"CspDeque":      closeWhenDone(tb, NewCspDeque()),
+	}
+}
+
+func priorityQueues(tb testing.TB) map[string]PriorityQueue {
+	return map[string]PriorityQueue{
+		"SafePriorityQueue":     new(SafePriorityQueue),
+		"LockFreePriorityQueue": new(LockFreePriorityQueue),
+		"CspPriorityQueue":      closeWhenDone(tb, NewCspPriorityQueue()),
+	}
+}
+
+// queueStack lets the stack test harness drive a Queue.
+type queueStack struct{ Queue }
+
+func (q queueStack) Push(value interface{}) { q.Enqueue(value) }
+func (q queueStack) Pop() interface{}       { return q.Dequeue() }
+
+// dequeStack uses the back of a Deque as a stack.
+type dequeStack struct{ Deque }
+
+func (d dequeStack) Push(value interface{}) { d.PushBack(value) }
+func (d dequeStack) Pop() interface{}       { return d.PopBack() }
+
+// priorityStack uses a PriorityQueue as a stack, by giving every pushed value a higher
+// priority than the ones before it.
+type priorityStack struct {
+	PriorityQueue
+	priority *atomic.Int64
+}
+
+func (p priorityStack) Push(value interface{}) { p.PriorityQueue.Push(value, int(p.priority.Add(1))) }
+
+// allAsStacks returns every queue, deque and priority queue driven through the Stack interface.
+func allAsStacks(tb testing.TB) map[string]Stack {
+	stacks := make(map[string]Stack)
+	for name, q := range queues(tb) {
+		stacks[name] = queueStack{q}
+	}
+	for name, d := range deques(tb) {
+		stacks[name] = dequeStack{d}
+	}
+	for name, pq := range priorityQueues(tb) {
+		stacks[name] = priorityStack{pq, new(atomic.Int64)}
+	}
+	return stacks
+}
+
+func TestQueues(t *testing.T) {
+	runtime.GOMAXPROCS(runtime.NumCPU())
+	for name, s := range allAsStacks(t) {
+		fmt.Printf("%s Test\n", name)
+		testConcurrentStackAccess(s)
+	}
+}
+
+func TestOpsDequesAndPriorityQueues(t *testing.T) {
+	// used as stacks, deques and priority queues behave exactly like the stacks
+	for name, s := range allAsStacks(t) {
+		if _, ok := s.(queueStack); ok {
+			continue
+		}
+		fmt.Printf("Test operations %s\n", name)
+		testStackOperations(s, t)
+	}
+}
+
+func TestOpsQueues(t *testing.T) {
+	all := make(map[string]Queue)
+	for name, q := range queues(t) {
+		all[name] = q
+	}
+	// a deque used from opposite ends is a queue, in both directions
+	for name, d := range deques(t) {
+		all[name+"/back-to-front"] = dequeQueue{d, false}
+		all[name+"/front-to-back"] = dequeQueue{d, true}
+	}
+	for name, q := range all {
+		fmt.Printf("Test operations %s\n", name)
+		testQueueOperations(q, t)
+	}
+}
+
+func TestOpsDeques(t *testing.T) {
+	// random operations must give the same results as on a plain slice
+	for name, d := range deques(t) {
+		rng := rand.New(rand.NewSource(1))
+		var want []interface{}
+		for i := 0; i < 2000; i++ {
+			var got, expected interface{}
+			switch rng.Intn(4) {
+			case 0:
+				d.PushFront(i)
+				want = append([]interface{}{i}, want...)
+			case 1:
+				d.PushBack(i)
+				want = append(want, i)
+			case 2:
+				got = d.PopFront()
+				if len(want) > 0 {
+					expected, want = want[0], want[1:]
+				}
+			case 3:
+				got = d.PopBack()
+				if len(want) > 0 {
+					expected, want = want[len(want)-1], want[:len(want)-1]
+				}
+			}
+			if got != expected {
+				t.Fatalf("%s: operation %d returned %v, want %v", name, i, got, expected)
+			}
+			if d.Size() != len(want) {
+				t.Fatalf("%s: Size() after operation %d = %d, want %d", name, i, d.Size(), len(want))
+			}
+		}
+	}
+}
+
+func TestOpsPriorityQueues(t *testing.T) {
+	for name, pq := range priorityQueues(t) {
+		pq.Push("low", 1)
+		pq.Push("high", 10)
+		pq.Push("first medium", 5)
+		pq.Push("negative", -3)
+		pq.Push("second medium", 5)
+		if size := pq.Size(); size != 5 {
+			t.Errorf("%s: Size() = %d, want 5", name, size)
+		}
+		for _, want := range []interface{}{"high", "first medium", "second medium", "low", "negative", nil} {
+			if got := pq.Pop(); got != want {
+				t.Errorf("%s: Pop() = %v, want %v", name, got, want)
+			}
+		}
+	}
+}
+
+func TestQueuesNoLostValues(t *testing.T) {
+	const producers, consumers, perProducer = 4, 4, 1000
+	for name, s := range allAsStacks(t) {
+		var wg sync.WaitGroup
+		wg.Add(producers)
+		for i := 0; i < producers; i++ {
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < perProducer; j++ {
+					s.Push(i*perProducer + j)
+				}
+			}(i)
+		}
+		var popped sync.Map
+		var count atomic.Int64
+		var consumerWg sync.WaitGroup
+		consumerWg.Add(consumers)
+		for i := 0; i < consumers; i++ {
+			go func() {
+				defer consumerWg.Done()
+				for count.Load() < producers*perProducer {
+					if v := s.Pop(); v != nil {
+						if _, dup := popped.LoadOrStore(v, true); dup {
+							t.Errorf("%s: value %v was popped twice", name, v)
+						}
+						count.Add(1)
+					} else {
+						runtime.Gosched()
+					}
+				}
+			}()
+		}
+		wg.Wait()
+		consumerWg.Wait()
+		if n := count.Load(); n != producers*perProducer {
+			t.Errorf("%s: popped %d values, want %d", name, n, producers*perProducer)
+		}
+	}
+}
+
+func TestCspQueuesClose(t *testing.T) {
+	q, d, pq := NewCspQueue(), NewCspDeque(), NewCspPriorityQueue()
+	q.Enqueue(1)
+	for _, c := range []closer{q, d, pq} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Close(); err != ErrClosed {
+			t.Errorf("second Close(): want '%v', got '%v'", ErrClosed, err)
+		}
+	}
+	q.Enqueue(2)
+	if v := q.Dequeue(); v != nil {
+		t.Errorf("Dequeue() after Close() = %v, want nil", v)
+	}
+}
+
+// BenchmarkQueues runs the stack benchmark on every queue, deque and priority queue.
+func BenchmarkQueues(b *testing.B) {
+	for name, s := range allAsStacks(b) {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				benchStackOperations(s)
+			}
+		})
+	}
+}
+
+// BenchmarkQueuesParallel compares the throughput of the queues, deques and priority
+// queues when 1 to 64 goroutines use the same one at once, like BenchmarkStacksParallel.
+func BenchmarkQueuesParallel(b *testing.B) {
+	for name := range allAsStacks(b) {
+		for goroutines := 1; goroutines <= 64; goroutines *= 2 {
+			b.Run(fmt.Sprintf("%s/goroutines=%d", name, goroutines), func(b *testing.B) {
+				benchParallelStackOperations(b, allAsStacks(b)[name], goroutines)
+			})
+		}
+	}
+}
+
+// dequeQueue uses a Deque as a Queue, adding at one end and removing at the other.
+type dequeQueue struct {
+	Deque
+	reversed bool // add at the front and remove at the back
+}
+
+func (d dequeQueue) Enqueue(value interface{}) {
+	if d.reversed {
+		d.PushFront(value)
+	} else {
+		d.PushBack(value)
+	}
+}
+
+func (d dequeQueue) Dequeue() interface{} {
+	if d.reversed {
+		return d.PopBack()
+	}
+	return d.PopFront()
+}
+
+func testQueueOperations(q Queue, t *testing.T) {
+	if v := q.Dequeue(); v != nil {
+		t.Errorf("Dequeue() on an empty queue = %v, want nil", v)
+	}
+	const size = 200
+	for i := 0; i < size; i++ {
+		q.Enqueue(i)
+		if i%3 == 2 {
+			// interleave dequeues so that lists and buffers wrap around
+			if v := q.Dequeue(); v != i/3 {
+				t.Fatalf("Dequeue() = %v, want %d", v, i/3)
+			}
+		}
+	}
+	if length := q.Size(); length != size-size/3 {
+		t.Errorf("Size() = %d, want %d", length, size-size/3)
+	}
+	for i := size / 3; i < size; i++ {
+		if v := q.Dequeue(); v != i {
+			t.Fatalf("Dequeue() = %v, want %d", v, i)
+		}
+	}
+	if length := q.Size(); length != 0 {
+		t.Errorf("Size() of an emptied queue = %d, want 0", length)
+	}
+}
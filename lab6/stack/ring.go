@@ -0,0 +1,63 @@
+package stack
+
+// ring is a growable circular buffer, used by the queues and deques that are
+// protected by a mutex or owned by a single goroutine. It is not safe for concurrent use.
+type ring struct {
+	buf  []interface{}
+	head int // index of the front value
+	size int
+}
+
+// len returns the number of values in the buffer.
+func (r *ring) len() int {
+	return r.size
+}
+
+// pushBack adds value after the back value.
+func (r *ring) pushBack(value interface{}) {
+	r.grow()
+	r.buf[(r.head+r.size)%len(r.buf)] = value
+	r.size++
+}
+
+// pushFront adds value before the front value.
+func (r *ring) pushFront(value interface{}) {
+	r.grow()
+	r.head = (r.head - 1 + len(r.buf)) % len(r.buf)
+	r.buf[r.head] = value
+	r.size++
+}
+
+// popFront removes and returns the front value, or nil if the buffer is empty.
+func (r *ring) popFront() (value interface{}) {
+	if r.size == 0 {
+		return nil
+	}
+	value, r.buf[r.head] = r.buf[r.head], nil
+	r.head = (r.head + 1) % len(r.buf)
+	r.size--
+	return value
+}
+
+// popBack removes and returns the back value, or nil if the buffer is empty.
+func (r *ring) popBack() (value interface{}) {
+	if r.size == 0 {
+		return nil
+	}
+	i := (r.head + r.size - 1) % len(r.buf)
+	value, r.buf[i] = r.buf[i], nil
+	r.size--
+	return value
+}
+
+// grow makes room for one more value.
+func (r *ring) grow() {
+	if r.size < len(r.buf) {
+		return
+	}
+	buf := make([]interface{}, max(DefaultCap, 2*len(r.buf)))
+	for i := 0; i < r.size; i++ {
+		buf[i] = r.buf[(r.head+i)%len(r.buf)]
+	}
+	r.buf, r.head = buf, 0
+}
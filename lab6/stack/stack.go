@@ -11,9 +11,17 @@ func (us *UnsafeStack) Size() int {
 	return us.size
 }
 
+// unsafePushHook, if not nil, is called by UnsafeStack.Push between reading the top of
+// the stack and setting the new one. Tests use it to interleave two pushes.
+var unsafePushHook func()
+
 // Push pushes value onto the stack.
 func (us *UnsafeStack) Push(value interface{}) {
-	us.top = &Element{value, us.top}
+	top := us.top
+	if unsafePushHook != nil {
+		unsafePushHook()
+	}
+	us.top = &Element{value, top}
 	us.size++
 }
 
@@ -0,0 +1,145 @@
+package stack
+
+import (
+	"context"
+	"sync"
+)
+
+// BlockingStack is a stack with a fixed capacity. Push blocks while the stack is full
+// and Pop blocks while it is empty, so Pop never returns nil for an empty stack.
+type BlockingStack interface {
+	Stack
+	// Cap returns the capacity of the stack.
+	Cap() int
+	// PushCtx pushes value onto the stack, waiting until there is room or ctx is done.
+	PushCtx(ctx context.Context, value interface{}) error
+	// PopCtx pops the value at the top of the stack, waiting until there is one or ctx is done.
+	PopCtx(ctx context.Context) (interface{}, error)
+	// TryPush pushes value onto the stack if it is not full, and reports whether it did.
+	TryPush(value interface{}) bool
+	// TryPop pops the value at the top of the stack if it is not empty, and reports whether it did.
+	TryPop() (interface{}, bool)
+}
+
+// BoundedStack is a BlockingStack protected by a mutex.
+type BoundedStack struct {
+	mu       sync.Mutex // guards slice and changed
+	slice    []interface{}
+	changed  chan struct{} // closed and replaced whenever the size changes
+	capacity int
+}
+
+// NewBoundedStack returns an empty BoundedStack that holds at most capacity values.
+// A capacity less than 1 gives a stack with DefaultCap capacity.
+func NewBoundedStack(capacity int) *BoundedStack {
+	if capacity < 1 {
+		capacity = DefaultCap
+	}
+	return &BoundedStack{
+		slice:    make([]interface{}, 0, capacity),
+		changed:  make(chan struct{}),
+		capacity: capacity,
+	}
+}
+
+// Size returns the size of the stack.
+func (bs *BoundedStack) Size() int {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	return len(bs.slice)
+}
+
+// Cap returns the capacity of the stack.
+func (bs *BoundedStack) Cap() int {
+	return bs.capacity
+}
+
+// Push pushes value onto the stack, blocking while the stack is full.
+func (bs *BoundedStack) Push(value interface{}) {
+	_ = bs.PushCtx(context.Background(), value)
+}
+
+// Pop pops the value at the top of the stack and returns it, blocking while the stack is empty.
+func (bs *BoundedStack) Pop() (value interface{}) {
+	value, _ = bs.PopCtx(context.Background())
+	return value
+}
+
+// PushCtx pushes value onto the stack, waiting until there is room or ctx is done.
+func (bs *BoundedStack) PushCtx(ctx context.Context, value interface{}) error {
+	for {
+		bs.mu.Lock()
+		if len(bs.slice) < bs.capacity {
+			bs.slice = append(bs.slice, value)
+			bs.broadcast()
+			bs.mu.Unlock()
+			return nil
+		}
+		changed := bs.changed
+		bs.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// PopCtx pops the value at the top of the stack, waiting until there is one or ctx is done.
+func (bs *BoundedStack) PopCtx(ctx context.Context) (interface{}, error) {
+	for {
+		bs.mu.Lock()
+		if len(bs.slice) > 0 {
+			value := bs.pop()
+			bs.mu.Unlock()
+			return value, nil
+		}
+		changed := bs.changed
+		bs.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// TryPush pushes value onto the stack if it is not full, and reports whether it did.
+func (bs *BoundedStack) TryPush(value interface{}) bool {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if len(bs.slice) == bs.capacity {
+		return false
+	}
+	bs.slice = append(bs.slice, value)
+	bs.broadcast()
+	return true
+}
+
+// TryPop pops the value at the top of the stack if it is not empty, and reports whether it did.
+func (bs *BoundedStack) TryPop() (interface{}, bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+	if len(bs.slice) == 0 {
+		return nil, false
+	}
+	return bs.pop(), true
+}
+
+// pop removes and returns the top value. The caller must hold bs.mu and the stack must not be empty.
+func (bs *BoundedStack) pop() interface{} {
+	n := len(bs.slice)
+	value := bs.slice[n-1]
+	bs.slice[n-1] = nil // don't keep the popped value alive
+	bs.slice = bs.slice[:n-1]
+	bs.broadcast()
+	return value
+}
+
+// broadcast wakes up all goroutines waiting for the size to change. The caller must hold bs.mu.
+func (bs *BoundedStack) broadcast() {
+	close(bs.changed)
+	bs.changed = make(chan struct{})
+}
@@ -0,0 +1,204 @@
+package stack
+
+import (
+	"context"
+	"sync"
+)
+
+// BoundedCspStack is a BlockingStack owned by a single goroutine, which serves requests
+// sent over channels. The goroutine only receives pushes while the stack has room and
+// only offers the top value while the stack is not empty, so blocked operations simply
+// wait on their channel. The goroutine runs until Close is called.
+type BoundedCspStack struct {
+	pushChan      chan interface{}
+	popChan       chan interface{}
+	tryPushChan   chan tryPush
+	tryPopChan    chan chan tryPop
+	lenChan       chan interface{}
+	lenReturnChan chan int
+	stack         []interface{}
+	capacity      int
+
+	closeOnce sync.Once
+	done      chan struct{} // closed by Close to stop the goroutine
+	stopped   chan struct{} // closed by the goroutine when it has stopped
+}
+
+// tryPush is a TryPush request and the channel its result is returned on.
+type tryPush struct {
+	value interface{}
+	ok    chan bool
+}
+
+// tryPop is the result of a TryPop request.
+type tryPop struct {
+	value interface{}
+	ok    bool
+}
+
+// NewBoundedCspStack returns an empty BoundedCspStack that holds at most capacity values.
+// A capacity less than 1 gives a stack with DefaultCap capacity.
+// Close must be called when the stack is no longer used, to stop its goroutine.
+func NewBoundedCspStack(capacity int) *BoundedCspStack {
+	if capacity < 1 {
+		capacity = DefaultCap
+	}
+	cspStack := &BoundedCspStack{
+		pushChan:      make(chan interface{}),
+		popChan:       make(chan interface{}),
+		tryPushChan:   make(chan tryPush),
+		tryPopChan:    make(chan chan tryPop),
+		lenChan:       make(chan interface{}),
+		lenReturnChan: make(chan int),
+		stack:         make([]interface{}, 0, capacity),
+		capacity:      capacity,
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	go cspStack.run()
+	return cspStack
+}
+
+// Size returns the size of the stack, or 0 if the stack is closed.
+func (cs *BoundedCspStack) Size() int {
+	// Signal that we want to know the size
+	select {
+	case cs.lenChan <- true:
+		return <-cs.lenReturnChan
+	case <-cs.done:
+		return 0
+	}
+}
+
+// Cap returns the capacity of the stack.
+func (cs *BoundedCspStack) Cap() int {
+	return cs.capacity
+}
+
+// Push pushes value onto the stack, blocking while the stack is full.
+// The value is dropped if the stack is closed; use PushCtx to detect that.
+func (cs *BoundedCspStack) Push(value interface{}) {
+	_ = cs.PushCtx(context.Background(), value)
+}
+
+// Pop pops the value at the top of the stack and returns it, blocking while the stack is empty.
+// It returns nil if the stack is closed; use PopCtx to detect that.
+func (cs *BoundedCspStack) Pop() (value interface{}) {
+	value, _ = cs.PopCtx(context.Background())
+	return value
+}
+
+// PushCtx pushes value onto the stack, waiting until there is room or ctx is done.
+// It returns ErrClosed if the stack is closed.
+func (cs *BoundedCspStack) PushCtx(ctx context.Context, value interface{}) error {
+	select {
+	case cs.pushChan <- value:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-cs.done:
+		return ErrClosed
+	}
+}
+
+// PopCtx pops the value at the top of the stack, waiting until there is one or ctx is done.
+// It returns ErrClosed if the stack is closed.
+func (cs *BoundedCspStack) PopCtx(ctx context.Context) (interface{}, error) {
+	select {
+	case value := <-cs.popChan:
+		return value, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-cs.done:
+		return nil, ErrClosed
+	}
+}
+
+// TryPush pushes value onto the stack if it is not full, and reports whether it did.
+// Nothing can be pushed onto a closed stack.
+func (cs *BoundedCspStack) TryPush(value interface{}) bool {
+	ok := make(chan bool)
+	select {
+	case cs.tryPushChan <- tryPush{value, ok}:
+		return <-ok
+	case <-cs.done:
+		return false
+	}
+}
+
+// TryPop pops the value at the top of the stack if it is not empty, and reports whether it did.
+// Nothing can be popped from a closed stack.
+func (cs *BoundedCspStack) TryPop() (interface{}, bool) {
+	result := make(chan tryPop)
+	select {
+	case cs.tryPopChan <- result:
+		r := <-result
+		return r.value, r.ok
+	case <-cs.done:
+		return nil, false
+	}
+}
+
+// Close stops the goroutine serving the stack and waits for it to finish.
+// Values left on the stack are discarded, and blocked operations return.
+// Closing a closed stack returns ErrClosed.
+func (cs *BoundedCspStack) Close() error {
+	err := ErrClosed
+	cs.closeOnce.Do(func() {
+		close(cs.done)
+		err = nil
+	})
+	<-cs.stopped
+	return err
+}
+
+func (cs *BoundedCspStack) run() {
+	defer close(cs.stopped)
+	for {
+		// a nil channel blocks forever, which disables its case in the select
+		pushChan, popChan := cs.pushChan, cs.popChan
+		var top interface{}
+		if len(cs.stack) == cs.capacity {
+			pushChan = nil
+		}
+		if len(cs.stack) == 0 {
+			popChan = nil
+		} else {
+			top = cs.stack[len(cs.stack)-1]
+		}
+
+		select {
+		case <-cs.done:
+			cs.stack = nil
+			return
+		case <-cs.lenChan:
+			cs.lenReturnChan <- len(cs.stack)
+		case value := <-pushChan:
+			cs.stack = append(cs.stack, value)
+		case popChan <- top:
+			cs.pop()
+		case req := <-cs.tryPushChan:
+			if len(cs.stack) == cs.capacity {
+				req.ok <- false
+				break
+			}
+			cs.stack = append(cs.stack, req.value)
+			req.ok <- true
+		case result := <-cs.tryPopChan:
+			if len(cs.stack) == 0 {
+				result <- tryPop{}
+				break
+			}
+			result <- tryPop{cs.pop(), true}
+		}
+	}
+}
+
+// pop removes and returns the top value. The stack must not be empty.
+func (cs *BoundedCspStack) pop() interface{} {
+	n := len(cs.stack)
+	value := cs.stack[n-1]
+	cs.stack[n-1] = nil // don't keep the popped value alive
+	cs.stack = cs.stack[:n-1]
+	return value
+}
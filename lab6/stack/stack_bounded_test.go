@@ -0,0 +1,169 @@
+package stack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+var _ = []BlockingStack{(*BoundedStack)(nil), (*BoundedCspStack)(nil)}
+
+// blockingStacks returns a new instance of each BlockingStack with the given capacity.
+// The CSP stack is closed when the test ends.
+func blockingStacks(t testing.TB, capacity int) map[string]BlockingStack {
+	cspStack := NewBoundedCspStack(capacity)
+	t.Cleanup(func() { cspStack.Close() })
+	return map[string]BlockingStack{
+		"BoundedStack":    NewBoundedStack(capacity),
+		"BoundedCspStack": cspStack,
+	}
+}
+
+func TestBlockingStack(t *testing.T) {
+	runtime.GOMAXPROCS(runtime.NumCPU())
+	for name, stack := range blockingStacks(t, numGoroutines*numOperations) {
+		fmt.Printf("%s Test\n", name)
+		testConcurrentStackAccess(&nonBlocking{stack})
+	}
+}
+
+func TestOpsBlockingStack(t *testing.T) {
+	for name, stack := range blockingStacks(t, 200) {
+		fmt.Printf("Test operations %s\n", name)
+		testStackOperations(&nonBlocking{stack}, t)
+	}
+}
+
+func TestBlockingStackTryOps(t *testing.T) {
+	for name, stack := range blockingStacks(t, 2) {
+		if stack.Cap() != 2 {
+			t.Errorf("%s: Cap() = %d, want 2", name, stack.Cap())
+		}
+		if v, ok := stack.TryPop(); ok {
+			t.Errorf("%s: TryPop() on an empty stack = (%v, true), want false", name, v)
+		}
+		if !stack.TryPush(1) || !stack.TryPush(2) {
+			t.Errorf("%s: TryPush() failed on a stack with room", name)
+		}
+		if stack.TryPush(3) {
+			t.Errorf("%s: TryPush() succeeded on a full stack", name)
+		}
+		if v, ok := stack.TryPop(); !ok || v != 2 {
+			t.Errorf("%s: TryPop() = (%v, %t), want (2, true)", name, v, ok)
+		}
+		if size := stack.Size(); size != 1 {
+			t.Errorf("%s: Size() = %d, want 1", name, size)
+		}
+	}
+}
+
+func TestBlockingStackCtx(t *testing.T) {
+	for name, stack := range blockingStacks(t, 1) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		if _, err := stack.PopCtx(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: PopCtx() on an empty stack: want '%v', got '%v'", name, context.DeadlineExceeded, err)
+		}
+		cancel()
+
+		if err := stack.PushCtx(context.Background(), "first"); err != nil {
+			t.Fatalf("%s: PushCtx() failed: %v", name, err)
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		cancel()
+		if err := stack.PushCtx(ctx, "second"); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s: PushCtx() on a full stack: want '%v', got '%v'", name, context.Canceled, err)
+		}
+		if size := stack.Size(); size != 1 {
+			t.Errorf("%s: a cancelled PushCtx() changed the size to %d", name, size)
+		}
+	}
+}
+
+func TestBlockingStackBlocks(t *testing.T) {
+	for name, stack := range blockingStacks(t, 1) {
+		stack.Push("first")
+		pushed := make(chan struct{})
+		go func() {
+			stack.Push("second") // blocks until "first" is popped
+			close(pushed)
+		}()
+		select {
+		case <-pushed:
+			t.Fatalf("%s: Push() on a full stack did not block", name)
+		case <-time.After(10 * time.Millisecond):
+		}
+		if v := stack.Pop(); v != "first" {
+			t.Errorf("%s: Pop() = %v, want first", name, v)
+		}
+		<-pushed
+		if v := stack.Pop(); v != "second" {
+			t.Errorf("%s: Pop() = %v, want second", name, v)
+		}
+
+		popped := make(chan interface{})
+		go func() { popped <- stack.Pop() }() // blocks until "third" is pushed
+		select {
+		case v := <-popped:
+			t.Fatalf("%s: Pop() on an empty stack did not block, got %v", name, v)
+		case <-time.After(10 * time.Millisecond):
+		}
+		stack.Push("third")
+		if v := <-popped; v != "third" {
+			t.Errorf("%s: Pop() = %v, want third", name, v)
+		}
+	}
+}
+
+func TestBlockingStackProducerConsumer(t *testing.T) {
+	const producers, perProducer = 4, 500
+	for name, stack := range blockingStacks(t, 8) {
+		var wg sync.WaitGroup
+		wg.Add(producers)
+		for i := 0; i < producers; i++ {
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < perProducer; j++ {
+					stack.Push(i*perProducer + j)
+				}
+			}(i)
+		}
+		seen := make(map[interface{}]bool)
+		for i := 0; i < producers*perProducer; i++ {
+			seen[stack.Pop()] = true
+		}
+		wg.Wait()
+		if len(seen) != producers*perProducer {
+			t.Errorf("%s: popped %d distinct values, want %d", name, len(seen), producers*perProducer)
+		}
+	}
+}
+
+func BenchmarkBoundedStack(b *testing.B) {
+	boundedStack := NewBoundedStack(10000)
+	for i := 0; i < b.N; i++ {
+		benchStackOperations(boundedStack)
+	}
+}
+
+func BenchmarkBoundedCspStack(b *testing.B) {
+	boundedCspStack := NewBoundedCspStack(10000)
+	defer boundedCspStack.Close()
+	for i := 0; i < b.N; i++ {
+		benchStackOperations(boundedCspStack)
+	}
+}
+
+// nonBlocking makes a BlockingStack behave like the other stacks, where Pop returns nil
+// when the stack is empty, so that random operations never block forever.
+type nonBlocking struct {
+	BlockingStack
+}
+
+func (s *nonBlocking) Pop() interface{} {
+	value, _ := s.TryPop()
+	return value
+}
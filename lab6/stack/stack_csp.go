@@ -1,52 +1,125 @@
 package stack
 
+import (
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned by operations on a CSP stack after it has been closed.
+var ErrClosed = errors.New("stack is closed")
+
 // CspStack is a struct with methods needed to implement the Stack interface.
+// The stack is served by a goroutine that runs until Close is called.
 type CspStack struct {
-	pushChan      chan interface{}
-	popStartChan  chan interface{}
-	popReturnChan chan interface{}
-	lenChan       chan interface{}
-	lenReturnChan chan int
-	stack         []interface{}
+	pushChan       chan interface{}
+	popStartChan   chan interface{}
+	popReturnChan  chan interface{}
+	lenChan        chan interface{}
+	lenReturnChan  chan int
+	pushAllChan    chan []interface{}
+	popNChan       chan int
+	popNReturnChan chan []interface{}
+	stack          []interface{}
+
+	closeOnce sync.Once
+	done      chan struct{} // closed by Close to stop the goroutine
+	stopped   chan struct{} // closed by the goroutine when it has stopped
 }
 
 // NewCspStack returns an empty CspStack.
+// Close must be called when the stack is no longer used, to stop its goroutine.
 func NewCspStack() *CspStack {
 	cspStack := &CspStack{
-		pushChan:      make(chan interface{}),
-		popReturnChan: make(chan interface{}),
-		popStartChan:  make(chan interface{}),
-		lenChan:       make(chan interface{}),
-		lenReturnChan: make(chan int),
-		stack:         []interface{}{},
+		pushChan:       make(chan interface{}),
+		popReturnChan:  make(chan interface{}),
+		popStartChan:   make(chan interface{}),
+		lenChan:        make(chan interface{}),
+		lenReturnChan:  make(chan int),
+		pushAllChan:    make(chan []interface{}),
+		popNChan:       make(chan int),
+		popNReturnChan: make(chan []interface{}),
+		stack:          []interface{}{},
+		done:           make(chan struct{}),
+		stopped:        make(chan struct{}),
 	}
 	go cspStack.run()
 	return cspStack
 }
 
-// Size returns the size of the stack.
+// Size returns the size of the stack, or 0 if the stack is closed.
 func (cs *CspStack) Size() int {
 	// Signal that we want to know the size
-	cs.lenChan <- true
-	return <-cs.lenReturnChan
+	select {
+	case cs.lenChan <- true:
+		return <-cs.lenReturnChan
+	case <-cs.done:
+		return 0
+	}
 }
 
-// Push pushes value onto the stack.
+// Push pushes value onto the stack. Since the Stack interface has no way of reporting
+// errors, the value is dropped if the stack is closed; use PushAll to detect that.
 func (cs *CspStack) Push(value interface{}) {
-	cs.pushChan <- value
+	select {
+	case cs.pushChan <- value:
+	case <-cs.done:
+	}
 }
 
-// Pop pops the value at the top of the stack and returns it.
+// Pop pops the value at the top of the stack and returns it. It returns nil if the
+// stack is empty or closed; use PopN to tell the two apart.
 func (cs *CspStack) Pop() (value interface{}) {
 	// Signal that we want to pop of the stack
-	cs.popStartChan <- true
-	return <-cs.popReturnChan
+	select {
+	case cs.popStartChan <- true:
+		return <-cs.popReturnChan
+	case <-cs.done:
+		return nil
+	}
+}
+
+// PushAll pushes all values onto the stack in a single round trip, in order,
+// so the last value ends up on top. It returns ErrClosed if the stack is closed.
+func (cs *CspStack) PushAll(values ...interface{}) error {
+	select {
+	case cs.pushAllChan <- values:
+		return nil
+	case <-cs.done:
+		return ErrClosed
+	}
+}
+
+// PopN pops up to n values from the stack in a single round trip and returns them,
+// the top value first. It returns ErrClosed if the stack is closed.
+func (cs *CspStack) PopN(n int) ([]interface{}, error) {
+	select {
+	case cs.popNChan <- n:
+		return <-cs.popNReturnChan, nil
+	case <-cs.done:
+		return nil, ErrClosed
+	}
+}
+
+// Close stops the goroutine serving the stack and waits for it to finish.
+// Values left on the stack are discarded. Closing a closed stack returns ErrClosed.
+func (cs *CspStack) Close() error {
+	err := ErrClosed
+	cs.closeOnce.Do(func() {
+		close(cs.done)
+		err = nil
+	})
+	<-cs.stopped
+	return err
 }
 
 func (cs *CspStack) run() {
+	defer close(cs.stopped)
 	var value interface{}
 	for {
 		select {
+		case <-cs.done:
+			cs.stack = nil
+			return
 		case <-cs.lenChan:
 			cs.lenReturnChan <- len(cs.stack)
 		case value = <-cs.pushChan:
@@ -60,7 +133,16 @@ func (cs *CspStack) run() {
 				// If there are no elements to pop: return nil
 				cs.popReturnChan <- nil
 			}
-
+		case values := <-cs.pushAllChan:
+			cs.stack = append(cs.stack, values...)
+		case n := <-cs.popNChan:
+			n = max(0, min(n, len(cs.stack)))
+			values := make([]interface{}, n)
+			for i := range values {
+				values[i] = cs.stack[len(cs.stack)-1-i]
+			}
+			cs.stack = cs.stack[:len(cs.stack)-n]
+			cs.popNReturnChan <- values
 		}
 	}
 }
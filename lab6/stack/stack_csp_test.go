@@ -0,0 +1,96 @@
+package stack
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCspStackBatch(t *testing.T) {
+	cspStack := NewCspStack()
+	defer cspStack.Close()
+
+	if err := cspStack.PushAll(1, 2, 3, 4); err != nil {
+		t.Fatal(err)
+	}
+	cspStack.Push(5)
+	got, err := cspStack.PopN(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []interface{}{5, 4, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PopN(3) = %v, want %v", got, want)
+	}
+	if got, _ := cspStack.PopN(10); len(got) != 2 {
+		t.Errorf("PopN(10) on a stack of 2 returned %d values, want 2", len(got))
+	}
+	if got, _ := cspStack.PopN(1); len(got) != 0 {
+		t.Errorf("PopN(1) on an empty stack returned %v, want no values", got)
+	}
+}
+
+func TestCspStackClose(t *testing.T) {
+	cspStack := NewCspStack()
+	cspStack.Push("left behind")
+	if err := cspStack.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if err := cspStack.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("second Close(): want '%v', got '%v'", ErrClosed, err)
+	}
+	if err := cspStack.PushAll(1); !errors.Is(err, ErrClosed) {
+		t.Errorf("PushAll() after Close(): want '%v', got '%v'", ErrClosed, err)
+	}
+	if _, err := cspStack.PopN(1); !errors.Is(err, ErrClosed) {
+		t.Errorf("PopN() after Close(): want '%v', got '%v'", ErrClosed, err)
+	}
+	// the Stack methods can't return errors, but must not block
+	cspStack.Push(1)
+	if v := cspStack.Pop(); v != nil {
+		t.Errorf("Pop() after Close() = %v, want nil", v)
+	}
+	if size := cspStack.Size(); size != 0 {
+		t.Errorf("Size() after Close() = %d, want 0", size)
+	}
+
+	// Close releases operations blocked on a bounded stack
+	bounded := NewBoundedCspStack(1)
+	errc := make(chan error)
+	go func() {
+		_, err := bounded.PopCtx(context.Background())
+		errc <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := bounded.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+	if err := <-errc; !errors.Is(err, ErrClosed) {
+		t.Errorf("PopCtx() blocked during Close(): want '%v', got '%v'", ErrClosed, err)
+	}
+	if bounded.TryPush(1) {
+		t.Errorf("TryPush() succeeded after Close()")
+	}
+}
+
+func TestCspStackGoroutineLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		cspStack := NewCspStack()
+		cspStack.Push(i)
+		bounded := NewBoundedCspStack(1)
+		bounded.Push(i)
+		if err := cspStack.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := bounded.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Close waits for the goroutines to stop, so none are left
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("%d goroutines leaked by closed stacks", after-before)
+	}
+}
@@ -0,0 +1,53 @@
+package stack
+
+import "sync/atomic"
+
+// LockFreeStack is a Treiber stack: a linked list whose top is only ever changed
+// with an atomic compare-and-swap, so goroutines never block each other.
+//
+// The classic ABA problem of Treiber stacks is a Pop that reads top A and its next
+// element B, is delayed while A is popped, B is popped and A's memory is pushed again,
+// and then successfully swaps top from A to the stale B. In Go, the garbage collector
+// never reuses the memory of an element while the delayed Pop still references it, so
+// A cannot come back as long as elements are never recycled. Every Push therefore
+// allocates a new Element, and Pop never puts elements back in a pool.
+type LockFreeStack struct {
+	top  atomic.Pointer[Element]
+	size atomic.Int64
+}
+
+// Size returns the size of the stack. While other goroutines push and pop,
+// the size is only approximate, since it is updated after the top has changed.
+func (ls *LockFreeStack) Size() int {
+	if size := ls.size.Load(); size > 0 {
+		return int(size)
+	}
+	return 0
+}
+
+// Push pushes value onto the stack.
+func (ls *LockFreeStack) Push(value interface{}) {
+	e := &Element{value: value}
+	for {
+		e.next = ls.top.Load()
+		if ls.top.CompareAndSwap(e.next, e) {
+			ls.size.Add(1)
+			return
+		}
+	}
+}
+
+// Pop pops the value at the top of the stack and returns it.
+func (ls *LockFreeStack) Pop() (value interface{}) {
+	for {
+		top := ls.top.Load()
+		if top == nil {
+			return nil
+		}
+		// top.next never changes once top is on the stack, so it is safe to read
+		if ls.top.CompareAndSwap(top, top.next) {
+			ls.size.Add(-1)
+			return top.value
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package stack
 
+import "sync"
+
 // DefaultCap is the default stack capacity.
 const DefaultCap = 10
 
 // SliceStack is a struct with methods needed to implement the Stack interface.
+// Each stack has its own lock, so unrelated stacks never contend with each other.
 type SliceStack struct {
+	mu    sync.RWMutex // guards slice and top
 	slice []interface{}
 	top   int
 }
@@ -19,15 +23,15 @@ func NewSliceStack() *SliceStack {
 
 // Size returns the size of the stack.
 func (ss *SliceStack) Size() int {
-	mutex.Lock()
-	defer mutex.Unlock()
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
 	return ss.top + 1
 }
 
 // Push pushes value onto the stack.
 func (ss *SliceStack) Push(value interface{}) {
-	mutex.Lock()
-	defer mutex.Unlock()
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
 	ss.top++
 
 	if ss.top == len(ss.slice) {
@@ -43,8 +47,8 @@ func (ss *SliceStack) Push(value interface{}) {
 
 // Pop pops the value at the top of the stack and returns it.
 func (ss *SliceStack) Pop() (value interface{}) {
-	mutex.Lock()
-	defer mutex.Unlock()
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
 	if ss.top > -1 {
 		defer func() { ss.top-- }()
 		return ss.slice[ss.top]
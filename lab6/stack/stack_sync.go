@@ -7,18 +7,18 @@ import (
 //Withya (1&2)
 
 // SafeStack holds the top element of the stack and its size.
+// Each stack has its own lock, so unrelated stacks never contend with each other.
 type SafeStack struct {
+	mu   sync.RWMutex // guards top and size
 	top  *Element
 	size int
 }
 
-var mutex sync.Mutex
-
 // Size returns the size of the stack.
 func (ss *SafeStack) Size() int {
 
-	mutex.Lock()
-	defer mutex.Unlock()
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
 	size := ss.size
 	return size
 
@@ -26,23 +26,22 @@ func (ss *SafeStack) Size() int {
 
 // Push pushes value onto the stack.
 func (ss *SafeStack) Push(value interface{}) {
-	mutex.Lock()
+	ss.mu.Lock()
 	ss.top = &Element{value, ss.top}
 	ss.size++
-	mutex.Unlock()
+	ss.mu.Unlock()
 }
 
 // Pop pops the value at the top of the stack and returns it.
 func (ss *SafeStack) Pop() (value interface{}) {
 
-	mutex.Lock()
-	defer mutex.Unlock()
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
 	if ss.size > 0 {
 		value, ss.top = ss.top.value, ss.top.next
 		ss.size--
 		return
 	}
-	//mutex.Unlock()
 
 	return nil
 }
@@ -27,6 +27,7 @@ func TestSafeStack(t *testing.T) {
 func TestCspStack(t *testing.T) {
 	runtime.GOMAXPROCS(runtime.NumCPU())
 	cspStack := NewCspStack()
+	defer cspStack.Close()
 	fmt.Println("CSP Stack Test")
 	testConcurrentStackAccess(cspStack)
 }
@@ -38,6 +39,75 @@ func TestSliceStack(t *testing.T) {
 	testConcurrentStackAccess(sliceStack)
 }
 
+func TestLockFreeStack(t *testing.T) {
+	runtime.GOMAXPROCS(runtime.NumCPU())
+	lockFreeStack := new(LockFreeStack)
+	fmt.Println("Lock-free Stack Test")
+	testConcurrentStackAccess(lockFreeStack)
+}
+
+func TestLockFreeStackNoLostValues(t *testing.T) {
+	// concurrent pushes and pops must neither lose nor duplicate values,
+	// which is what an ABA problem in Pop would cause
+	const pushers, poppers, perPusher = 8, 8, 2000
+	stack := new(LockFreeStack)
+	popped := make(chan interface{}, pushers*perPusher)
+	var pushWg, popWg sync.WaitGroup
+	done := make(chan struct{})
+
+	pushWg.Add(pushers)
+	for i := 0; i < pushers; i++ {
+		go func(i int) {
+			defer pushWg.Done()
+			for j := 0; j < perPusher; j++ {
+				stack.Push(i*perPusher + j)
+			}
+		}(i)
+	}
+	popWg.Add(poppers)
+	for i := 0; i < poppers; i++ {
+		go func() {
+			defer popWg.Done()
+			for {
+				if value := stack.Pop(); value != nil {
+					popped <- value
+					continue
+				}
+				select {
+				case <-done:
+					return
+				default:
+					runtime.Gosched()
+				}
+			}
+		}()
+	}
+	pushWg.Wait()
+	close(done)
+	popWg.Wait()
+	for value := stack.Pop(); value != nil; value = stack.Pop() {
+		popped <- value
+	}
+	close(popped)
+
+	seen := make([]bool, pushers*perPusher)
+	for value := range popped {
+		v := value.(int)
+		if seen[v] {
+			t.Fatalf("value %d was popped twice", v)
+		}
+		seen[v] = true
+	}
+	for v, ok := range seen {
+		if !ok {
+			t.Fatalf("value %d was lost", v)
+		}
+	}
+	if size := stack.Size(); size != 0 {
+		t.Errorf("Size() of the emptied stack = %d, want 0", size)
+	}
+}
+
 func TestOpsUnsafeStack(t *testing.T) {
 	fmt.Println("Test operations UnsafeStack")
 	unsafeStack := new(UnsafeStack)
@@ -53,6 +123,7 @@ func TestOpsSafeStack(t *testing.T) {
 func TestOpsCspStack(t *testing.T) {
 	fmt.Println("Test operations CspStack")
 	cspStack := NewCspStack()
+	defer cspStack.Close()
 	testStackOperations(cspStack, t)
 }
 
@@ -62,12 +133,19 @@ func TestOpsSliceStack(t *testing.T) {
 	testStackOperations(sliceStack, t)
 }
 
+func TestOpsLockFreeStack(t *testing.T) {
+	fmt.Println("Test operations LockFreeStack")
+	lockFreeStack := new(LockFreeStack)
+	testStackOperations(lockFreeStack, t)
+}
+
 func TestOpsAllStacks(t *testing.T) {
 	fmt.Println("Test operations all stacks")
 	TestOpsUnsafeStack(t)
 	TestOpsSafeStack(t)
 	TestOpsCspStack(t)
 	TestOpsSliceStack(t)
+	TestOpsLockFreeStack(t)
 }
 
 func BenchmarkSafeStack(b *testing.B) {
@@ -86,11 +164,78 @@ func BenchmarkSliceStack(b *testing.B) {
 
 func BenchmarkCspStack(b *testing.B) {
 	sliceStack := NewCspStack()
+	defer sliceStack.Close()
 	for i := 0; i < b.N; i++ {
 		benchStackOperations(sliceStack)
 	}
 }
 
+func BenchmarkLockFreeStack(b *testing.B) {
+	lockFreeStack := new(LockFreeStack)
+	for i := 0; i < b.N; i++ {
+		benchStackOperations(lockFreeStack)
+	}
+}
+
+// BenchmarkStacksParallel compares the throughput of the thread-safe stacks when
+// 1 to 64 goroutines push and pop on the same stack at once. Each op is one Push
+// followed by one Pop.
+func BenchmarkStacksParallel(b *testing.B) {
+	stacks := []struct {
+		name     string
+		newStack func() Stack
+	}{
+		{"SafeStack", func() Stack { return new(SafeStack) }},
+		{"SliceStack", func() Stack { return NewSliceStack() }},
+		{"CspStack", func() Stack { return NewCspStack() }},
+		{"LockFreeStack", func() Stack { return new(LockFreeStack) }},
+	}
+	for _, s := range stacks {
+		for goroutines := 1; goroutines <= 64; goroutines *= 2 {
+			b.Run(fmt.Sprintf("%s/goroutines=%d", s.name, goroutines), func(b *testing.B) {
+				benchParallelStackOperations(b, closeWhenDone(b, s.newStack()), goroutines)
+			})
+		}
+	}
+}
+
+// BenchmarkIndependentStacks lets every goroutine push and pop on a stack of its own,
+// with 16 goroutines per CPU. Since each stack has its own lock, the goroutines don't
+// contend with each other, unlike when they all share a single stack.
+func BenchmarkIndependentStacks(b *testing.B) {
+	stacks := []struct {
+		name     string
+		newStack func() Stack
+	}{
+		{"SafeStack", func() Stack { return new(SafeStack) }},
+		{"SliceStack", func() Stack { return NewSliceStack() }},
+	}
+	for _, s := range stacks {
+		b.Run(s.name+"/independent", func(b *testing.B) {
+			b.SetParallelism(16)
+			b.RunParallel(func(pb *testing.PB) {
+				stack := s.newStack()
+				for i := 0; pb.Next(); i++ {
+					stack.Push(i)
+					stack.Size()
+					stack.Pop()
+				}
+			})
+		})
+		b.Run(s.name+"/shared", func(b *testing.B) {
+			stack := s.newStack()
+			b.SetParallelism(16)
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					stack.Push(i)
+					stack.Size()
+					stack.Pop()
+				}
+			})
+		})
+	}
+}
+
 const (
 	numGoroutines = 4
 	numOperations = 10
@@ -229,6 +374,24 @@ func testStackOperations(stack Stack, t *testing.T) {
 	}
 }
 
+// benchParallelStackOperations splits b.N push and pop pairs evenly between goroutines
+// that all work on stack at the same time.
+func benchParallelStackOperations(b *testing.B, stack Stack, goroutines int) {
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	b.ResetTimer()
+	for g := 0; g < goroutines; g++ {
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < b.N; i += goroutines {
+				stack.Push(i)
+				stack.Pop()
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
 func benchStackOperations(stack Stack) {
 	const numOps = 10000
 	for i := 0; i < numOps; i++ {
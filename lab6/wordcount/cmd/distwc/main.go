@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"dat320/lab6/wordcount"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var (
+	worker   = flag.Bool("worker", false, "If set, run as a worker that counts shards for the coordinator at -addr, instead of as the coordinator.")
+	addr     = flag.String("addr", "localhost:61120", "Address the coordinator listens on, and workers connect to.")
+	shards   = flag.Int("shards", 4*runtime.NumCPU(), "Number of shards each file is split into.")
+	spawn    = flag.Int("spawn", runtime.NumCPU(), "Number of worker processes the coordinator starts on this machine, and restarts when they die. Other workers may connect as well.")
+	timeout  = flag.Duration("timeout", 10*time.Second, "Time a worker has to count a shard before it is considered dead, and the shard is given to another worker.")
+	dieAfter = flag.Duration("dieafter", 0, "If set, a worker exits after this long, even in the middle of a shard, to test the coordinator. Restarted workers don't.")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <file>...\n", os.Args[0])
+	fmt.Fprintf(flag.CommandLine.Output(), "       %s -worker [flags]\n", os.Args[0])
+	fmt.Fprintln(flag.CommandLine.Output(), "Counts the words in the files by sharing them out to worker processes, and prints the total.")
+	flag.PrintDefaults()
+}
+
+// spawnWorkers starts n worker processes of this program that connect to addr, and
+// restarts those that die until finished is closed. The returned WaitGroup is done
+// when all workers have exited after that.
+func spawnWorkers(n int, addr string, finished <-chan struct{}) (*sync.WaitGroup, error) {
+	self, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		// only the first worker process in each slot may die on purpose, so that the
+		// job finishes
+		cmd := exec.Command(self, "-worker", "-addr", addr, "-dieafter", dieAfter.String())
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			return &wg, err
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := cmd.Wait()
+				select {
+				case <-finished:
+					return
+				default:
+				}
+				log.Printf("Worker %d exited (%v), restarting it", cmd.Process.Pid, err)
+				cmd = exec.Command(self, "-worker", "-addr", addr)
+				cmd.Stderr = os.Stderr
+				if err := cmd.Start(); err != nil {
+					log.Printf("Failed restarting worker: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	return &wg, nil
+}
+
+func runWorker() {
+	if *dieAfter > 0 {
+		time.AfterFunc(*dieAfter, func() {
+			log.Printf("Worker %d dying", os.Getpid())
+			os.Exit(1)
+		})
+	}
+	if err := wordcount.Work(*addr); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if *worker {
+		runWorker()
+		return
+	}
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var inputs [][]byte
+	for _, name := range flag.Args() {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		inputs = append(inputs, b)
+	}
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	finished := make(chan struct{})
+	workers, err := spawnWorkers(*spawn, l.Addr().String(), finished)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	start := time.Now()
+	words, err := wordcount.CountDistributed(context.Background(), l, wordcount.ShardFiles(inputs, *shards), *timeout)
+	close(finished)
+	if err != nil {
+		log.Fatal(err)
+	}
+	workers.Wait()
+	fmt.Printf("%d words in %d files, counted in %v\n", words, len(inputs), time.Since(start))
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"dat320/lab6/wordcount"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// parallelSize is the size from which a file is counted by -j goroutines. Smaller files
+// are counted faster by a single goroutine.
+const parallelSize = 1 << 20
+
+const usage = `Usage: %[1]s [OPTION]... [FILE]...
+Print newline, word, and byte counts for each FILE, and a total line if
+more than one FILE is specified.  A word is a non-zero-length sequence of
+printable characters delimited by white space.
+
+With no FILE, or when FILE is -, read standard input.
+
+The options below may be used to select which counts are printed, always in
+the following order: newline, word, character, byte, maximum line length.
+  -c, --bytes            print the byte counts
+  -m, --chars            print the character counts
+  -l, --lines            print the newline counts
+  -L, --max-line-length  print the maximum display width
+  -w, --words            print the word counts
+  -j, --jobs=N           count large inputs with N goroutines (default %[2]d)
+      --help             display this help and exit
+`
+
+var program = filepath.Base(os.Args[0])
+
+// the options
+var (
+	printLines, printWords, printChars, printBytes, printMaxLineLength bool
+
+	jobs = runtime.NumCPU()
+)
+
+// usageError reports a problem with the command line like getopt does, and exits.
+func usageError(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "%s: %s\n", program, fmt.Sprintf(format, args...))
+	fmt.Fprintf(os.Stderr, "Try '%s --help' for more information.\n", program)
+	os.Exit(1)
+}
+
+// parseArgs parses the options among args and returns the file names. The flag package
+// can't be used, since wc must accept combined short options like -lw, and options
+// after file names, to be a drop-in replacement.
+func parseArgs(args []string) (files []string) {
+	setJobs := func(value string) {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			usageError("invalid number of jobs: '%s'", value)
+		}
+		jobs = n
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return append(files, args[i+1:]...)
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			files = append(files, arg)
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			switch name {
+			case "bytes":
+				printBytes = true
+			case "chars":
+				printChars = true
+			case "lines":
+				printLines = true
+			case "max-line-length":
+				printMaxLineLength = true
+			case "words":
+				printWords = true
+			case "help":
+				fmt.Printf(usage, program, runtime.NumCPU())
+				os.Exit(0)
+			case "jobs":
+				if !hasValue {
+					if i+1 == len(args) {
+						usageError("option '--jobs' requires an argument")
+					}
+					i++
+					value = args[i]
+				}
+				setJobs(value)
+				continue
+			default:
+				usageError("unrecognized option '%s'", arg)
+			}
+			if hasValue {
+				usageError("option '--%s' doesn't allow an argument", name)
+			}
+		default:
+			for j := 1; j < len(arg); j++ {
+				switch arg[j] {
+				case 'c':
+					printBytes = true
+				case 'm':
+					printChars = true
+				case 'l':
+					printLines = true
+				case 'L':
+					printMaxLineLength = true
+				case 'w':
+					printWords = true
+				case 'j':
+					value := arg[j+1:]
+					if value == "" {
+						if i+1 == len(args) {
+							usageError("option requires an argument -- 'j'")
+						}
+						i++
+						value = args[i]
+					}
+					setJobs(value)
+					j = len(arg)
+				default:
+					usageError("invalid option -- '%c'", arg[j])
+				}
+			}
+		}
+	}
+	return files
+}
+
+// input is a file to be counted. An empty name is standard input, when no files are given.
+type input struct {
+	name string
+	info fs.FileInfo // nil if the file hasn't been or couldn't be stat'ed
+}
+
+func (in input) open() (*os.File, error) {
+	if in.name == "" || in.name == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(in.name)
+}
+
+func (in input) stat() fs.FileInfo {
+	if in.name == "" || in.name == "-" {
+		info, _ := os.Stdin.Stat()
+		return info
+	}
+	info, _ := os.Stat(in.name)
+	return info
+}
+
+// numberWidth returns the width that all counts are printed with, computed like GNU wc
+// does before counting: wide enough for the total size of the regular files, and at
+// least 7 if any input is not a regular file, such as a pipe, whose size is unknown.
+func numberWidth(inputs []input) int {
+	width, minimum := 1, 1
+	var total int64
+	for _, in := range inputs {
+		if in.info == nil {
+			continue
+		}
+		if in.info.Mode().IsRegular() {
+			total += in.info.Size()
+		} else {
+			minimum = 7
+		}
+	}
+	for ; total >= 10; total /= 10 {
+		width++
+	}
+	return max(width, minimum)
+}
+
+func printCounts(counts wordcount.Counts, width int, name string) {
+	var line strings.Builder
+	for _, c := range []struct {
+		print bool
+		count int64
+	}{
+		{printLines, counts.Lines},
+		{printWords, counts.Words},
+		{printChars, counts.Chars},
+		{printBytes, counts.Bytes},
+		{printMaxLineLength, counts.MaxLineLength},
+	} {
+		if !c.print {
+			continue
+		}
+		if line.Len() > 0 {
+			line.WriteByte(' ')
+		}
+		fmt.Fprintf(&line, "%*d", width, c.count)
+	}
+	if name != "" {
+		line.WriteString(" " + name)
+	}
+	fmt.Println(line.String())
+}
+
+// reportError prints err for the named file the way GNU tools do, as in
+// "wc: nope: No such file or directory".
+func reportError(name string, err error) {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		err = pathErr.Err
+	}
+	msg := []rune(err.Error())
+	msg[0] = unicode.ToUpper(msg[0])
+	if name == "" {
+		name = "-"
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s: %s\n", program, name, string(msg))
+}
+
+// count counts f, with several goroutines if it is large or of unknown size.
+func count(f *os.File) (wordcount.Counts, error) {
+	workers := jobs
+	if info, err := f.Stat(); err == nil && info.Mode().IsRegular() && info.Size() < parallelSize {
+		workers = 1
+	}
+	return wordcount.CountAll(f, workers)
+}
+
+func main() {
+	files := parseArgs(os.Args[1:])
+	if !printLines && !printWords && !printChars && !printBytes && !printMaxLineLength {
+		printLines, printWords, printBytes = true, true, true
+	}
+	inputs := make([]input, max(len(files), 1))
+	for i, name := range files {
+		inputs[i].name = name
+	}
+
+	// a single count of a single input is printed without padding
+	counters := 0
+	for _, print := range []bool{printLines, printWords, printChars, printBytes, printMaxLineLength} {
+		if print {
+			counters++
+		}
+	}
+	width := 1
+	if len(inputs) > 1 || counters > 1 {
+		for i := range inputs {
+			inputs[i].info = inputs[i].stat()
+		}
+		width = numberWidth(inputs)
+	}
+
+	var total wordcount.Counts
+	status := 0
+	for _, in := range inputs {
+		f, err := in.open()
+		if err != nil {
+			// files that can't be opened aren't counted at all
+			reportError(in.name, err)
+			status = 1
+			continue
+		}
+		counts, err := count(f)
+		if f != os.Stdin {
+			f.Close()
+		}
+		if err != nil {
+			// but files that can't be read, like directories, count as empty
+			reportError(in.name, err)
+			status = 1
+		}
+		printCounts(counts, width, in.name)
+		total.Add(counts)
+	}
+	if len(inputs) > 1 {
+		printCounts(total, width, "total")
+	}
+	os.Exit(status)
+}
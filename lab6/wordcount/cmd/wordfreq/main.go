@@ -0,0 +1,102 @@
+package main
+
+import (
+	"dat320/lab6/wordcount"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+var (
+	top       = flag.Int("k", 20, "Number of words to print, most frequent first.")
+	shards    = flag.Int("shards", runtime.NumCPU(), "Number of shards each file is split into and counted in parallel.")
+	ext       = flag.String("ext", ".txt", "Only files with this extension are counted. If empty, all files are counted.")
+	keepCase  = flag.Bool("keepcase", false, "If set, words are not folded to lower case.")
+	keepPunct = flag.Bool("keeppunct", false, "If set, punctuation is not stripped from the start and end of words.")
+	stop      = flag.Bool("stop", true, "If set, common English stop words are not counted.")
+	stopFile  = flag.String("stopfile", "", "If set, the words in this file are not counted, in addition to the -stop words.")
+)
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <dir>\n", os.Args[0])
+	fmt.Fprintln(flag.CommandLine.Output(), "Prints a table of the most frequent words in the text files in dir and its subdirectories.")
+	flag.PrintDefaults()
+}
+
+// stopWords returns the words that are not counted according to the flags.
+func stopWords() (map[string]bool, error) {
+	words := make(map[string]bool)
+	if *stop {
+		for word := range wordcount.DefaultStopWords {
+			words[word] = true
+		}
+	}
+	if *stopFile != "" {
+		b, err := os.ReadFile(*stopFile)
+		if err != nil {
+			return nil, err
+		}
+		// stop words are matched in lower case and without punctuation, whatever the flags
+		for _, word := range strings.Fields(string(b)) {
+			words[strings.ToLower(strings.TrimFunc(word, unicode.IsPunct))] = true
+		}
+	}
+	return words, nil
+}
+
+// countDir returns the word frequencies of all files in dir, and the number of files.
+func countDir(dir string, opts wordcount.FreqOptions) (map[string]int, int, error) {
+	var freqs map[string]int
+	files := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || (*ext != "" && filepath.Ext(path) != *ext) {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		freqs = wordcount.MergeFrequencies(freqs, wordcount.WordFrequencies(b, *shards, opts))
+		files++
+		return nil
+	})
+	return freqs, files, err
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	stopWords, err := stopWords()
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts := wordcount.FreqOptions{KeepCase: *keepCase, KeepPunct: *keepPunct, StopWords: stopWords}
+	freqs, files, err := countDir(flag.Arg(0), opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	total := 0
+	for _, count := range freqs {
+		total += count
+	}
+	fmt.Printf("%d words, %d distinct, in %d files\n\n", total, len(freqs), files)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "rank\tword\tcount\tshare\t")
+	for i, wf := range wordcount.TopK(freqs, *top) {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%.2f%%\t\n", i+1, wf.Word, wf.Count, 100*float64(wf.Count)/float64(total))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,261 @@
+package wordcount
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Counts are the numbers that the wc command prints for an input.
+type Counts struct {
+	Lines         int64 // newlines
+	Words         int64 // runs of printable non-space characters, and the characters between them
+	Chars         int64 // UTF-8 encoded characters, not counting invalid bytes
+	Bytes         int64
+	MaxLineLength int64 // display width of the widest line
+}
+
+// Add adds the counts of o to c, as if o was counted after c.
+// The longest line of both is the longest line of the sum.
+func (c *Counts) Add(o Counts) {
+	c.Lines += o.Lines
+	c.Words += o.Words
+	c.Chars += o.Chars
+	c.Bytes += o.Bytes
+	c.MaxLineLength = max(c.MaxLineLength, o.MaxLineLength)
+}
+
+// lineCounter counts its input the same way as GNU wc in a UTF-8 locale, which agrees
+// with the C locale on ASCII input. Input can be written to it in pieces that end
+// anywhere, even in the middle of a rune.
+type lineCounter struct {
+	Counts
+	inWord  bool
+	linePos int64
+	partial []byte // the start of a rune that continues in the next Write
+}
+
+// Write counts p. It never fails.
+func (c *lineCounter) Write(p []byte) (int, error) {
+	c.Bytes += int64(len(p))
+	b := p
+	if k := len(c.partial); k > 0 {
+		// decode the runes that start in the partial bytes, followed by the first bytes of p
+		buf := append(c.partial, b[:min(len(b), utf8.UTFMax)]...)
+		i := 0
+		for i < k {
+			if !utf8.FullRune(buf[i:]) {
+				c.partial = append(c.partial[:0], buf[i:]...)
+				return len(p), nil
+			}
+			r, size := utf8.DecodeRune(buf[i:])
+			c.rune(r, size)
+			i += size
+		}
+		b = b[i-k:]
+		c.partial = c.partial[:0]
+	}
+	for i := 0; i < len(b); {
+		if b[i] < utf8.RuneSelf {
+			c.ascii(b[i])
+			i++
+			continue
+		}
+		if !utf8.FullRune(b[i:]) {
+			c.partial = append(c.partial, b[i:]...)
+			break
+		}
+		r, size := utf8.DecodeRune(b[i:])
+		c.rune(r, size)
+		i += size
+	}
+	return len(p), nil
+}
+
+func (c *lineCounter) ascii(b byte) {
+	c.Chars++
+	switch b {
+	case '\n':
+		c.Lines++
+		fallthrough
+	case '\r', '\f':
+		c.MaxLineLength = max(c.MaxLineLength, c.linePos)
+		c.linePos = 0
+		c.endWord()
+	case '\t':
+		c.linePos += 8 - c.linePos%8
+		c.endWord()
+	case ' ':
+		c.linePos++
+		c.endWord()
+	case '\v':
+		c.endWord()
+	default:
+		// control characters neither start nor end a word
+		if b >= ' ' && b != 0x7f {
+			c.linePos++
+			c.inWord = true
+		}
+	}
+}
+
+// rune counts the non-ASCII rune r, encoded in size bytes. An invalid byte is not a
+// character, and like other unprintable characters it neither starts nor ends a word.
+func (c *lineCounter) rune(r rune, size int) {
+	if r == utf8.RuneError && size == 1 {
+		return
+	}
+	c.Chars++
+	if !unicode.IsGraphic(r) && !unicode.Is(unicode.Cf, r) {
+		return
+	}
+	c.linePos += runeWidth(r)
+	if unicode.IsSpace(r) {
+		c.endWord()
+	} else {
+		c.inWord = true
+	}
+}
+
+func (c *lineCounter) endWord() {
+	if c.inWord {
+		c.Words++
+		c.inWord = false
+	}
+}
+
+// counts returns the counts of everything written so far, as if the input ended here.
+func (c *lineCounter) counts() Counts {
+	counts := c.Counts
+	if c.inWord {
+		counts.Words++
+	}
+	counts.MaxLineLength = max(counts.MaxLineLength, c.linePos)
+	return counts
+}
+
+// runeWidth returns the number of columns that r takes up in a terminal: 0 for
+// combining marks and format characters, 2 for East Asian wide characters and emoji,
+// and 1 for everything else.
+func runeWidth(r rune) int64 {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case 0x1100 <= r && r <= 0x115f, // Hangul Jamo
+		0x2e80 <= r && r <= 0xa4cf && r != 0x303f, // CJK, Hiragana, Katakana, Yi
+		0xac00 <= r && r <= 0xd7a3,                // Hangul syllables
+		0xf900 <= r && r <= 0xfaff,                // CJK compatibility ideographs
+		0xfe30 <= r && r <= 0xfe4f,                // CJK compatibility forms
+		0xff00 <= r && r <= 0xff60,                // fullwidth forms
+		0xffe0 <= r && r <= 0xffe6,                // fullwidth signs
+		0x1f300 <= r && r <= 0x1f64f,              // emoji
+		0x1f900 <= r && r <= 0x1f9ff,
+		0x20000 <= r && r <= 0x3fffd: // CJK extensions
+		return 2
+	}
+	return 1
+}
+
+// CountAll counts r like GNU wc. With more than one worker, r is read into chunks of
+// whole lines that workers goroutines count at the same time, and at most workers+2
+// chunks are in memory at once. A line longer than a chunk is counted in order by the
+// reading goroutine.
+func CountAll(r io.Reader, workers int) (Counts, error) {
+	if workers <= 1 {
+		var c lineCounter
+		_, err := io.Copy(&c, r)
+		return c.counts(), err
+	}
+
+	free := make(chan []byte, workers+2)
+	for i := 0; i < cap(free); i++ {
+		free <- make([]byte, chunkSize)
+	}
+	chunks := make(chan chunk)
+	var total Counts
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				// a chunk starts at the start of a line, so it doesn't depend on earlier chunks
+				var lc lineCounter
+				lc.Write(c.data)
+				free <- c.buf
+
+				mutex.Lock()
+				total.Add(lc.counts())
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	long, err := splitLines(r, free, chunks)
+	close(chunks)
+	wg.Wait()
+	total.Add(long.counts())
+	return total, err
+}
+
+// splitLines reads r into buffers taken from free, and sends the complete lines in them
+// to chunks. The start of a line at the end of a buffer is moved to the next buffer. If
+// a buffer has no newline, the line is too long for a chunk; the part of it that has
+// been read is counted by the returned lineCounter instead, and so is the rest of the
+// line when it is read.
+func splitLines(r io.Reader, free chan []byte, chunks chan<- chunk) (*lineCounter, error) {
+	long := new(lineCounter)
+	inLongLine := false
+	buf, n := <-free, 0
+	for {
+		m, err := io.ReadFull(r, buf[n:])
+		n += m
+		eof := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !eof {
+			free <- buf
+			return long, err
+		}
+
+		data := buf[:n]
+		if inLongLine {
+			end := bytes.IndexByte(data, '\n') + 1
+			if end == 0 {
+				end = len(data)
+			} else {
+				inLongLine = false
+			}
+			long.Write(data[:end])
+			data = data[end:]
+		}
+		end := len(data)
+		if !eof {
+			end = bytes.LastIndexByte(data, '\n') + 1
+			if end == 0 && len(data) == len(buf) {
+				long.Write(data)
+				inLongLine = true
+				n = 0
+				continue
+			}
+		}
+
+		if eof {
+			if len(data) > 0 {
+				chunks <- chunk{data: data, buf: buf}
+			} else {
+				free <- buf
+			}
+			return long, nil
+		}
+		next := <-free
+		rest := copy(next, data[end:])
+		if end > 0 {
+			chunks <- chunk{data: data[:end], buf: buf}
+		} else {
+			free <- buf
+		}
+		buf, n = next, rest
+	}
+}
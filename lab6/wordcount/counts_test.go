@@ -0,0 +1,88 @@
+package wordcount
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// the expected counts are those of GNU wc 9.1 in the C.UTF-8 locale
+var countsTests = []struct {
+	input string
+	want  Counts
+}{
+	{"", Counts{}},
+	{"no newline", Counts{0, 2, 10, 10, 10}},
+	{"hello world\n\tfoo\x01 bar\x01\x02 \x03\n", Counts{2, 4, 26, 26, 16}},
+	{"a\rbcd\fe\n", Counts{1, 3, 8, 8, 3}},
+	{"tab\tstops\t\tx\v\n", Counts{1, 3, 14, 14, 25}},
+	{"æøå 漢字 a b\u3000c\n\xff\xfe ok", Counts{1, 6, 16, 28, 15}},
+	{"e\u0301 \U0001F44D \x7f\n", Counts{1, 2, 7, 11, 5}},
+}
+
+func TestCountAll(t *testing.T) {
+	for _, test := range countsTests {
+		for _, workers := range []int{1, 4} {
+			got, err := CountAll(strings.NewReader(test.input), workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != test.want {
+				t.Errorf("CountAll(%q, %d)=%+v, expected %+v", test.input, workers, got, test.want)
+			}
+		}
+	}
+}
+
+func TestLineCounterPieces(t *testing.T) {
+	// writing the input in pieces that cut runes and lines must not change the counts
+	for _, test := range countsTests {
+		for size := 1; size < 5; size++ {
+			var c lineCounter
+			for input := []byte(test.input); len(input) > 0; input = input[min(size, len(input)):] {
+				c.Write(input[:min(size, len(input))])
+			}
+			if got := c.counts(); got != test.want {
+				t.Errorf("counting %q in pieces of %d bytes gave %+v, expected %+v", test.input, size, got, test.want)
+			}
+		}
+	}
+}
+
+func TestCountAllParallel(t *testing.T) {
+	moby := loadMoby()
+	inputs := map[string][]byte{
+		"mobydick.txt": moby,
+		// lines longer than a chunk are counted by the reading goroutine
+		"long lines":  []byte(strings.Repeat("word ", chunkSize/2) + "\n" + strings.Repeat("x\ty ", chunkSize) + "\n\nabc"),
+		"no newlines": bytes.Repeat([]byte("æøå\t漢字 "), chunkSize/4),
+		"mixed":       append(append(append([]byte{}, moby[:3*chunkSize/2]...), strings.Repeat("long ", chunkSize)...), moby...),
+	}
+	for name, input := range inputs {
+		want, err := CountAll(bytes.NewReader(input), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, workers := range []int{2, 3, 8} {
+			got, err := CountAll(iotest.HalfReader(bytes.NewReader(input)), workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("CountAll(%s, %d)=%+v, expected %+v", name, workers, got, want)
+			}
+		}
+	}
+	if got, _ := CountAll(bytes.NewReader(moby), 4); got.Lines != int64(bytes.Count(moby, []byte("\n"))) || got.Bytes != int64(len(moby)) {
+		t.Errorf("CountAll(mobydick.txt) counted %d lines and %d bytes", got.Lines, got.Bytes)
+	}
+}
+
+func BenchmarkCountAll(b *testing.B) {
+	mobyDick := loadMoby()
+	b.SetBytes(int64(len(mobyDick)))
+	for i := 0; i < b.N; i++ {
+		_, _ = CountAll(bytes.NewReader(mobyDick), 4)
+	}
+}
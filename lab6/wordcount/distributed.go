@@ -0,0 +1,227 @@
+package wordcount
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+// The coordinator and its workers talk over TCP, with one JSON encoded message per line.
+// A worker connects to the coordinator, which sends it a shardTask; the worker counts
+// the shard and answers with a shardResult, and then waits for the next task. When all
+// shards are counted, the coordinator tells the waiting workers to stop.
+
+// shardTask is sent by the coordinator to give a worker a shard to count, or to tell it to stop.
+type shardTask struct {
+	ID   int
+	Data []byte
+	Stop bool
+}
+
+// shardResult is sent by a worker when it has counted a shard.
+type shardResult struct {
+	ID    int
+	Words int
+}
+
+// ErrNoWorkers is returned by CountDistributed when no worker has been connected for
+// too long.
+var ErrNoWorkers = errors.New("no workers")
+
+// noWorkersTimeouts is how many timeouts CountDistributed waits for a worker to connect,
+// when none is connected, before it gives up.
+const noWorkersTimeouts = 3
+
+// ShardFiles splits each of the inputs into numShards shards with shardSlice, and returns
+// all the shards. Since words never continue from one input into the next, the sum of
+// the words in the shards is the sum of the words in each input.
+func ShardFiles(inputs [][]byte, numShards int) (shards [][]byte) {
+	for _, input := range inputs {
+		shards = append(shards, shardSlice(input, max(numShards, 1))...)
+	}
+	return shards
+}
+
+// CountDistributed counts the words in shards with wordCount, by handing the shards out
+// to the workers that connect to l, and returns the total. A worker that disconnects,
+// or doesn't answer within timeout, is considered dead: its connection is closed, and
+// its shard is given to another worker. Every shard is counted exactly once, however
+// many workers die.
+//
+// CountDistributed closes l when it returns: when all shards are counted, with ctx's
+// error when ctx is done first, or with ErrNoWorkers when no worker has been connected
+// for noWorkersTimeouts times timeout. Before returning, it tells the workers that are
+// waiting for a shard to stop, and disconnects those that are still counting one.
+func CountDistributed(ctx context.Context, l net.Listener, shards [][]byte, timeout time.Duration) (words int, err error) {
+	job, endJob := context.WithCancel(ctx)
+	c := &coordinator{
+		shards:    shards,
+		pending:   make(chan int, len(shards)),
+		results:   make(chan shardResult),
+		job:       job,
+		timeout:   timeout,
+		idleSince: time.Now(),
+	}
+	for id := range shards {
+		c.pending <- id
+	}
+	defer func() {
+		l.Close()
+		endJob()
+		c.wg.Wait()
+	}()
+	c.wg.Add(1)
+	go c.accept(l)
+
+	ticker := time.NewTicker(timeout)
+	defer ticker.Stop()
+	counted := make([]bool, len(shards))
+	for remaining := len(shards); remaining > 0; {
+		select {
+		case res := <-c.results:
+			if !counted[res.ID] {
+				counted[res.ID] = true
+				words += res.Words
+				remaining--
+			}
+		case <-ticker.C:
+			if idle := c.idle(); idle > noWorkersTimeouts*timeout {
+				return 0, fmt.Errorf("%w connected for %v, with %d of %d shards left", ErrNoWorkers, idle.Round(time.Millisecond), remaining, len(shards))
+			}
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	return words, nil
+}
+
+type coordinator struct {
+	shards  [][]byte
+	pending chan int // shards that are waiting for a worker; room for all of them
+	results chan shardResult
+	job     context.Context // done when the job is over
+	timeout time.Duration
+	wg      sync.WaitGroup // the accepting goroutine and the workers' goroutines
+
+	mutex     sync.Mutex // protects the fields below
+	workers   int        // connected workers
+	idleSince time.Time  // when the last worker disconnected
+}
+
+// idle returns how long no worker has been connected, or 0 if one is.
+func (c *coordinator) idle() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.workers > 0 {
+		return 0
+	}
+	return time.Since(c.idleSince)
+}
+
+// connected adds delta to the number of connected workers.
+func (c *coordinator) connected(delta int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.workers += delta
+	if c.workers == 0 {
+		c.idleSince = time.Now()
+	}
+}
+
+func (c *coordinator) accept(l net.Listener) {
+	defer c.wg.Done()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("Failed accepting worker: %v", err)
+			}
+			return
+		}
+		log.Printf("Worker %v connected", conn.RemoteAddr())
+		c.wg.Add(1)
+		go c.serve(conn)
+	}
+}
+
+// serve hands out shards to the worker on conn until the job is over or the worker dies.
+func (c *coordinator) serve(conn net.Conn) {
+	defer c.wg.Done()
+	defer conn.Close()
+	c.connected(1)
+	defer c.connected(-1)
+	enc, dec := json.NewEncoder(conn), json.NewDecoder(conn)
+	for {
+		var id int
+		select {
+		case id = <-c.pending:
+		case <-c.job.Done():
+			conn.SetDeadline(time.Now().Add(c.timeout))
+			enc.Encode(shardTask{Stop: true})
+			return
+		}
+
+		// if the job ends while the worker is counting, it is disconnected at once
+		conn.SetDeadline(time.Now().Add(c.timeout))
+		stop := context.AfterFunc(c.job, func() { conn.SetDeadline(time.Now()) })
+		var res shardResult
+		err := enc.Encode(shardTask{ID: id, Data: c.shards[id]})
+		if err == nil {
+			err = dec.Decode(&res)
+		}
+		if err == nil && res.ID != id {
+			err = fmt.Errorf("answered shard %d instead of %d", res.ID, id)
+		}
+		if !stop() || c.job.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("Worker %v died with shard %d, giving it to another worker: %v", conn.RemoteAddr(), id, err)
+			c.pending <- id
+			return
+		}
+
+		select {
+		case c.results <- res:
+		case <-c.job.Done():
+			return
+		}
+	}
+}
+
+// Work connects to the coordinator at addr, and counts the shards it is given until the
+// coordinator tells it to stop.
+func Work(addr string) error {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed connecting to coordinator: %w", err)
+	}
+	defer conn.Close()
+	return work(conn, -1)
+}
+
+// work counts shards from the coordinator on conn. If failAfter is not negative, the
+// worker dies without answering after counting that many shards, for testing.
+func work(conn net.Conn, failAfter int) error {
+	enc, dec := json.NewEncoder(conn), json.NewDecoder(conn)
+	for counted := 0; ; counted++ {
+		var task shardTask
+		if err := dec.Decode(&task); err != nil {
+			return fmt.Errorf("failed receiving shard: %w", err)
+		}
+		if task.Stop {
+			return nil
+		}
+		if counted == failAfter {
+			return fmt.Errorf("failing on purpose with shard %d", task.ID)
+		}
+		if err := enc.Encode(shardResult{task.ID, wordCount(task.Data)}); err != nil {
+			return fmt.Errorf("failed sending count of shard %d: %w", task.ID, err)
+		}
+	}
+}
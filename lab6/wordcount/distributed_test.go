@@ -0,0 +1,149 @@
+package wordcount
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+// countWithWorkers counts shards with CountDistributed on a local port, with the given
+// workers connected to it.
+func countWithWorkers(t *testing.T, shards [][]byte, timeout time.Duration, workers ...func(addr string)) (int, error) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, worker := range workers {
+		go worker(l.Addr().String())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	return CountDistributed(ctx, l, shards, timeout)
+}
+
+func healthyWorker(t *testing.T) func(string) {
+	return func(addr string) {
+		if err := Work(addr); err != nil {
+			t.Errorf("Work(%s): %v", addr, err)
+		}
+	}
+}
+
+// failingWorker dies without answering after counting failAfter shards.
+func failingWorker(failAfter int) func(string) {
+	return func(addr string) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		work(conn, failAfter)
+	}
+}
+
+// hangingWorker takes a shard and never answers, but keeps the connection open.
+func hangingWorker(stop <-chan struct{}) func(string) {
+	return func(addr string) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var task shardTask
+		json.NewDecoder(conn).Decode(&task)
+		<-stop
+	}
+}
+
+func TestCountDistributed(t *testing.T) {
+	moby := loadMoby()
+	want := wordCount(moby)
+	stop := make(chan struct{})
+	defer close(stop)
+	tests := []struct {
+		name    string
+		workers []func(string)
+	}{
+		{"one worker", []func(string){healthyWorker(t)}},
+		{"three workers", []func(string){healthyWorker(t), healthyWorker(t), healthyWorker(t)}},
+		{"workers dying", []func(string){failingWorker(0), failingWorker(3), healthyWorker(t), failingWorker(1)}},
+		{"worker hanging", []func(string){hangingWorker(stop), healthyWorker(t)}},
+	}
+	for _, test := range tests {
+		got, err := countWithWorkers(t, ShardFiles([][]byte{moby}, 16), 500*time.Millisecond, test.workers...)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got != want {
+			t.Errorf("%s: CountDistributed(mobydick.txt)=%d, expected %d", test.name, got, want)
+		}
+	}
+}
+
+func TestCountDistributedFiles(t *testing.T) {
+	moby := loadMoby()
+	inputs := [][]byte{moby[:1000], []byte("no space at the end"), moby, {}, []byte(utf8Texts[0].text)}
+	want := 0
+	for _, input := range inputs {
+		want += wordCount(input)
+	}
+	got, err := countWithWorkers(t, ShardFiles(inputs, 5), time.Second, healthyWorker(t), healthyWorker(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("CountDistributed() of %d files=%d, expected %d", len(inputs), got, want)
+	}
+}
+
+func TestCountDistributedCanceled(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no worker ever connects
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := CountDistributed(ctx, l, ShardFiles([][]byte{loadMoby()}, 4), time.Second); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CountDistributed() without workers: want '%v', got '%v'", context.DeadlineExceeded, err)
+	}
+	if _, err := net.Dial("tcp", l.Addr().String()); err == nil {
+		t.Errorf("CountDistributed() didn't close the listener")
+	}
+}
+
+func TestCountDistributedNoWorkers(t *testing.T) {
+	// the only worker dies, and no other connects
+	start := time.Now()
+	_, err := countWithWorkers(t, ShardFiles([][]byte{loadMoby()}, 8), 100*time.Millisecond, failingWorker(1))
+	if !errors.Is(err, ErrNoWorkers) {
+		t.Errorf("CountDistributed() after the workers died: want '%v', got '%v'", ErrNoWorkers, err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("CountDistributed() took %v to notice that there were no workers", elapsed)
+	}
+}
+
+func TestCountDistributedCanceledWhileCounting(t *testing.T) {
+	stop := make(chan struct{})
+	defer close(stop)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go hangingWorker(stop)(l.Addr().String())
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := CountDistributed(ctx, l, ShardFiles([][]byte{loadMoby()}, 4), time.Minute); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CountDistributed() with a hanging worker: want '%v', got '%v'", context.DeadlineExceeded, err)
+	}
+	// the worker must be disconnected, and not waited for until the timeout
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("CountDistributed() took %v to return after it was canceled", elapsed)
+	}
+}
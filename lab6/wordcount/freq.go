@@ -0,0 +1,145 @@
+package wordcount
+
+import (
+	"bytes"
+	"container/heap"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FreqOptions controls how WordFrequencies turns the text between spaces into words.
+// The zero value folds case and strips punctuation, but keeps all words.
+type FreqOptions struct {
+	KeepCase  bool            // don't fold words to lower case
+	KeepPunct bool            // don't strip punctuation from the start and end of words
+	StopWords map[string]bool // words that are not counted, in lower case and without punctuation
+}
+
+// DefaultStopWords are common English words that say little about a text.
+var DefaultStopWords = stopWords(`a about after all also an and any are as at be been but by
+can could did do does for from had has have he her him his how i if in into is it its
+me my no not of on one or our out she so than that the their them then there these they
+this to up upon was we were what when which who will with would you your`)
+
+func stopWords(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range strings.Fields(words) {
+		set[word] = true
+	}
+	return set
+}
+
+// WordFreq is the number of times a word occurs.
+type WordFreq struct {
+	Word  string
+	Count int
+}
+
+// WordFrequencies counts how many times each word occurs in input, as a map-reduce:
+// input is split into numShards shards with shardSlice, a goroutine per shard maps its
+// words to their counts, and the shards' counts are then merged.
+func WordFrequencies(input []byte, numShards int, opts FreqOptions) map[string]int {
+	shards := shardSlice(input, max(numShards, 1))
+	results := make(chan map[string]int)
+	for _, shard := range shards {
+		go func(shard []byte) {
+			results <- shardFrequencies(shard, opts)
+		}(shard)
+	}
+
+	var freqs map[string]int
+	for range shards {
+		freqs = MergeFrequencies(freqs, <-results)
+	}
+	return freqs
+}
+
+// shardFrequencies is the map step of WordFrequencies. Stop words are matched against
+// the word folded to lower case and stripped of punctuation, whatever opts says, so
+// that "The" and "the," are stop words even if the counted words keep case and
+// punctuation.
+func shardFrequencies(shard []byte, opts FreqOptions) map[string]int {
+	freqs := make(map[string]int)
+	for _, field := range bytes.Fields(shard) {
+		trimmed := bytes.TrimFunc(field, unicode.IsPunct)
+		if !opts.KeepPunct {
+			field = trimmed
+		}
+		if len(field) == 0 {
+			continue
+		}
+		word := string(field)
+		if !opts.KeepCase {
+			word = strings.ToLower(word)
+		}
+		if len(opts.StopWords) > 0 {
+			stopWord := word
+			if opts.KeepCase || opts.KeepPunct {
+				stopWord = strings.ToLower(string(trimmed))
+			}
+			if opts.StopWords[stopWord] {
+				continue
+			}
+		}
+		freqs[word]++
+	}
+	return freqs
+}
+
+// MergeFrequencies adds the counts of b to a and returns the result. To save work,
+// the smaller of the maps is added to the larger one, which is modified and returned.
+func MergeFrequencies(a, b map[string]int) map[string]int {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	if a == nil {
+		return make(map[string]int)
+	}
+	for word, count := range b {
+		a[word] += count
+	}
+	return a
+}
+
+// TopK returns the k most frequent words in freqs, most frequent first. Words that
+// occur equally often are sorted alphabetically.
+func TopK(freqs map[string]int, k int) []WordFreq {
+	if k <= 0 {
+		return nil
+	}
+	// keep the k most frequent words seen so far in a heap with the least frequent on top
+	h := make(freqHeap, 0, min(k, len(freqs)))
+	for word, count := range freqs {
+		wf := WordFreq{word, count}
+		if len(h) < k {
+			heap.Push(&h, wf)
+		} else if h.less(h[0], wf) {
+			h[0] = wf
+			heap.Fix(&h, 0)
+		}
+	}
+	sort.Slice(h, func(i, j int) bool { return h.less(h[j], h[i]) })
+	return h
+}
+
+type freqHeap []WordFreq
+
+// less reports whether a is less frequent than b.
+func (freqHeap) less(a, b WordFreq) bool {
+	if a.Count != b.Count {
+		return a.Count < b.Count
+	}
+	return a.Word > b.Word
+}
+
+func (h freqHeap) Len() int            { return len(h) }
+func (h freqHeap) Less(i, j int) bool  { return h.less(h[i], h[j]) }
+func (h freqHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *freqHeap) Push(x interface{}) { *h = append(*h, x.(WordFreq)) }
+func (h *freqHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,79 @@
+package wordcount
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWordFrequencies(t *testing.T) {
+	input := []byte("The cat saw the dog. \"The dog!\" said the Cat... and-so on; --- the END\n")
+	tests := []struct {
+		opts FreqOptions
+		want map[string]int
+	}{
+		{FreqOptions{}, map[string]int{
+			"the": 5, "cat": 2, "dog": 2, "saw": 1, "said": 1, "and-so": 1, "on": 1, "end": 1,
+		}},
+		{FreqOptions{StopWords: DefaultStopWords}, map[string]int{
+			"cat": 2, "dog": 2, "saw": 1, "said": 1, "and-so": 1, "end": 1,
+		}},
+		{FreqOptions{KeepCase: true, KeepPunct: true}, map[string]int{
+			"The": 1, "cat": 1, "saw": 1, "the": 3, "dog.": 1, "\"The": 1, "dog!\"": 1, "said": 1,
+			"Cat...": 1, "and-so": 1, "on;": 1, "---": 1, "END": 1,
+		}},
+		// stop words are found whatever the case and punctuation of the counted words
+		{FreqOptions{KeepCase: true, StopWords: DefaultStopWords}, map[string]int{
+			"cat": 1, "saw": 1, "dog": 2, "said": 1, "Cat": 1, "and-so": 1, "END": 1,
+		}},
+		{FreqOptions{KeepPunct: true, StopWords: DefaultStopWords}, map[string]int{
+			"cat": 1, "saw": 1, "dog.": 1, "dog!\"": 1, "said": 1, "cat...": 1, "and-so": 1, "---": 1, "end": 1,
+		}},
+	}
+	for _, test := range tests {
+		for shards := 1; shards < 6; shards++ {
+			if got := WordFrequencies(input, shards, test.opts); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("WordFrequencies(..., %d, %+v)=%v, expected %v", shards, test.opts, got, test.want)
+			}
+		}
+	}
+}
+
+func TestWordFrequenciesManyShards(t *testing.T) {
+	b := loadMoby()
+	want := WordFrequencies(b, 1, FreqOptions{})
+	for shards := 2; shards < 10; shards++ {
+		if got := WordFrequencies(b, shards, FreqOptions{}); !reflect.DeepEqual(got, want) {
+			t.Errorf("WordFrequencies(..., %d) differs from the frequencies counted with one shard", shards)
+		}
+	}
+}
+
+func TestTopK(t *testing.T) {
+	freqs := map[string]int{"a": 3, "b": 5, "c": 1, "d": 3, "e": 5, "f": 2}
+	tests := []struct {
+		k    int
+		want []WordFreq
+	}{
+		{0, nil},
+		{1, []WordFreq{{"b", 5}}},
+		{4, []WordFreq{{"b", 5}, {"e", 5}, {"a", 3}, {"d", 3}}},
+		{10, []WordFreq{{"b", 5}, {"e", 5}, {"a", 3}, {"d", 3}, {"f", 2}, {"c", 1}}},
+	}
+	for _, test := range tests {
+		if got := TopK(freqs, test.k); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("TopK(..., %d)=%v, expected %v", test.k, got, test.want)
+		}
+	}
+
+	top := TopK(WordFrequencies(loadMoby(), 4, FreqOptions{StopWords: DefaultStopWords}), 3)
+	if len(top) != 3 || top[0].Word != "whale" {
+		t.Errorf("TopK() of mobydick.txt without stop words=%v, expected \"whale\" first", top)
+	}
+}
+
+func BenchmarkWordFrequencies(b *testing.B) {
+	mobyDick := loadMoby()
+	for i := 0; i < b.N; i++ {
+		_ = WordFrequencies(mobyDick, 4, FreqOptions{StopWords: DefaultStopWords})
+	}
+}
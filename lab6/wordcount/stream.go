@@ -0,0 +1,155 @@
+package wordcount
+
+import (
+	"io"
+	"os"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// chunkSize is the size of the chunks that CountReader and CountFiles read their input in.
+const chunkSize = 64 << 10
+
+// CountReader counts the words in r the same way as wordCount, with workers goroutines
+// counting chunks of r while it is being read. At most workers+2 chunks are in memory
+// at once, no matter how large the input is.
+func CountReader(r io.Reader, workers int) (words int, err error) {
+	counts, err := countStreams(1, workers, chunkSize, func(int) (io.ReadCloser, error) {
+		return io.NopCloser(r), nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return counts[0], nil
+}
+
+// CountFiles counts the words in each of the named files, like CountReader. The files
+// are read one after another, but the workers count chunks of several files at once.
+func CountFiles(workers int, names ...string) (counts []int, err error) {
+	return countStreams(len(names), workers, chunkSize, func(i int) (io.ReadCloser, error) {
+		return os.Open(names[i])
+	})
+}
+
+// chunk is a piece of an input that can be counted on its own.
+type chunk struct {
+	input int
+	data  []byte
+	// afterLetter is true if the input continues from a letter in the previous chunk,
+	// so that a space at the start of data ends a word.
+	afterLetter bool
+	buf         []byte // the buffer that data is in, to be reused when data is counted
+}
+
+// countStreams counts the words in n inputs, which are opened one at a time by open.
+// The inputs are read into chunks of size bytes by the calling goroutine and passed
+// to workers goroutines, which count them. Since chunks are read into a fixed set of
+// buffers, reading waits for the workers when they fall behind.
+func countStreams(n, workers, size int, open func(i int) (io.ReadCloser, error)) ([]int, error) {
+	workers = max(workers, 1)
+	free := make(chan []byte, workers+2)
+	for i := 0; i < cap(free); i++ {
+		free <- make([]byte, size)
+	}
+	chunks := make(chan chunk)
+	counts := make([]int, n)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for c := range chunks {
+				words := countWords(c.data, c.afterLetter)
+				free <- c.buf
+
+				mutex.Lock()
+				counts[c.input] += words
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	var err error
+	for i := 0; i < n && err == nil; i++ {
+		var rc io.ReadCloser
+		if rc, err = open(i); err != nil {
+			break
+		}
+		err = splitChunks(rc, i, free, chunks)
+		if cerr := rc.Close(); err == nil {
+			err = cerr
+		}
+	}
+	close(chunks)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// splitChunks reads r into buffers taken from free, and sends them to chunks. A chunk
+// ends after the last space in its buffer, and the partial word after it is moved to the
+// next buffer, so words are never split. Only a buffer without any space is split in
+// the middle of a word, but still between runes, unless the buffer is shorter than
+// utf8.UTFMax.
+func splitChunks(r io.Reader, input int, free chan []byte, chunks chan<- chunk) error {
+	buf, n := <-free, 0
+	afterLetter := false
+	for {
+		m, err := io.ReadFull(r, buf[n:])
+		n += m
+		switch err {
+		case nil:
+		case io.EOF, io.ErrUnexpectedEOF:
+			if n == 0 {
+				free <- buf
+				return nil
+			}
+			chunks <- chunk{input, buf[:n], afterLetter, buf}
+			return nil
+		default:
+			free <- buf
+			return err
+		}
+
+		end := lastSpace(buf) + 1
+		if end == 0 {
+			end = runeBoundary(buf)
+		}
+		next := <-free
+		rest := copy(next, buf[end:])
+		last, _ := utf8.DecodeLastRune(buf[:end])
+		nextAfterLetter := unicode.IsLetter(last)
+		chunks <- chunk{input, buf[:end], afterLetter, buf}
+		buf, n, afterLetter = next, rest, nextAfterLetter
+	}
+}
+
+// lastSpace returns the index of the last ASCII space in b, or -1 if there is none.
+// Other spaces are not considered, since a byte above 0x7f can be in the middle of a
+// UTF-8 encoded rune, and chunks should end on rune boundaries.
+func lastSpace(b []byte) int {
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < utf8.RuneSelf && unicode.IsSpace(rune(b[i])) {
+			return i
+		}
+	}
+	return -1
+}
+
+// runeBoundary returns len(b), or the start of the incomplete rune at the end of b, if
+// there is one and it doesn't start at 0.
+func runeBoundary(b []byte) int {
+	for i := len(b) - 1; i > 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
@@ -0,0 +1,179 @@
+package wordcount
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+func TestCountReader(t *testing.T) {
+	b := loadMoby()
+	wc := wordCount(b)
+	for _, workers := range []int{0, 1, 4, 16} {
+		cnt, err := CountReader(bytes.NewReader(b), workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cnt != wc {
+			t.Errorf("CountReader(..., %d)=%d, expected %d", workers, cnt, wc)
+		}
+	}
+	// a reader that returns less than asked for must not change the chunks
+	cnt, err := CountReader(iotest.HalfReader(bytes.NewReader(b)), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cnt != wc {
+		t.Errorf("CountReader() of a slow reader=%d, expected %d", cnt, wc)
+	}
+}
+
+func TestCountStreamsChunkSizes(t *testing.T) {
+	// words are split between chunks when there is no space in a whole chunk
+	inputs := [][]byte{
+		loadMoby()[:20000],
+		[]byte("a  very\tlong\nwooooooooooooooooooooooooooooooooooooooooooooooord. and more "),
+		[]byte(" \n\n  "),
+		{},
+	}
+	for _, input := range inputs {
+		wc := wordCount(input)
+		for size := 1; size <= 40; size++ {
+			counts, err := countStreams(1, 3, size, func(int) (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(input)), nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counts[0] != wc {
+				t.Errorf("countStreams() with %d byte chunks of %.20q...=%d, expected %d", size, input, counts[0], wc)
+			}
+		}
+	}
+}
+
+func TestCountReaderUTF8(t *testing.T) {
+	for _, test := range utf8Texts {
+		cnt, err := CountReader(strings.NewReader(test.text), 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cnt != test.words {
+			t.Errorf("CountReader(%q)=%d, expected %d", test.text, cnt, test.words)
+		}
+		// chunks that are too short for a word are split between runes
+		for size := utf8.UTFMax; size <= 20; size++ {
+			counts, err := countStreams(1, 3, size, func(int) (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(test.text)), nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counts[0] != test.words {
+				t.Errorf("countStreams() with %d byte chunks of %q=%d, expected %d", size, test.text, counts[0], test.words)
+			}
+		}
+	}
+}
+
+func TestCountFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.txt": "one two three\n",
+		"b.txt": "four five",
+		"c.txt": "",
+	}
+	var names []string
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, filepath.Join(dir, name))
+	}
+	names = append(names, "mobydick.txt")
+
+	counts, err := CountFiles(runtime.NumCPU(), names...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range names {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// words don't continue from one file into the next
+		if wc := wordCount(b); counts[i] != wc {
+			t.Errorf("CountFiles() counted %d words in %s, expected %d", counts[i], name, wc)
+		}
+	}
+
+	if _, err := CountFiles(2, "mobydick.txt", filepath.Join(dir, "missing.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("CountFiles() of a missing file: expected '%v', got '%v'", os.ErrNotExist, err)
+	}
+}
+
+func TestCountReaderError(t *testing.T) {
+	errRead := errors.New("read failed")
+	r := io.MultiReader(bytes.NewReader(loadMoby()), iotest.ErrReader(errRead))
+	if _, err := CountReader(r, 4); !errors.Is(err, errRead) {
+		t.Errorf("CountReader() of a failing reader: expected '%v', got '%v'", errRead, err)
+	}
+}
+
+// repeatReader returns the same content over and over, up to n bytes in total.
+type repeatReader struct {
+	content []byte
+	n, off  int64
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	if r.n <= 0 {
+		return 0, io.EOF
+	}
+	p = p[:min(int64(len(p)), r.n)]
+	n := 0
+	for n < len(p) {
+		m := copy(p[n:], r.content[r.off:])
+		n += m
+		r.off = (r.off + int64(m)) % int64(len(r.content))
+	}
+	r.n -= int64(n)
+	return n, nil
+}
+
+func TestCountReaderConstantMemory(t *testing.T) {
+	moby := loadMoby()
+	const repeats = 200 // about 230 MiB
+	size := repeats * int64(len(moby))
+	r := &repeatReader{content: moby, n: size}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	cnt, err := CountReader(r, 4)
+	runtime.ReadMemStats(&after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// mobydick.txt ends with a newline, so no word continues into the next copy
+	if wc := repeats * wordCount(moby); cnt != wc {
+		t.Errorf("CountReader() of %d copies of mobydick.txt=%d, expected %d", repeats, cnt, wc)
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 2<<20 {
+		t.Errorf("CountReader() allocated %d bytes for a %d byte input, expected at most %d", allocated, size, 2<<20)
+	}
+}
+
+func BenchmarkCountReader(b *testing.B) {
+	mobyDick := loadMoby()
+	b.SetBytes(int64(len(mobyDick)))
+	for i := 0; i < b.N; i++ {
+		_, _ = CountReader(bytes.NewReader(mobyDick), runtime.NumCPU())
+	}
+}
@@ -0,0 +1,202 @@
+package wordcount
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Tokenizer splits UTF-8 encoded text into words. Unlike wordCount, which looks at one
+// rune at a time, it decodes the text into grapheme clusters: user-perceived characters
+// such as a letter with combining accents, an emoji with a skin tone modifier, a family
+// emoji joined with zero width joiners, or a flag made of two regional indicators.
+// Whether a cluster belongs to a word is decided by its first rune.
+//
+// The zero value treats runs of letters and numbers as words and everything else as
+// separators.
+type Tokenizer struct {
+	// IsWord reports whether a cluster starting with r is part of a word. If nil,
+	// letters and numbers are.
+	IsWord func(r rune) bool
+	// Joiners are runes that join the word characters on either side into one word,
+	// such as the apostrophe in "don't" or the hyphen in "sjø-kaptein".
+	Joiners string
+	// SplitIdeographs makes every Han, Hiragana and Katakana character a word of its
+	// own, since Chinese and Japanese are written without spaces between words.
+	SplitIdeographs bool
+	// Emoji makes every emoji a word of its own, instead of a separator.
+	Emoji bool
+}
+
+// DefaultTokenizer joins words with apostrophes and hyphens, and counts ideographs and
+// emoji as words.
+var DefaultTokenizer = &Tokenizer{Joiners: "'’-", SplitIdeographs: true, Emoji: true}
+
+// Each calls fn with every word in b, in order.
+func (t *Tokenizer) Each(b []byte, fn func(word []byte)) {
+	start, end := -1, 0 // the current word is b[start:end]
+	joined := false     // the current word is followed by a joiner
+	flush := func() {
+		if start >= 0 {
+			fn(b[start:end])
+		}
+		start, joined = -1, false
+	}
+	for i, n := 0, 0; i < len(b); i += n {
+		var r rune
+		r, n = nextGrapheme(b[i:])
+		switch {
+		case t.SplitIdeographs && isIdeograph(r), t.Emoji && isEmoji(r):
+			flush()
+			fn(b[i : i+n])
+		case t.isWord(r):
+			// anything but a single joiner between two word clusters ends the word
+			if start < 0 {
+				start = i
+			}
+			end, joined = i+n, false
+		case start >= 0 && !joined && strings.ContainsRune(t.Joiners, r):
+			joined = true
+		default:
+			flush()
+		}
+	}
+	flush()
+}
+
+// Words returns the words in b.
+func (t *Tokenizer) Words(b []byte) (words []string) {
+	t.Each(b, func(word []byte) { words = append(words, string(word)) })
+	return words
+}
+
+// Count returns the number of words in b.
+func (t *Tokenizer) Count(b []byte) (words int) {
+	t.Each(b, func([]byte) { words++ })
+	return words
+}
+
+// Shard splits input into at most numShards shards of roughly equal size, that can be
+// tokenized separately with the same result as input as a whole. Shards always end on
+// a rune boundary after a space, or after an ideograph if they are split, so neither
+// runes, grapheme clusters nor words are cut in two. Input with few such boundaries
+// gives fewer shards.
+func (t *Tokenizer) Shard(input []byte, numShards int) (shards [][]byte) {
+	shardSize := len(input) / max(numShards, 1)
+	start := 0
+	for i := 1; i < numShards; i++ {
+		end := max(start+1, i*shardSize)
+		for end < len(input) && !t.boundary(input, end) {
+			end++
+		}
+		if end >= len(input) {
+			break
+		}
+		shards = append(shards, input[start:end])
+		start = end
+	}
+	return append(shards, input[start:])
+}
+
+// boundary reports whether b can be split in front of b[i].
+func (t *Tokenizer) boundary(b []byte, i int) bool {
+	if i == 0 || !utf8.RuneStart(b[i]) {
+		return false
+	}
+	prev, _ := utf8.DecodeLastRune(b[:i])
+	next, _ := utf8.DecodeRune(b[i:])
+	if extends(next) || prev == zeroWidthJoiner {
+		return false
+	}
+	return unicode.IsSpace(prev) || t.SplitIdeographs && isIdeograph(prev)
+}
+
+// ParallelCount counts the words in input like Count, with a goroutine for each of
+// up to numShards shards.
+func (t *Tokenizer) ParallelCount(input []byte, numShards int) (words int) {
+	shards := t.Shard(input, numShards)
+	counts := make([]int, len(shards))
+	var wg sync.WaitGroup
+	wg.Add(len(shards))
+	for i := range shards {
+		go func(i int) {
+			defer wg.Done()
+			counts[i] = t.Count(shards[i])
+		}(i)
+	}
+	wg.Wait()
+	for _, count := range counts {
+		words += count
+	}
+	return words
+}
+
+func (t *Tokenizer) isWord(r rune) bool {
+	if t.IsWord != nil {
+		return t.IsWord(r)
+	}
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
+
+const zeroWidthJoiner = '\u200d'
+
+// nextGrapheme returns the first rune of the grapheme cluster at the start of b,
+// and the length of the cluster. It follows the most common rules of Unicode's
+// extended grapheme clusters (UAX #29): combining marks, variation selectors, emoji
+// modifiers and tags extend a cluster, a zero width joiner joins it with a following
+// emoji, regional indicators pair up into flags, and CR LF is one cluster. An invalid
+// byte is a cluster of its own.
+func nextGrapheme(b []byte) (first rune, size int) {
+	first, size = utf8.DecodeRune(b)
+	if first == utf8.RuneError && size <= 1 {
+		return first, size
+	}
+	prev, indicators := first, 0
+	if isRegionalIndicator(first) {
+		indicators = 1
+	}
+	for size < len(b) {
+		next, n := utf8.DecodeRune(b[size:])
+		switch {
+		case next == utf8.RuneError && n <= 1:
+			return first, size
+		case extends(next):
+		case prev == zeroWidthJoiner && isEmoji(next):
+		case prev == '\r' && next == '\n' && size == 1:
+		case indicators == 1 && isRegionalIndicator(next):
+			indicators++
+		default:
+			return first, size
+		}
+		size += n
+		prev = next
+	}
+	return first, size
+}
+
+// extends reports whether r belongs to the grapheme cluster before it.
+func extends(r rune) bool {
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		r == zeroWidthJoiner ||
+		'\ufe00' <= r && r <= '\ufe0f' || // variation selectors
+		0x1f3fb <= r && r <= 0x1f3ff || // skin tone modifiers
+		0xe0020 <= r && r <= 0xe007f // tags, as in subdivision flags
+}
+
+func isRegionalIndicator(r rune) bool {
+	return 0x1f1e6 <= r && r <= 0x1f1ff
+}
+
+// isEmoji approximates Unicode's Extended_Pictographic property with the symbol
+// blocks that emoji are taken from.
+func isEmoji(r rune) bool {
+	return 0x1f000 <= r && r <= 0x1faff ||
+		0x2600 <= r && r <= 0x27bf ||
+		0x2b00 <= r && r <= 0x2bff ||
+		r == '©' || r == '®' || r == '‼' || r == '⁉' || r == '™'
+}
+
+func isIdeograph(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
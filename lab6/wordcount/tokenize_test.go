@@ -0,0 +1,145 @@
+package wordcount
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+var tokenizerTests = []struct {
+	name      string
+	tokenizer *Tokenizer
+	text      string
+	want      []string
+}{
+	{"norwegian", &Tokenizer{},
+		"Blåbærsyltetøy er godt, sa Ærlige Øystein på Ålesund-turen.",
+		[]string{"Blåbærsyltetøy", "er", "godt", "sa", "Ærlige", "Øystein", "på", "Ålesund", "turen"}},
+	{"norwegian with joiners", DefaultTokenizer,
+		"Ålesund-turen var Pers idé, ikke Kari's.",
+		[]string{"Ålesund-turen", "var", "Pers", "idé", "ikke", "Kari's"}},
+	{"combining marks", DefaultTokenizer,
+		// the å and é are written as a letter followed by a combining accent
+		"bla\u030abærsyltetøy og ide\u0301",
+		[]string{"bla\u030abærsyltetøy", "og", "ide\u0301"}},
+	{"chinese", DefaultTokenizer,
+		"我爱北京天安门。",
+		[]string{"我", "爱", "北", "京", "天", "安", "门"}},
+	{"chinese without splitting", &Tokenizer{},
+		"我爱北京，天安门。",
+		[]string{"我爱北京", "天安门"}},
+	{"japanese", DefaultTokenizer,
+		"東京はカレー",
+		[]string{"東", "京", "は", "カ", "レ", "ー"}},
+	{"korean", DefaultTokenizer,
+		"안녕하세요 세계",
+		[]string{"안녕하세요", "세계"}},
+	{"emoji", DefaultTokenizer,
+		"I ❤\ufe0f Go 👍🏽👨\u200d👩\u200d👧 🇳🇴🇸🇪!",
+		[]string{"I", "❤\ufe0f", "Go", "👍🏽", "👨\u200d👩\u200d👧", "🇳🇴", "🇸🇪"}},
+	{"emoji as separators", &Tokenizer{},
+		"ja👍🏽nei 🇳🇴",
+		[]string{"ja", "nei"}},
+	{"joiners", DefaultTokenizer,
+		"don't -stop-- 'quoted' rock'n'roll",
+		[]string{"don't", "stop", "quoted", "rock'n'roll"}},
+	{"custom words", &Tokenizer{IsWord: func(r rune) bool { return !unicode.IsSpace(r) }},
+		"e-post: per@example.no, ok?",
+		[]string{"e-post:", "per@example.no,", "ok?"}},
+	{"invalid utf-8", DefaultTokenizer,
+		"ab\xffcd \xc3",
+		[]string{"ab", "cd"}},
+}
+
+func TestTokenizer(t *testing.T) {
+	for _, test := range tokenizerTests {
+		if got := test.tokenizer.Words([]byte(test.text)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Words(%q)=%q, expected %q", test.name, test.text, got, test.want)
+		}
+		if got := test.tokenizer.Count([]byte(test.text)); got != len(test.want) {
+			t.Errorf("%s: Count(%q)=%d, expected %d", test.name, test.text, got, len(test.want))
+		}
+	}
+}
+
+func TestNextGrapheme(t *testing.T) {
+	clusters := []string{"a", "a\u030a", "ø", "❤\ufe0f", "👍🏽", "👨\u200d👩\u200d👧", "🇳🇴", "🏴\U000e0067\U000e0062\U000e0073\U000e0063\U000e0074\U000e007f", "1\ufe0f⃣", "\r\n", "\xff", "我"}
+	text := []byte(strings.Join(clusters, ""))
+	var got []string
+	for len(text) > 0 {
+		_, n := nextGrapheme(text)
+		got = append(got, string(text[:n]))
+		text = text[n:]
+	}
+	if !reflect.DeepEqual(got, clusters) {
+		t.Errorf("nextGrapheme() split the text into %q, expected %q", got, clusters)
+	}
+}
+
+func TestTokenizerShard(t *testing.T) {
+	texts := []string{
+		strings.Repeat("Blåbærsyltetøy på skjærgårdsturen ", 40),
+		strings.Repeat("我爱北京天安门", 40),
+		strings.Repeat("👨\u200d👩\u200d👧🇳🇴 á ", 40),
+		strings.Repeat("ㅅ", 100), // E3 85 85 in UTF-8; 0x85 is a space when cast to a rune
+		string(loadMoby()[:20000]),
+	}
+	for _, text := range texts {
+		input := []byte(text)
+		want := DefaultTokenizer.Words(input)
+		for numShards := 1; numShards < 12; numShards++ {
+			shards := DefaultTokenizer.Shard(input, numShards)
+			if len(shards) > numShards {
+				t.Errorf("Shard(%.10q..., %d) returned %d shards", text, numShards, len(shards))
+			}
+			if !bytes.Equal(bytes.Join(shards, nil), input) {
+				t.Errorf("Shard(%.10q..., %d) lost or reordered input", text, numShards)
+			}
+			var got []string
+			for _, shard := range shards {
+				if !utf8.Valid(shard) {
+					t.Errorf("Shard(%.10q..., %d) cut a rune: %q", text, numShards, shard)
+				}
+				got = append(got, DefaultTokenizer.Words(shard)...)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Shard(%.10q..., %d) gave different words than the whole input", text, numShards)
+			}
+			if cnt := DefaultTokenizer.ParallelCount(input, numShards); cnt != len(want) {
+				t.Errorf("ParallelCount(%.10q..., %d)=%d, expected %d", text, numShards, cnt, len(want))
+			}
+		}
+	}
+	// text without spaces can still be sharded between ideographs
+	if shards := DefaultTokenizer.Shard([]byte(texts[1]), 4); len(shards) != 4 {
+		t.Errorf("Shard() of Chinese text into 4 shards gave %d shards", len(shards))
+	}
+}
+
+func TestShardSliceRuneBoundaries(t *testing.T) {
+	input := []byte(strings.Repeat("ㅅ", 300) + " " + strings.Repeat("ø à ", 300))
+	for shards := 1; shards < 10; shards++ {
+		for _, shard := range shardSlice(input, shards) {
+			if !utf8.Valid(shard) {
+				t.Errorf("shardSlice(..., %d) cut a rune: %.10q...", shards, shard)
+			}
+		}
+	}
+}
+
+func BenchmarkTokenizerCount(b *testing.B) {
+	mobyDick := loadMoby()
+	for i := 0; i < b.N; i++ {
+		_ = DefaultTokenizer.Count(mobyDick)
+	}
+}
+
+func BenchmarkTokenizerParallelCount(b *testing.B) {
+	mobyDick := loadMoby()
+	for i := 0; i < b.N; i++ {
+		_ = DefaultTokenizer.ParallelCount(mobyDick, 4)
+	}
+}
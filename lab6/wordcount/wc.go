@@ -6,6 +6,7 @@ import (
 	"runtime"
 	"sync"
 	"unicode"
+	"unicode/utf8"
 )
 
 func loadMoby() []byte {
@@ -17,10 +18,19 @@ func loadMoby() []byte {
 }
 
 func wordCount(b []byte) (words int) {
+	return countWords(b, false)
+}
 
-	inWord := false
-	for _, v := range b {
-		r := rune(v)
+// countWords counts the words in b like wordCount. If inWord is true, b continues a word,
+// so a space at the start of b ends it. b is decoded as UTF-8, so that letters such as
+// 'å' and '東' count as letters.
+func countWords(b []byte, inWord bool) (words int) {
+	for i, n := 0, 0; i < len(b); i += n {
+		r := rune(b[i])
+		n = 1
+		if r >= utf8.RuneSelf {
+			r, n = utf8.DecodeRune(b[i:])
+		}
 		if unicode.IsSpace(r) && inWord {
 			words++
 			inWord = false
@@ -41,7 +51,8 @@ func shardSlice(input []byte, numShards int) (shards [][]byte) {
 	for i := 0; i < numShards; i++ {
 		for j := end; j < len(input); j++ {
 			char := rune(input[j])
-			if unicode.IsSpace(char) {
+			// only ASCII spaces, since a byte above 0x7f can be in the middle of a rune
+			if char < utf8.RuneSelf && unicode.IsSpace(char) {
 				// split slice at position j, where there is a space
 				// note: need to include the space in the shard to get accurate count
 				end = j + 1
@@ -70,8 +81,7 @@ func doParallelWordCount(input []byte, numShards int) (words int) {
 	var wg sync.WaitGroup
 	var mutex sync.Mutex
 
-	file_byte := loadMoby()
-	sclices := shardSlice(file_byte, numShards)
+	sclices := shardSlice(input, numShards)
 	wg.Add(numShards)
 
 	for i := range sclices {
@@ -13,6 +13,31 @@ func TestWordCount(t *testing.T) {
 	}
 }
 
+// utf8Texts are texts with multi-byte runes. Only runs of letters followed by a space
+// are words, so emoji are not.
+var utf8Texts = []struct {
+	text  string
+	words int
+}{
+	{"jeg så på en blåbær i dag \n", 7},
+	{"Ærlig talt, æblegrød og rømme på Øvre Årdal. ", 6}, // "talt," and "Årdal." end with punctuation
+	{"東京は日本の首都です 北京 \t서울 ", 3},
+	{"I \u2764\ufe0f Go 🎉 and 👍🏽 naïve café ", 5},
+}
+
+func TestWordCountUTF8(t *testing.T) {
+	for _, test := range utf8Texts {
+		if cnt := wordCount([]byte(test.text)); cnt != test.words {
+			t.Errorf("wordCount(%q)=%d, expected %d", test.text, cnt, test.words)
+		}
+		for shards := 1; shards < 8; shards++ {
+			if cnt := doParallelWordCount([]byte(test.text), shards); cnt != test.words {
+				t.Errorf("doParallelWordCount(%q, %d)=%d, expected %d", test.text, shards, cnt, test.words)
+			}
+		}
+	}
+}
+
 func TestParallelWordCount(t *testing.T) {
 	b := loadMoby()
 	cnt := parallelWordCount(b)
@@ -15,6 +15,13 @@ var (
 	serverAddr = flag.String("raddr", "192.168.0.10:61111", "address:port of the remote UDP server.")
 	delay      = flag.Uint("delay", 3000, "Delay (in milliseconds) between each message sent to the server.")
 	textfile   = flag.String("src", "loremipsum.txt", "Name of the textfile to use as the source for messages to the server.")
+
+	timeout      = flag.Uint("timeout", 500, "Time (in milliseconds) to wait for a response before the message is retransmitted.")
+	maxTimeout   = flag.Uint("maxtimeout", 8000, "Maximum time (in milliseconds) to wait for a response. The timeout is doubled after each retransmission, up to this.")
+	retries      = flag.Uint("retries", 6, "Number of retransmissions of a message before it is given up.")
+	loss         = flag.Float64("loss", 0, "Probability of dropping each datagram sent to the server, to simulate a lossy network.")
+	reorder      = flag.Float64("reorder", 0, "Probability of delaying each datagram sent to the server by -reorderdelay, so that later datagrams overtake it.")
+	reorderDelay = flag.Uint("reorderdelay", 1000, "Delay (in milliseconds) of the datagrams that are reordered.")
 )
 
 var sentences []string // sentences from the input file
@@ -63,25 +70,24 @@ func setupConnection() (*net.UDPConn, error) {
 
 // sendAndReceive continuously picks a random sentence from the input file,
 // sends it to the remote server at `conn` and reads and prints the result.
+// A message that the server doesn't respond to is retransmitted, and given
+// up after `retries` retransmissions.
 func sendAndReceive(conn *net.UDPConn) error {
 	log.Println("Starting to message server...")
 
+	rc := newReliableConn(conn)
 	t := time.Tick(time.Duration(*delay) * time.Millisecond)
 	for range t {
 		// pick a random sentence and send it to the server
 		s := sentences[rand.Intn(len(sentences))]
-		_, err := conn.Write([]byte(s))
+		res, err := rc.request([]byte(s))
 		if err != nil {
-			return fmt.Errorf("failed to write to the server: %w", err)
+			log.Printf("Giving up message: %v", err)
+			continue
 		}
 
-		// read the result from the server and print it
-		b := make([]byte, 1024)
-		n, err := conn.Read(b)
-		if err != nil {
-			return fmt.Errorf("failed to read from the server: %w", err)
-		}
-		log.Println(string(b[:n]))
+		// print the result from the server
+		log.Println(string(res))
 	}
 
 	return nil
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"time"
+)
+
+// Every datagram between the client and the server starts with a header of two big
+// endian uint32s: the session, which the client picks at random when it starts, and the
+// sequence number of the request, which the response repeats. The rest of the datagram
+// is the payload. The server has the same definitions, since the two programs are built
+// separately.
+const (
+	headerSize  = 8
+	maxDatagram = 65507 // the largest UDP payload over IPv4
+)
+
+type header struct {
+	session uint32
+	seq     uint32
+}
+
+// datagram returns the header followed by payload.
+func (h header) datagram(payload []byte) []byte {
+	b := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(b[0:], h.session)
+	binary.BigEndian.PutUint32(b[4:], h.seq)
+	copy(b[headerSize:], payload)
+	return b
+}
+
+// parseDatagram splits b into its header and payload.
+func parseDatagram(b []byte) (header, []byte, error) {
+	if len(b) < headerSize {
+		return header{}, nil, fmt.Errorf("datagram of %d bytes is shorter than the header", len(b))
+	}
+	h := header{binary.BigEndian.Uint32(b[0:]), binary.BigEndian.Uint32(b[4:])}
+	return h, b[headerSize:], nil
+}
+
+// unreliable simulates a bad network for the datagrams that are sent through it, to
+// test the retransmissions on loopback.
+type unreliable struct {
+	loss    float64       // probability that a datagram is dropped
+	reorder float64       // probability that a datagram is delayed, so that later ones overtake it
+	delay   time.Duration // how long a reordered datagram is delayed
+}
+
+// send sends b with write, unless it is dropped or delayed.
+func (u unreliable) send(b []byte, write func([]byte) error) error {
+	if rand.Float64() < u.loss {
+		return nil
+	}
+	if rand.Float64() < u.reorder {
+		time.AfterFunc(u.delay, func() {
+			if err := write(b); err != nil {
+				log.Printf("failed writing delayed datagram: %v", err)
+			}
+		})
+		return nil
+	}
+	return write(b)
+}
+
+// reliableConn sends requests to the server, and retransmits them with exponential
+// backoff until a response arrives. Only one request is outstanding at a time, so a
+// response to an earlier request, which arrives late or twice, is recognized by its
+// sequence number and ignored.
+type reliableConn struct {
+	conn       *net.UDPConn
+	session    uint32
+	seq        uint32
+	timeout    time.Duration // how long to wait for the first response
+	maxTimeout time.Duration // the timeout is doubled after each retransmission, up to this
+	retries    int           // retransmissions before giving up
+	net        unreliable
+}
+
+func newReliableConn(conn *net.UDPConn) *reliableConn {
+	return &reliableConn{
+		conn:       conn,
+		session:    rand.Uint32(),
+		timeout:    time.Duration(*timeout) * time.Millisecond,
+		maxTimeout: time.Duration(*maxTimeout) * time.Millisecond,
+		retries:    int(*retries),
+		net:        unreliable{*loss, *reorder, time.Duration(*reorderDelay) * time.Millisecond},
+	}
+}
+
+// request sends payload to the server and returns the response.
+func (c *reliableConn) request(payload []byte) ([]byte, error) {
+	c.seq++
+	h := header{c.session, c.seq}
+	b := h.datagram(payload)
+	if len(b) > maxDatagram {
+		return nil, fmt.Errorf("message of %d bytes is too long for a datagram", len(payload))
+	}
+
+	buf := make([]byte, maxDatagram)
+	timeout := c.timeout
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		if attempt > 0 {
+			log.Printf("No response to message %d after %v, retransmitting...", c.seq, timeout)
+			timeout = min(2*timeout, c.maxTimeout)
+		}
+		err := c.net.send(b, func(b []byte) error {
+			_, err := c.conn.Write(b)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write to the server: %w", err)
+		}
+
+		deadline := time.Now().Add(timeout)
+		c.conn.SetReadDeadline(deadline)
+		for {
+			n, err := c.conn.Read(buf)
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			}
+			if err != nil {
+				// e.g. the server isn't running yet; wait for it like for a lost response
+				log.Printf("failed reading from the server: %v", err)
+				time.Sleep(time.Until(deadline))
+				break
+			}
+			rh, res, err := parseDatagram(buf[:n])
+			if err != nil {
+				log.Printf("Ignoring response: %v", err)
+				continue
+			}
+			if rh != h {
+				// a duplicate or late response to an earlier message
+				continue
+			}
+			return append([]byte(nil), res...), nil
+		}
+	}
+	return nil, fmt.Errorf("no response to message %d after %d retransmissions", c.seq, c.retries)
+}
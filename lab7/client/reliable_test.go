@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// lossyServer answers requests on loopback with their payload in reverse, but drops the
+// first `drop` datagrams it receives. Before each response, it sends a response to the
+// previous request, as if it had arrived late. It returns the connection to the server,
+// and the number of datagrams it has received.
+func lossyServer(t *testing.T, drop int64) (*net.UDPConn, *atomic.Int64) {
+	t.Helper()
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	conn, err := net.DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var received atomic.Int64
+	go func() {
+		buf := make([]byte, maxDatagram)
+		for {
+			n, addr, err := server.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if received.Add(1) <= drop {
+				continue
+			}
+			h, payload, err := parseDatagram(buf[:n])
+			if err != nil {
+				t.Errorf("server received a bad datagram: %v", err)
+				continue
+			}
+			res := make([]byte, len(payload))
+			for i, c := range payload {
+				res[len(res)-1-i] = c
+			}
+			server.WriteToUDP(header{h.session, h.seq - 1}.datagram([]byte("late")), addr)
+			server.WriteToUDP(h.datagram(res), addr)
+		}
+	}()
+	return conn, &received
+}
+
+func TestRequestRetransmits(t *testing.T) {
+	conn, received := lossyServer(t, 3)
+	c := &reliableConn{conn: conn, session: 42, timeout: 20 * time.Millisecond, maxTimeout: 50 * time.Millisecond, retries: 3}
+	res, err := c.request([]byte("Lorem"))
+	if err != nil {
+		t.Fatalf("request() with 3 lost datagrams and 3 retransmissions failed: %v", err)
+	}
+	if !bytes.Equal(res, []byte("meroL")) {
+		t.Errorf("request()=%q, expected %q", res, "meroL")
+	}
+	if n := received.Load(); n != 4 {
+		t.Errorf("server received %d datagrams, expected 4", n)
+	}
+
+	// the next request gets its own response, not the late one to the first request
+	if res, err := c.request([]byte("ipsum")); err != nil || !bytes.Equal(res, []byte("muspi")) {
+		t.Errorf("second request()=%q, %v, expected %q", res, err, "muspi")
+	}
+}
+
+func TestRequestGivesUp(t *testing.T) {
+	conn, received := lossyServer(t, 4)
+	c := &reliableConn{conn: conn, session: 42, timeout: 20 * time.Millisecond, maxTimeout: 50 * time.Millisecond, retries: 3}
+	if res, err := c.request([]byte("Lorem")); err == nil {
+		t.Errorf("request() with 4 lost datagrams and 3 retransmissions returned %q", res)
+	}
+	if n := received.Load(); n != 4 {
+		t.Errorf("server received %d datagrams, expected 4", n)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	serverAddr = flag.String("raddr", "192.168.1.10:61111", "address:port of the remote UDP server.")
+	clients    = flag.Int("clients", 100, "Number of clients that message the server at the same time, each from its own port.")
+	duration   = flag.Duration("d", 10*time.Second, "How long to flood the server.")
+	rate       = flag.Float64("rate", 0, "Messages per second sent by each client. If 0, each client sends its next message as soon as it has a response.")
+	timeout    = flag.Duration("timeout", 200*time.Millisecond, "Time to wait for a response before the message is counted as lost. Messages are not retransmitted.")
+	message    = flag.String("msg", "Lorem ipsum dolor sit amet, consectetur adipiscing elit.", "The message that is sent.")
+)
+
+// Every datagram starts with the session and sequence number of the message, as big
+// endian uint32s, like those of the client and the server.
+const headerSize = 8
+
+// results are the counts of all clients
+type results struct {
+	sent     atomic.Int64
+	answered atomic.Int64
+	lost     atomic.Int64 // not answered within the timeout, e.g. because they were rejected
+
+	lock      sync.Mutex
+	latencies []time.Duration // of the answered messages
+}
+
+// flood sends messages to the server from its own port until `stop`, and waits for
+// each response before it sends the next message
+func flood(r *results, stop time.Time) error {
+	addr, err := net.ResolveUDPAddr("udp", *serverAddr)
+	if err != nil {
+		return fmt.Errorf("address translation failed: %w", err)
+	}
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return fmt.Errorf("failed to dial remote server: %w", err)
+	}
+	defer conn.Close()
+
+	var interval time.Duration
+	if *rate > 0 {
+		interval = time.Duration(float64(time.Second) / *rate)
+	}
+	session := rand.Uint32()
+	req := make([]byte, headerSize+len(*message))
+	copy(req[headerSize:], *message)
+	buf := make([]byte, 2*len(req))
+	var latencies []time.Duration
+	next := time.Now()
+	for seq := uint32(1); time.Now().Before(stop); seq++ {
+		time.Sleep(time.Until(next))
+		next = next.Add(interval)
+
+		binary.BigEndian.PutUint32(req[0:], session)
+		binary.BigEndian.PutUint32(req[4:], seq)
+		start := time.Now()
+		if _, err := conn.Write(req); err != nil {
+			return fmt.Errorf("failed to write to the server: %w", err)
+		}
+		r.sent.Add(1)
+
+		conn.SetReadDeadline(start.Add(*timeout))
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				r.lost.Add(1)
+				break
+			}
+			if n >= headerSize && binary.BigEndian.Uint32(buf[4:]) == seq {
+				r.answered.Add(1)
+				latencies = append(latencies, time.Since(start))
+				break
+			}
+			// a late response to an earlier message
+		}
+	}
+
+	r.lock.Lock()
+	r.latencies = append(r.latencies, latencies...)
+	r.lock.Unlock()
+	return nil
+}
+
+// percentile returns the latency that `p` percent of the sorted `latencies` are below
+func percentile(latencies []time.Duration, p float64) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	return latencies[min(len(latencies)-1, int(float64(len(latencies))*p/100))]
+}
+
+func main() {
+	flag.Parse()
+
+	log.Printf("Flooding '%s' from %d clients for %v...", *serverAddr, *clients, *duration)
+	var r results
+	var wg sync.WaitGroup
+	start := time.Now()
+	stop := start.Add(*duration)
+	for i := 0; i < *clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := flood(&r, stop); err != nil {
+				log.Printf("Client stopped: %v", err)
+			}
+		}()
+	}
+
+	// print progress while the clients are running
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for running := true; running; {
+		select {
+		case <-t.C:
+			log.Printf("sent: %d, answered: %d, lost: %d", r.sent.Load(), r.answered.Load(), r.lost.Load())
+		case <-done:
+			running = false
+		}
+	}
+
+	elapsed := time.Since(start)
+	sort.Slice(r.latencies, func(i, j int) bool { return r.latencies[i] < r.latencies[j] })
+	sent, answered, lost := r.sent.Load(), r.answered.Load(), r.lost.Load()
+	fmt.Printf("sent %d messages in %v: %d answered (%.0f/s), %d lost (%.1f%%)\n",
+		sent, elapsed.Round(time.Millisecond), answered, float64(answered)/elapsed.Seconds(), lost, 100*float64(lost)/float64(max(sent, 1)))
+	fmt.Printf("latency p50: %v, p90: %v, p99: %v, max: %v\n",
+		percentile(r.latencies, 50), percentile(r.latencies, 90), percentile(r.latencies, 99), percentile(r.latencies, 100))
+}
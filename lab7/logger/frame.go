@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The server sends an event to the logger for every request it handles. On the TCP
+// connection, each event is a frame: a big endian uint32 with the length of the body,
+// followed by the body, which is the JSON encoded event. Since TCP is a stream, a read
+// may return part of a frame, or several frames at once; the length tells where each
+// frame ends. The server and the logger have the same definitions, since the two
+// programs are built separately.
+
+// protocolVersion is the version of the event schema. It must be increased when the
+// meaning of a field changes, or a field is removed.
+const protocolVersion = 1
+
+// maxFrameSize is the largest body a frame may have, to avoid allocating huge buffers
+// for a corrupt length.
+const maxFrameSize = 64 << 10
+
+// errBadEvent is returned by readFrame for a complete frame that doesn't hold a valid event.
+var errBadEvent = errors.New("bad event")
+
+// event is the message sent by the server to the logger about a request from a client.
+type event struct {
+	Version  int
+	Client   string        // address:port of the client
+	Time     time.Time     // when the server received the request
+	BytesIn  int           // size of the request payload
+	BytesOut int           // size of the response payload
+	Latency  time.Duration // how long the server took to handle the request
+}
+
+// writeFrame writes e to w as one frame, with a single Write, so that frames written
+// by different goroutines to the same connection are not interleaved.
+func writeFrame(w io.Writer, e event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	if len(body) > maxFrameSize {
+		return fmt.Errorf("event of %d bytes is larger than a frame", len(body))
+	}
+	frame := make([]byte, 4+len(body))
+	binary.BigEndian.PutUint32(frame, uint32(len(body)))
+	copy(frame[4:], body)
+	_, err = w.Write(frame)
+	return err
+}
+
+// readFrame reads the next frame from r into e. If the frame can't be decoded, the
+// returned error wraps errBadEvent, and the next frame can still be read.
+func readFrame(r io.Reader, e *event) error {
+	var length [4]byte
+	if _, err := io.ReadFull(r, length[:]); err != nil {
+		return err
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n > maxFrameSize {
+		return fmt.Errorf("frame of %d bytes is larger than %d bytes", n, maxFrameSize)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	*e = event{}
+	if err := json.Unmarshal(body, e); err != nil {
+		return fmt.Errorf("%w: %v", errBadEvent, err)
+	}
+	if e.Version != protocolVersion {
+		return fmt.Errorf("%w: unsupported version %d", errBadEvent, e.Version)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+func TestFrameCopiesMatch(t *testing.T) {
+	// the server and the logger are built separately, so each has a copy of frame.go
+	logger, err := os.ReadFile("frame.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := os.ReadFile("../server/frame.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(logger, server) {
+		t.Error("logger/frame.go and server/frame.go differ")
+	}
+}
+
+func frameEvents() []event {
+	t := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	return []event{
+		{protocolVersion, "192.168.1.2:45678", t, 57, 57, 120 * time.Microsecond},
+		{protocolVersion, "192.168.1.3:1234", t.Add(time.Second), 0, 0, 0},
+		{protocolVersion, "[::1]:61111", t.Add(time.Minute), 1000, 1000, time.Second},
+	}
+}
+
+func TestReadFrameCoalescedAndPartial(t *testing.T) {
+	// all frames are coalesced into one buffer, which is read one byte at a time
+	var buf bytes.Buffer
+	want := frameEvents()
+	for _, e := range want {
+		if err := writeFrame(&buf, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := iotest.OneByteReader(&buf)
+	for i, w := range want {
+		var e event
+		if err := readFrame(r, &e); err != nil {
+			t.Fatalf("readFrame() of frame %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(e, w) {
+			t.Errorf("readFrame() of frame %d=%+v, expected %+v", i, e, w)
+		}
+	}
+	var e event
+	if err := readFrame(r, &e); err != io.EOF {
+		t.Errorf("readFrame() after the last frame: want '%v', got '%v'", io.EOF, err)
+	}
+}
+
+func TestReadFrameTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeFrame(&buf, frameEvents()[0]); err != nil {
+		t.Fatal(err)
+	}
+	frame := buf.Bytes()
+	for _, n := range []int{2, 4, 10, len(frame) - 1} {
+		var e event
+		if err := readFrame(bytes.NewReader(frame[:n]), &e); err != io.ErrUnexpectedEOF {
+			t.Errorf("readFrame() of the first %d of %d bytes: want '%v', got '%v'", n, len(frame), io.ErrUnexpectedEOF, err)
+		}
+	}
+}
+
+func TestReadFrameOversized(t *testing.T) {
+	var frame [4]byte
+	binary.BigEndian.PutUint32(frame[:], maxFrameSize+1)
+	var e event
+	err := readFrame(bytes.NewReader(frame[:]), &e)
+	if err == nil || errors.Is(err, errBadEvent) || err == io.ErrUnexpectedEOF {
+		t.Errorf("readFrame() with a length of %d: want a frame size error, got '%v'", maxFrameSize+1, err)
+	}
+
+	big := frameEvents()[0]
+	big.Client = strings.Repeat("x", maxFrameSize)
+	if err := writeFrame(io.Discard, big); err == nil {
+		t.Errorf("writeFrame() of an event larger than a frame succeeded")
+	}
+}
+
+func TestReadFrameBadEvent(t *testing.T) {
+	var buf bytes.Buffer
+	events := frameEvents()
+	wrongVersion := events[0]
+	wrongVersion.Version = protocolVersion + 1
+	writeFrame(&buf, wrongVersion)
+	buf.Write([]byte{0, 0, 0, 3})
+	buf.WriteString("{x}")
+	writeFrame(&buf, events[1])
+
+	r := iotest.OneByteReader(&buf)
+	for _, what := range []string{"a wrong version", "invalid JSON"} {
+		var e event
+		if err := readFrame(r, &e); !errors.Is(err, errBadEvent) {
+			t.Errorf("readFrame() of %s: want '%v', got '%v'", what, errBadEvent, err)
+		}
+	}
+	var e event
+	if err := readFrame(r, &e); err != nil || !reflect.DeepEqual(e, events[1]) {
+		t.Errorf("readFrame() after bad events=%+v, %v, expected %+v", e, err, events[1])
+	}
+}
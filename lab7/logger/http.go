@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// defaultWindows are the windows that rates are returned for, if none are given
+var defaultWindows = []string{"1m", "5m", "1h"}
+
+// clientReport is the JSON-formatted stats of a client returned by the query API
+type clientReport struct {
+	Messages     int                     `json:"messages"`
+	BytesIn      int                     `json:"bytesIn"`
+	BytesOut     int                     `json:"bytesOut"`
+	AvgLatencyMs float64                 `json:"avgLatencyMs"`
+	Windows      map[string]windowReport `json:"windows"`
+}
+
+// windowReport is the messages received from a client during a window up to now
+type windowReport struct {
+	Messages int     `json:"messages"`
+	Rate     float64 `json:"rate"` // messages per second
+}
+
+// serveStats returns the stats of every client as JSON, with their message counts and
+// rates during the windows given by the `window` parameters, e.g.
+//
+//	GET /stats?window=10s&window=1m&client=192.168.1.2:45678
+//
+// If the `client` parameter is given, only that client is returned.
+func (l *logger) serveStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	names := query["window"]
+	if len(names) == 0 {
+		names = defaultWindows
+	}
+	windows := make(map[string]time.Duration)
+	for _, name := range names {
+		window, err := time.ParseDuration(name)
+		if err != nil || window <= 0 || window > *retention {
+			http.Error(w, fmt.Sprintf("invalid window '%s': must be a duration up to %v", name, *retention), http.StatusBadRequest)
+			return
+		}
+		windows[name] = window
+	}
+	client := query.Get("client")
+
+	now := time.Now()
+	reports := make(map[string]clientReport)
+	l.lock.Lock()
+	for addr, s := range l.log {
+		if client != "" && addr != client {
+			continue
+		}
+		report := clientReport{
+			Messages:     s.Messages,
+			BytesIn:      s.BytesIn,
+			BytesOut:     s.BytesOut,
+			AvgLatencyMs: float64(s.avgLatency()) / float64(time.Millisecond),
+			Windows:      make(map[string]windowReport),
+		}
+		for name, window := range windows {
+			n := s.inWindow(now, window)
+			report.Windows[name] = windowReport{n, float64(n) / window.Seconds()}
+		}
+		reports[addr] = report
+	}
+	l.lock.Unlock()
+
+	if client != "" && len(reports) == 0 {
+		http.Error(w, fmt.Sprintf("unknown client '%s'", client), http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(struct {
+		Time    time.Time               `json:"time"`
+		Clients map[string]clientReport `json:"clients"`
+	}{now, reports})
+	if err != nil {
+		log.Printf("Failed writing stats to %v: %v", r.RemoteAddr, err)
+	}
+}
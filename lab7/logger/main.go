@@ -1,11 +1,13 @@
 package main
 
 import (
-	"encoding/json"
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"sync"
 	"time"
 )
@@ -13,28 +15,39 @@ import (
 var (
 	hostName = flag.String("addr", ":61112", "address:port to listen for TCP requests.")
 	interval = flag.Uint("t", 10, "How often (in seconds) the logger prints its current state.")
+
+	dataDir   = flag.String("dir", "logdata", "Directory of the on-disk log, which the logger's state is rebuilt from when it is restarted.")
+	maxSize   = flag.Int64("maxsize", 1<<20, "Size (in bytes) of the on-disk log segments. A new segment is started when the current one is full.")
+	keep      = flag.Int("keep", 2, "Number of full segments that are kept before they are compacted into the snapshot.")
+	retention = flag.Duration("retention", time.Hour, "How long the number of messages per second is kept, which is the longest window that rates can be queried for.")
+	httpAddr  = flag.String("http", ":8080", "address:port to serve the HTTP query API at.")
 )
 
 // logger logs the number of messages received by hosts connected to a remote server
 type logger struct {
-	log      map[string]int
+	log      counters
+	store    *store
 	listener *net.TCPListener
 	lock     sync.Mutex
 }
 
-// msg is used by the JSON-formatted message sent by the server
-type msg struct {
-	Client string
+// newLogger rebuilds the logger's state from the on-disk log
+func newLogger() (*logger, error) {
+	store, log, err := openStore(*dataDir, *maxSize, *keep, *retention)
+	if err != nil {
+		return nil, fmt.Errorf("failed opening the log in '%s': %w", *dataDir, err)
+	}
+	return &logger{log: log, store: store}, nil
 }
 
-func newLogger() *logger {
-	return &logger{log: make(map[string]int)}
-}
+// updateLog appends the event to the on-disk log and adds it to the client's stats
+func (l *logger) updateLog(e event) {
+	if err := l.store.append(e); err != nil {
+		log.Printf("Failed writing event to the log: %v", err)
+	}
 
-// updateLog increments the client's counter
-func (l *logger) updateLog(client string) {
 	l.lock.Lock()
-	l.log[client]++
+	l.log.add(e)
 	l.lock.Unlock()
 }
 
@@ -64,6 +77,9 @@ func (l *logger) handle() {
 		t := time.Tick(time.Duration(*interval) * time.Second)
 		for range t {
 			l.printState()
+			l.lock.Lock()
+			l.log.prune(time.Now().Add(-*retention))
+			l.lock.Unlock()
 		}
 	}()
 
@@ -79,24 +95,22 @@ func (l *logger) handle() {
 		// handle requests in the background
 		go func(conn *net.TCPConn) {
 			defer conn.Close()
-			b := make([]byte, 1024)
+			r := bufio.NewReader(conn)
 			for {
-				n, err := conn.Read(b)
+				// read the next frame, however the stream is split into reads
+				var e event
+				err := readFrame(r, &e)
+				if errors.Is(err, errBadEvent) {
+					log.Printf("Failed to decode event from %v. Ignoring the event. Error: %v", conn.RemoteAddr(), err)
+					continue
+				}
 				if err != nil {
 					log.Printf("Failed reading from %v. Closing connection. Error: %v", conn.RemoteAddr(), err)
 					return
 				}
 
-				// translate the JSON-formatted message
-				m := &msg{}
-				err = json.Unmarshal(b[:n], m)
-				if err != nil {
-					log.Printf("Failed to unmarshal message from %v. Ignoring the message. Error: %v", conn.RemoteAddr(), err)
-					continue
-				}
-
 				// update the log
-				l.updateLog(m.Client)
+				l.updateLog(e)
 			}
 		}(conn)
 	}
@@ -109,17 +123,31 @@ func (l *logger) printState() {
 
 	var str string
 	for k, v := range l.log {
-		str += fmt.Sprintf("(%s: %d)", k, v)
+		str += fmt.Sprintf("(%s: %v)", k, v)
 	}
 	log.Println("Current state:", str)
 }
 
 func main() {
-	l := newLogger()
-	err := l.startListener()
-	defer l.listener.Close()
+	flag.Parse()
+
+	l, err := newLogger()
+	if err != nil {
+		panic(err)
+	}
+	defer l.store.close()
+	err = l.startListener()
 	if err != nil {
 		panic(err)
 	}
+	defer l.listener.Close()
+
+	// serve the query API in the background
+	http.HandleFunc("/stats", l.serveStats)
+	go func() {
+		log.Printf("Serving the query API at '%s'...", *httpAddr)
+		err := http.ListenAndServe(*httpAddr, nil)
+		log.Printf("Query API stopped: %v", err)
+	}()
 	l.handle()
 }
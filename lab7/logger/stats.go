@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// stats are the totals of the events logged for a client, and the number of messages
+// in each second of the retention period, to compute rates over time windows
+type stats struct {
+	Messages  int
+	BytesIn   int
+	BytesOut  int
+	Latency   time.Duration // the sum of the latencies
+	PerSecond map[int64]int // messages received in each Unix second
+}
+
+func (s *stats) String() string {
+	return fmt.Sprintf("%d msgs, %d/%d bytes in/out, %v avg latency",
+		s.Messages, s.BytesIn, s.BytesOut, s.avgLatency())
+}
+
+func (s *stats) avgLatency() time.Duration {
+	if s.Messages == 0 {
+		return 0
+	}
+	return s.Latency / time.Duration(s.Messages)
+}
+
+// inWindow returns the number of messages received during the `window` up to `now`
+func (s *stats) inWindow(now time.Time, window time.Duration) int {
+	from, to := now.Add(-window).Unix(), now.Unix()
+	n := 0
+	for second, messages := range s.PerSecond {
+		if from < second && second <= to {
+			n += messages
+		}
+	}
+	return n
+}
+
+// counters are the stats of every client, by address:port
+type counters map[string]*stats
+
+// add adds event `e` to the stats of its client
+func (c counters) add(e event) {
+	s, ok := c[e.Client]
+	if !ok {
+		s = &stats{PerSecond: make(map[int64]int)}
+		c[e.Client] = s
+	}
+	s.Messages++
+	s.BytesIn += e.BytesIn
+	s.BytesOut += e.BytesOut
+	s.Latency += e.Latency
+	s.PerSecond[e.Time.Unix()]++
+}
+
+// prune forgets the messages per second from before `t`; the totals are kept
+func (c counters) prune(t time.Time) {
+	for _, s := range c {
+		for second := range s.PerSecond {
+			if second < t.Unix() {
+				delete(s.PerSecond, second)
+			}
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The logger appends every event to a log on disk, so that it can rebuild its counters
+// when it is restarted. The log is a directory of segments, files with one JSON encoded
+// event per line, which are numbered in the order they are written. When the active
+// segment has grown to `maxSize` bytes, a new one is started. The oldest closed segments
+// are then compacted: their events are folded into the snapshot, which holds the
+// counters as of the end of the last segment in it, and the segments are removed.
+
+const snapshotName = "snapshot.json"
+
+// segmentName returns the file name of segment number `n`
+func segmentName(n int) string {
+	return fmt.Sprintf("events-%06d.log", n)
+}
+
+// segments returns the numbers of the segments in `dir`, in order
+func segments(dir string) ([]int, error) {
+	names, err := filepath.Glob(filepath.Join(dir, "events-*.log"))
+	if err != nil {
+		return nil, err
+	}
+	var nums []int
+	for _, name := range names {
+		var n int
+		if _, err := fmt.Sscanf(filepath.Base(name), "events-%d.log", &n); err == nil {
+			nums = append(nums, n)
+		}
+	}
+	sort.Ints(nums)
+	return nums, nil
+}
+
+// replaySegment adds the events in segment `n` to `c`. A line that can't be decoded,
+// like the last line of a segment that was being written when the logger died, is
+// skipped.
+func replaySegment(dir string, n int, c counters) error {
+	f, err := os.Open(filepath.Join(dir, segmentName(n)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxFrameSize+1)
+	for line := 1; scanner.Scan(); line++ {
+		var e event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			log.Printf("Skipping line %d of %s: %v", line, segmentName(n), err)
+			continue
+		}
+		c.add(e)
+	}
+	return scanner.Err()
+}
+
+// snapshot is the counters of all events in the segments up to and including `Through`
+type snapshot struct {
+	Through int
+	Clients counters
+}
+
+// readSnapshot reads the snapshot in `dir`, or returns an empty one if there is none
+func readSnapshot(dir string) (snapshot, error) {
+	snap := snapshot{Clients: make(counters)}
+	b, err := os.ReadFile(filepath.Join(dir, snapshotName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return snap, nil
+	}
+	if err != nil {
+		return snap, err
+	}
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return snap, fmt.Errorf("corrupt snapshot: %w", err)
+	}
+	if snap.Clients == nil {
+		snap.Clients = make(counters)
+	}
+	return snap, nil
+}
+
+// writeSnapshot replaces the snapshot in `dir` by `snap`. The old snapshot is only
+// replaced once the new one is completely written, so a crash leaves one of them.
+func writeSnapshot(dir string, snap snapshot) error {
+	f, err := os.CreateTemp(dir, snapshotName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // fails once renamed
+
+	err = json.NewEncoder(f).Encode(snap)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, snapshotName))
+}
+
+// store appends events to the segments in a directory
+type store struct {
+	dir       string
+	maxSize   int64         // size from which a new segment is started
+	keep      int           // closed segments that are kept, and not compacted
+	retention time.Duration // how long the messages per second are kept
+
+	lock   sync.Mutex // protects the active segment
+	active *os.File
+	seq    int // the number of the active segment
+	size   int64
+
+	compacting sync.Mutex // held while segments are compacted
+}
+
+// openStore opens the store in `dir`, creating it if it doesn't exist, and returns the
+// counters rebuilt from the snapshot and segments in it. New events are appended to a
+// new segment.
+func openStore(dir string, maxSize int64, keep int, retention time.Duration) (*store, counters, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, nil, err
+	}
+	snap, err := readSnapshot(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	nums, err := segments(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := snap.Through
+	for _, n := range nums {
+		if n <= snap.Through {
+			// left behind by a compaction that was interrupted
+			os.Remove(filepath.Join(dir, segmentName(n)))
+			continue
+		}
+		if err := replaySegment(dir, n, snap.Clients); err != nil {
+			return nil, nil, fmt.Errorf("failed replaying %s: %w", segmentName(n), err)
+		}
+		last = n
+	}
+	snap.Clients.prune(time.Now().Add(-retention))
+
+	s := &store{dir: dir, maxSize: maxSize, keep: keep, retention: retention, seq: last}
+	s.lock.Lock()
+	err = s.rotate()
+	s.lock.Unlock()
+	if err != nil {
+		return nil, nil, err
+	}
+	go s.compact()
+	return s, snap.Clients, nil
+}
+
+// append appends `e` to the active segment, and starts a new segment first if the
+// active one is full, or couldn't be started before
+func (s *store) append(e event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.active == nil || (s.size > 0 && s.size+int64(len(b)) > s.maxSize) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+		go s.compact()
+	}
+	n, err := s.active.Write(b)
+	s.size += int64(n)
+	return err
+}
+
+// rotate closes the active segment and starts the next one. The caller must hold the lock.
+func (s *store) rotate() error {
+	if s.active != nil {
+		if err := s.active.Close(); err != nil {
+			log.Printf("Failed closing %s: %v", segmentName(s.seq), err)
+		}
+	}
+	s.seq++
+	f, err := os.OpenFile(filepath.Join(s.dir, segmentName(s.seq)), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		s.active = nil
+		return fmt.Errorf("failed starting segment: %w", err)
+	}
+	s.active, s.size = f, 0
+	return nil
+}
+
+// compact folds the closed segments, except the `keep` newest, into the snapshot, and
+// removes them. Closed segments are never written again, so this runs in the
+// background, but only one compaction runs at a time.
+func (s *store) compact() {
+	if !s.compacting.TryLock() {
+		return
+	}
+	defer s.compacting.Unlock()
+
+	s.lock.Lock()
+	active := s.seq
+	s.lock.Unlock()
+	nums, err := segments(s.dir)
+	if err != nil {
+		log.Printf("Compaction failed: %v", err)
+		return
+	}
+	var closed []int
+	for _, n := range nums {
+		if n < active {
+			closed = append(closed, n)
+		}
+	}
+	if len(closed) <= s.keep {
+		return
+	}
+	fold := closed[:len(closed)-s.keep]
+
+	snap, err := readSnapshot(s.dir)
+	if err != nil {
+		log.Printf("Compaction failed: %v", err)
+		return
+	}
+	for _, n := range fold {
+		if n <= snap.Through {
+			continue
+		}
+		if err := replaySegment(s.dir, n, snap.Clients); err != nil {
+			log.Printf("Compaction failed: %v", err)
+			return
+		}
+		snap.Through = n
+	}
+	snap.Clients.prune(time.Now().Add(-s.retention))
+	if err := writeSnapshot(s.dir, snap); err != nil {
+		log.Printf("Compaction failed writing the snapshot: %v", err)
+		return
+	}
+	for _, n := range fold {
+		os.Remove(filepath.Join(s.dir, segmentName(n)))
+	}
+	log.Printf("Compacted %d segments into the snapshot, through %s", len(fold), segmentName(snap.Through))
+}
+
+// close closes the active segment
+func (s *store) close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.active == nil {
+		return nil
+	}
+	return s.active.Close()
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testEvent(client string, t time.Time) event {
+	return event{Version: protocolVersion, Client: client, Time: t, BytesIn: 10, BytesOut: 12, Latency: time.Millisecond}
+}
+
+// closeStore waits for a background compaction to finish, and closes s.
+func closeStore(t *testing.T, s *store) {
+	t.Helper()
+	s.compacting.Lock()
+	defer s.compacting.Unlock()
+	if err := s.close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeSegment writes the lines as segment number n in dir.
+func writeSegment(t *testing.T, dir string, n int, lines ...string) {
+	t.Helper()
+	var b []byte
+	for _, line := range lines {
+		b = append(b, line+"\n"...)
+	}
+	if err := os.WriteFile(filepath.Join(dir, segmentName(n)), b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func eventLine(t *testing.T, e event) string {
+	t.Helper()
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestStoreRotateCompactRestart(t *testing.T) {
+	dir := t.TempDir()
+	s, got, err := openStore(dir, 500, 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("openStore() of an empty directory returned %d clients", len(got))
+	}
+
+	want := make(counters)
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		e := testEvent(fmt.Sprintf("10.0.0.%d:1234", i%3), now.Add(-time.Duration(i)*time.Second))
+		if err := s.append(e); err != nil {
+			t.Fatal(err)
+		}
+		want.add(e)
+	}
+	// wait for the compactions started by the rotations, and compact what is left
+	s.compacting.Lock()
+	s.compacting.Unlock()
+	s.compact()
+
+	nums, err := segments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nums) != 2 {
+		t.Errorf("after compaction, %d segments are left, expected the active one and 1 kept: %v", len(nums), nums)
+	}
+	snap, err := readSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Through != nums[0]-1 {
+		t.Errorf("snapshot is through segment %d, expected %d", snap.Through, nums[0]-1)
+	}
+	closeStore(t, s)
+
+	s, got, err = openStore(dir, 500, 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeStore(t, s)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("counters after restart differ from those before:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestStoreTornLine(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	line := eventLine(t, testEvent("a:1", now))
+	writeSegment(t, dir, 1, line, line, line[:len(line)/2])
+
+	s, got, err := openStore(dir, 1<<20, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeStore(t, s)
+	if c := got["a:1"]; c == nil || c.Messages != 2 {
+		t.Errorf("openStore() with a torn last line counted %v, expected 2 messages", c)
+	}
+}
+
+func TestStoreInterruptedCompaction(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old, recent := testEvent("a:1", now.Add(-time.Minute)), testEvent("a:1", now)
+
+	// segments 1 and 2 were folded into the snapshot, but only 1 was removed
+	snap := snapshot{Through: 2, Clients: make(counters)}
+	snap.Clients.add(old)
+	snap.Clients.add(old)
+	if err := writeSnapshot(dir, snap); err != nil {
+		t.Fatal(err)
+	}
+	writeSegment(t, dir, 2, eventLine(t, old))
+	writeSegment(t, dir, 3, eventLine(t, recent))
+
+	s, got, err := openStore(dir, 1<<20, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closeStore(t, s)
+	want := make(counters)
+	want.add(old)
+	want.add(old)
+	want.add(recent)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("openStore() after an interrupted compaction counted %v, expected %v", got["a:1"], want["a:1"])
+	}
+	if _, err := os.Stat(filepath.Join(dir, segmentName(2))); !os.IsNotExist(err) {
+		t.Errorf("segment 2, which is in the snapshot, was not removed: %v", err)
+	}
+	if s.seq != 4 {
+		t.Errorf("new events are appended to segment %d, expected 4", s.seq)
+	}
+}
+
+func TestServeStats(t *testing.T) {
+	now := time.Now()
+	l := &logger{log: make(counters)}
+	for _, age := range []time.Duration{10 * time.Second, 20 * time.Second, 30 * time.Second, 2 * time.Minute, 3 * time.Minute} {
+		l.log.add(testEvent("a:1", now.Add(-age)))
+	}
+	l.log.add(testEvent("b:2", now.Add(-10*time.Minute)))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		l.serveStats(w, httptest.NewRequest(http.MethodGet, "/stats"+query, nil))
+		return w
+	}
+
+	w := get("?window=1m&window=5m")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /stats: status %d: %s", w.Code, w.Body)
+	}
+	var res struct {
+		Clients map[string]clientReport
+	}
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	a, b := res.Clients["a:1"], res.Clients["b:2"]
+	if a.Messages != 5 || a.BytesIn != 50 || a.BytesOut != 60 || a.AvgLatencyMs != 1 {
+		t.Errorf("GET /stats returned the totals %+v for a:1", a)
+	}
+	wantA := map[string]windowReport{"1m": {3, 3.0 / 60}, "5m": {5, 5.0 / 300}}
+	if !reflect.DeepEqual(a.Windows, wantA) {
+		t.Errorf("GET /stats returned the windows %v for a:1, expected %v", a.Windows, wantA)
+	}
+	wantB := map[string]windowReport{"1m": {0, 0}, "5m": {0, 0}}
+	if b.Messages != 1 || !reflect.DeepEqual(b.Windows, wantB) {
+		t.Errorf("GET /stats returned %+v for b:2, expected 1 message outside the windows", b)
+	}
+
+	w = get("?client=b:2")
+	res.Clients = nil
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Clients) != 1 || len(res.Clients["b:2"].Windows) != len(defaultWindows) {
+		t.Errorf("GET /stats?client=b:2 returned %+v", res.Clients)
+	}
+
+	for query, code := range map[string]int{
+		"?window=nope":      http.StatusBadRequest,
+		"?window=-1m":       http.StatusBadRequest,
+		"?window=2h":        http.StatusBadRequest, // longer than the retention
+		"?client=unknown:1": http.StatusNotFound,
+	} {
+		if w := get(query); w.Code != code {
+			t.Errorf("GET /stats%s: status %d, expected %d", query, w.Code, code)
+		}
+	}
+}
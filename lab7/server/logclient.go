@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	minBackoff   = 100 * time.Millisecond // wait before the first reconnect
+	maxBackoff   = 10 * time.Second       // the wait is doubled after each failure, up to this
+	writeTimeout = 5 * time.Second        // a logger that doesn't accept an event this fast is considered dead
+)
+
+// logClient sends events to the logger. If the logger is down when the server starts,
+// or dies later, the client reconnects with exponential backoff. Meanwhile, events are
+// kept in a bounded buffer, and sent when the connection is back up. When the buffer
+// is full, new events are dropped, and counted. Since the logger doesn't acknowledge
+// events, those it had received but not read when it died are lost.
+type logClient struct {
+	addr   string
+	events chan event
+
+	connected  atomic.Bool
+	sent       atomic.Int64
+	dropped    atomic.Int64
+	reconnects atomic.Int64
+}
+
+// newLogClient starts connecting to the logger at `addr` in the background, and buffers
+// up to `buffer` events until it is connected
+func newLogClient(addr string, buffer int) *logClient {
+	c := &logClient{addr: addr, events: make(chan event, buffer)}
+	go c.run()
+	return c
+}
+
+// log queues event `e` to be sent to the logger, or drops it if the buffer is full.
+// It never blocks.
+func (c *logClient) log(e event) {
+	select {
+	case c.events <- e:
+	default:
+		c.dropped.Add(1)
+	}
+}
+
+// String returns the metrics of the connection to the logger
+func (c *logClient) String() string {
+	return fmt.Sprintf("connected: %v, sent: %d, queued: %d, dropped: %d, reconnects: %d",
+		c.connected.Load(), c.sent.Load(), len(c.events), c.dropped.Load(), c.reconnects.Load())
+}
+
+// run connects to the logger and sends the queued events, and reconnects whenever the
+// connection fails
+func (c *logClient) run() {
+	backoff := minBackoff
+	var pending *event // the event that failed to be sent, to be sent again
+	for first := true; ; first = false {
+		conn, err := c.dial()
+		for err != nil {
+			log.Printf("Failed connecting to logger, retrying in %v: %v", backoff, err)
+			time.Sleep(backoff)
+			backoff = min(2*backoff, maxBackoff)
+			conn, err = c.dial()
+		}
+		backoff = minBackoff
+		if !first {
+			c.reconnects.Add(1)
+		}
+		log.Printf("Connected to logger at %v", conn.RemoteAddr())
+
+		c.connected.Store(true)
+		pending, err = c.send(conn, pending)
+		c.connected.Store(false)
+		conn.Close()
+		log.Printf("Lost connection to logger, reconnecting: %v", err)
+	}
+}
+
+func (c *logClient) dial() (*net.TCPConn, error) {
+	addr, err := net.ResolveTCPAddr("tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("address translation failed: %w", err)
+	}
+	return net.DialTCP("tcp", nil, addr)
+}
+
+// send sends `pending`, if it is not nil, and then the queued events over `conn` until
+// the connection fails. It returns the event that couldn't be sent, if any.
+func (c *logClient) send(conn *net.TCPConn, pending *event) (*event, error) {
+	// The logger never sends anything, so a read only returns when the connection is
+	// closed. Without it, a dead logger would only be noticed when writing fails, which
+	// is not until the event after the first one that is lost.
+	closed := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(closed)
+	}()
+
+	for {
+		if pending == nil {
+			select {
+			case e := <-c.events:
+				pending = &e
+			case <-closed:
+				return nil, errors.New("connection closed by the logger")
+			}
+		}
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := writeFrame(conn, *pending); err != nil {
+			return pending, err
+		}
+		c.sent.Add(1)
+		pending = nil
+	}
+}
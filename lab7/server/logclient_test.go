@@ -0,0 +1,87 @@
+package main
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it is true, and fails the test if that takes too long.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+	}
+}
+
+// acceptEvents accepts a connection from the log client on l, and reads events from
+// it, which must be for the clients in want, in order.
+func acceptEvents(t *testing.T, l net.Listener, want ...string) net.Conn {
+	t.Helper()
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for _, client := range want {
+		var e event
+		if err := readFrame(conn, &e); err != nil {
+			t.Fatalf("reading the event for client %s: %v", client, err)
+		}
+		if e.Client != client {
+			t.Errorf("received the event for client %s, expected %s", e.Client, client)
+		}
+	}
+	return conn
+}
+
+func TestLogClientReconnect(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	// the logger is down, so the events are buffered until the buffer is full
+	c := newLogClient(addr, 3)
+	for i := 0; i < 5; i++ {
+		c.log(event{Version: protocolVersion, Client: strconv.Itoa(i)})
+	}
+	if n := c.dropped.Load(); n != 2 {
+		t.Errorf("%d events dropped with a full buffer, expected 2", n)
+	}
+
+	// the buffered events are sent once the logger is up
+	if l, err = net.Listen("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	conn := acceptEvents(t, l, "0", "1", "2")
+	waitFor(t, "3 events are sent", func() bool { return c.sent.Load() == 3 })
+	if n := c.reconnects.Load(); n != 0 {
+		t.Errorf("%d reconnects counted for the first connection, expected 0", n)
+	}
+
+	// the logger dies and restarts, and the events logged meanwhile are sent after
+	// the client has reconnected
+	conn.Close()
+	l.Close()
+	waitFor(t, "the client notices that the logger is down", func() bool { return !c.connected.Load() })
+	c.log(event{Version: protocolVersion, Client: "5"})
+	c.log(event{Version: protocolVersion, Client: "6"})
+	if l, err = net.Listen("tcp", addr); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	conn = acceptEvents(t, l, "5", "6")
+	defer conn.Close()
+	if n := c.reconnects.Load(); n != 1 {
+		t.Errorf("%d reconnects counted after the logger restarted, expected 1", n)
+	}
+	if n := c.dropped.Load(); n != 2 {
+		t.Errorf("%d events dropped in total, expected 2", n)
+	}
+}
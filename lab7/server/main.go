@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -9,13 +8,27 @@ import (
 	"log"
 	"math/rand"
 	"net"
+	"runtime"
 	"strings"
+	"sync/atomic"
+	"time"
 	"unicode"
 )
 
 var (
 	hostName   = flag.String("addr", ":61111", "address:port to listen for incoming UDP messages.")
-	loggerAddr = flag.String("logaddr", "192.168.0.20:61112", "address:port of the logger.")
+	loggerAddr = flag.String("logaddr", "192.168.0.20:61112", "address:port of the logger. If empty, the logger is not used.")
+	logBuffer  = flag.Int("logbuffer", 10000, "Number of events that are kept while the logger can't be reached. Further events are dropped.")
+	interval   = flag.Uint("t", 10, "How often (in seconds) the server prints its metrics and those of its connection to the logger.")
+
+	workers = flag.Int("workers", 2*runtime.NumCPU(), "Number of goroutines that handle messages.")
+	queue   = flag.Int("queue", 1000, "Number of messages that can wait for a worker. Further messages are rejected.")
+	rate    = flag.Float64("rate", 100, "New messages per second that are accepted from each client, on average. Further messages are rejected; retransmissions are not counted.")
+	burst   = flag.Float64("burst", 100, "Messages that are accepted from a client in a burst, above -rate.")
+
+	loss         = flag.Float64("loss", 0, "Probability of dropping each response, to simulate a lossy network.")
+	reorder      = flag.Float64("reorder", 0, "Probability of delaying each response by -reorderdelay, so that later responses overtake it.")
+	reorderDelay = flag.Uint("reorderdelay", 1000, "Delay (in milliseconds) of the responses that are reordered.")
 )
 
 // setupListener sets up a UDP listener at `hostName`
@@ -35,15 +48,70 @@ func setupListener() (*net.UDPConn, error) {
 	return conn, nil
 }
 
-// listen continuously receives messages from the listener, processes the
-// message, and sends it back to the sender.
-func listen(conn *net.UDPConn, logger *net.TCPConn) error {
-	defer conn.Close()
+// server holds what is shared by the goroutines that handle messages
+type server struct {
+	conn     *net.UDPConn
+	logger   *logClient // nil if the logger is not used
+	sessions *sessions
+	limiter  *limiter
+	jobs     chan job   // messages waiting for a worker
+	network  unreliable // the simulated network that responses are sent through
+
+	// metrics
+	received     atomic.Int64 // valid messages, including rejected ones
+	handled      atomic.Int64
+	retransmits  atomic.Int64 // retransmitted messages, which are not handled again
+	rejectedRate atomic.Int64 // messages rejected because their client sent too many
+	rejectedBusy atomic.Int64 // messages rejected because the queue was full
+}
+
+// job is a message waiting to be handled by a worker
+type job struct {
+	h        header
+	seq      []byte
+	addr     *net.UDPAddr
+	received time.Time
+}
+
+func newServer(conn *net.UDPConn, logger *logClient) *server {
+	return &server{
+		conn:     conn,
+		logger:   logger,
+		sessions: newSessions(),
+		limiter:  newLimiter(*rate, *burst),
+		jobs:     make(chan job, *queue),
+		network:  unreliable{*loss, *reorder, time.Duration(*reorderDelay) * time.Millisecond},
+	}
+}
+
+// String returns the metrics of the server
+func (s *server) String() string {
+	return fmt.Sprintf("received: %d, handled: %d, retransmits: %d, queued: %d, rejected (rate): %d, rejected (busy): %d",
+		s.received.Load(), s.handled.Load(), s.retransmits.Load(), len(s.jobs), s.rejectedRate.Load(), s.rejectedBusy.Load())
+}
+
+// listen continuously receives messages from the listener, and queues them
+// for `workers` goroutines that process the message, and send it back to the
+// sender. A retransmitted message is not processed again; the response to the
+// original is sent back instead. Messages from a client that sends more than
+// its rate, or that arrive when the queue is full, are dropped; the client
+// retransmits them later.
+func (s *server) listen() error {
+	defer s.conn.Close()
+
+	for i := 0; i < *workers; i++ {
+		go func() {
+			for j := range s.jobs {
+				s.handleAndRespond(j)
+			}
+		}()
+	}
 
-	log.Printf("Starting to listen at '%s'...", conn.LocalAddr())
+	log.Printf("Starting to listen at '%s'...", s.conn.LocalAddr())
+	b := make([]byte, maxDatagram)
 	for {
-		b := make([]byte, 1024)
-		n, addr, err := conn.ReadFromUDP(b)
+		n, addr, err := s.conn.ReadFromUDP(b)
+		received := time.Now()
 		if err != nil {
 			if errors.Is(err, io.EOF) {
 				return fmt.Errorf("connection closed: %v", err)
@@ -51,20 +119,63 @@ func listen(conn *net.UDPConn, logger *net.TCPConn) error {
 			log.Printf("failed reading from %v: %v", addr, err)
 			continue
 		}
+		h, seq, err := parseDatagram(b[:n])
+		if err != nil {
+			log.Printf("Ignoring message from %v: %v", addr, err)
+			continue
+		}
+		s.received.Add(1)
 
-		// if logger is not present, the server prints some information itself
-		if logger != nil {
-			go writeToLog(logger, addr.String())
-		} else {
-			log.Printf("Processing message from '%v'.", addr)
+		kind, response, ok := s.admit(addr.String(), h, received)
+		switch {
+		case !ok || kind == staleRequest:
+			continue
+		case kind == retransmit:
+			if response != nil {
+				s.respond(addr, response)
+			}
+			continue
 		}
-		go handleAndRespond(b[:n], conn, addr)
 
+		select {
+		case s.jobs <- job{h, append([]byte(nil), seq...), addr, received}: // b is reused
+		default:
+			s.sessions.forget(addr.String(), h)
+			s.rejectedBusy.Add(1)
+		}
 	}
 }
 
-// handleAndRespond converts `seq` and sends the result back to `addr` over `conn`
-func handleAndRespond(seq []byte, conn *net.UDPConn, addr *net.UDPAddr) {
+// admit classifies the request with header `h` from `addr`, received at `now`,
+// and returns whether it is accepted. Only new requests are charged to the
+// client's rate limit, so that retransmissions after packet loss are not
+// rejected. A rejected request is forgotten, and handled as new if it is
+// retransmitted.
+func (s *server) admit(addr string, h header, now time.Time) (request, []byte, bool) {
+	kind, response := s.sessions.check(addr, h, now)
+	switch kind {
+	case retransmit:
+		s.retransmits.Add(1)
+	case newRequest:
+		if !s.limiter.allow(addr, now) {
+			s.sessions.forget(addr, h)
+			s.rejectedRate.Add(1)
+			return kind, nil, false
+		}
+	}
+	return kind, response, true
+}
+
+// handleAndRespond converts the message of `j`, sends the result back to its
+// sender, and messages the log about it
+func (s *server) handleAndRespond(j job) {
+	h, seq, addr, received := j.h, j.seq, j.addr, j.received
+
+	// if logger is not present, the server prints some information itself
+	if s.logger == nil {
+		log.Printf("Processing message from '%v'.", addr)
+	}
+
 	// randomly set the case of each letter
 	res := strings.Map(func(r rune) rune {
 		if unicode.IsLetter(r) {
@@ -75,45 +186,46 @@ func handleAndRespond(seq []byte, conn *net.UDPConn, addr *net.UDPAddr) {
 		return r
 	}, string(seq))
 
-	_, err := conn.WriteToUDP([]byte(res), addr)
-	if err != nil {
-		log.Printf("failed writing to %v: %v", addr, err)
+	response := h.datagram([]byte(res))
+	s.sessions.respond(addr.String(), h, response)
+	s.respond(addr, response)
+	s.handled.Add(1)
+
+	if s.logger != nil {
+		s.logger.log(event{
+			Version:  protocolVersion,
+			Client:   addr.String(),
+			Time:     received,
+			BytesIn:  len(seq),
+			BytesOut: len(res),
+			Latency:  time.Since(received),
+		})
 	}
 }
 
-// setupLogger tries to setup a TCP connection to the logger
-func setupLogger() (*net.TCPConn, error) {
-	addr, err := net.ResolveTCPAddr("tcp", *loggerAddr)
+// respond sends `response` to `addr` through the simulated network
+func (s *server) respond(addr *net.UDPAddr, response []byte) {
+	err := s.network.send(response, func(b []byte) error {
+		_, err := s.conn.WriteToUDP(b, addr)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("address translation failed: %w", err)
-	}
-
-	conn, err := net.DialTCP("tcp", nil, addr)
-	if err != nil {
-		return nil, fmt.Errorf("failed connecting to logger: %w", err)
+		log.Printf("failed writing to %v: %v", addr, err)
 	}
-	log.Printf("Connected to logger at %v", conn.RemoteAddr())
-
-	return conn, nil
 }
 
-type msg struct {
-	Client string
-}
-
-// writeToLog messages the log about an event from `addr`
-func writeToLog(logger *net.TCPConn, addr string) {
-	// parse to JSON
-	msg := msg{addr}
-	m, err := json.Marshal(msg)
-	if err != nil {
-		log.Printf("Failed to marshal message. Error: %v", err)
-		return
-	}
-
-	logger.Write(m)
-	if err != nil {
-		log.Printf("Failed writing to the logger. Error: %v", err)
+// printMetrics prints the metrics of the server, and of its connection to the
+// logger, at a regular interval. It also forgets idle clients, so that the
+// memory used for them doesn't grow without bound.
+func (s *server) printMetrics() {
+	t := time.Tick(time.Duration(*interval) * time.Second)
+	for now := range t {
+		s.limiter.prune(now)
+		s.sessions.prune(now.Add(-idleClient))
+		log.Println("Server:", s)
+		if s.logger != nil {
+			log.Println("Logger connection:", s.logger)
+		}
 	}
 }
 
@@ -126,14 +238,17 @@ func main() {
 	}
 	defer conn.Close()
 
-	logger, err := setupLogger()
-	if err != nil {
-		log.Printf("Logger will not be used: %v", err)
+	// start connecting to the logger in the background
+	var logger *logClient
+	if *loggerAddr != "" {
+		logger = newLogClient(*loggerAddr, *logBuffer)
 	} else {
-		defer logger.Close()
+		log.Println("Logger will not be used.")
 	}
 
-	err = listen(conn, logger)
+	s := newServer(conn, logger)
+	go s.printMetrics()
+	err = s.listen()
 	if err != nil {
 		log.Printf("stopped processing due to error: %v", err)
 	}
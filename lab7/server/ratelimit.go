@@ -0,0 +1,58 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// bucket is a token bucket: it holds up to `burst` tokens, and is refilled with `rate`
+// tokens per second. Each request takes a token, and is rejected if there is none.
+type bucket struct {
+	tokens float64
+	last   time.Time // when tokens was last refilled
+}
+
+// limiter limits the rate of requests from each client, by address:port
+type limiter struct {
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	lock    sync.Mutex
+}
+
+func newLimiter(rate, burst float64) *limiter {
+	return &limiter{rate: rate, burst: burst, buckets: make(map[string]*bucket)}
+}
+
+// allow takes a token from the bucket of the client at `addr`, and returns whether
+// there was one. A new client starts with a full bucket.
+func (l *limiter) allow(addr string, now time.Time) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	b, ok := l.buckets[addr]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[addr] = b
+	}
+	b.tokens = min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// prune forgets the clients whose buckets have been refilled, since a new bucket is
+// the same as a full one
+func (l *limiter) prune(now time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	for addr, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, addr)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	start := time.Now()
+	l := newLimiter(2, 3)
+	steps := []struct {
+		after time.Duration // since start
+		want  []bool        // results of allow at that time
+	}{
+		{0, []bool{true, true, true, false}}, // a new client has a full bucket
+		{100 * time.Millisecond, []bool{false}},
+		{500 * time.Millisecond, []bool{true, false}}, // refilled with 2 per second
+		{1500 * time.Millisecond, []bool{true, true, false}},
+		{time.Hour, []bool{true, true, true, false}}, // refilled up to the burst
+	}
+	for _, step := range steps {
+		for i, want := range step.want {
+			if got := l.allow("a:1", start.Add(step.after)); got != want {
+				t.Errorf("allow() number %d after %v=%v, expected %v", i+1, step.after, got, want)
+			}
+		}
+	}
+	// clients have separate buckets
+	if !l.allow("b:1", start.Add(time.Hour)) {
+		t.Errorf("allow() of another client=false, expected true")
+	}
+}
+
+func TestLimiterPrune(t *testing.T) {
+	start := time.Now()
+	l := newLimiter(1, 2)
+	l.allow("a:1", start)
+	l.allow("b:1", start)
+	l.allow("b:1", start.Add(time.Second))
+	l.allow("b:1", start.Add(time.Second))
+
+	// a:1 is refilled after 1 second, b:1 after 3
+	l.prune(start.Add(2 * time.Second))
+	if _, ok := l.buckets["a:1"]; ok || len(l.buckets) != 1 {
+		t.Errorf("prune() kept %d buckets, expected only that of b:1", len(l.buckets))
+	}
+	l.prune(start.Add(3 * time.Second))
+	if len(l.buckets) != 0 {
+		t.Errorf("prune() kept %d full buckets", len(l.buckets))
+	}
+}
+
+func TestAdmitRetransmits(t *testing.T) {
+	start := time.Now()
+	s := &server{sessions: newSessions(), limiter: newLimiter(1, 2)}
+	const addr = "a:1"
+	steps := []struct {
+		name  string
+		h     header
+		after time.Duration
+		kind  request
+		ok    bool
+	}{
+		{"first", header{1, 1}, 0, newRequest, true},
+		{"second", header{1, 2}, 0, newRequest, true},
+		// retransmissions don't take tokens, however many there are
+		{"retransmit", header{1, 2}, 0, retransmit, true},
+		{"retransmit", header{1, 2}, 0, retransmit, true},
+		{"retransmit", header{1, 2}, 0, retransmit, true},
+		{"third", header{1, 3}, 0, newRequest, false},
+		// a rejected request is new when it is retransmitted
+		{"rejected retransmit", header{1, 3}, 100 * time.Millisecond, newRequest, false},
+		{"retransmit after refill", header{1, 3}, time.Second, newRequest, true},
+		{"retransmit of accepted", header{1, 3}, time.Second, retransmit, true},
+	}
+	for _, step := range steps {
+		kind, _, ok := s.admit(addr, step.h, start.Add(step.after))
+		if kind != step.kind || ok != step.ok {
+			t.Errorf("%s: admit(%v) after %v=%v, %v, expected %v, %v", step.name, step.h, step.after, kind, ok, step.kind, step.ok)
+		}
+	}
+	if got := s.rejectedRate.Load(); got != 2 {
+		t.Errorf("%d requests were rejected, expected 2", got)
+	}
+	if got := s.retransmits.Load(); got != 4 {
+		t.Errorf("%d retransmissions were counted, expected 4", got)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Every datagram between the client and the server starts with a header of two big
+// endian uint32s: the session, which the client picks at random when it starts, and the
+// sequence number of the request, which the response repeats. The rest of the datagram
+// is the payload. The client has the same definitions, since the two programs are built
+// separately.
+const (
+	headerSize  = 8
+	maxDatagram = 65507 // the largest UDP payload over IPv4
+)
+
+type header struct {
+	session uint32
+	seq     uint32
+}
+
+// datagram returns the header followed by payload.
+func (h header) datagram(payload []byte) []byte {
+	b := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(b[0:], h.session)
+	binary.BigEndian.PutUint32(b[4:], h.seq)
+	copy(b[headerSize:], payload)
+	return b
+}
+
+// parseDatagram splits b into its header and payload.
+func parseDatagram(b []byte) (header, []byte, error) {
+	if len(b) < headerSize {
+		return header{}, nil, fmt.Errorf("datagram of %d bytes is shorter than the header", len(b))
+	}
+	h := header{binary.BigEndian.Uint32(b[0:]), binary.BigEndian.Uint32(b[4:])}
+	return h, b[headerSize:], nil
+}
+
+// unreliable simulates a bad network for the datagrams that are sent through it, to
+// test the client's retransmissions on loopback.
+type unreliable struct {
+	loss    float64       // probability that a datagram is dropped
+	reorder float64       // probability that a datagram is delayed, so that later ones overtake it
+	delay   time.Duration // how long a reordered datagram is delayed
+}
+
+// send sends b with write, unless it is dropped or delayed.
+func (u unreliable) send(b []byte, write func([]byte) error) error {
+	if rand.Float64() < u.loss {
+		return nil
+	}
+	if rand.Float64() < u.reorder {
+		time.AfterFunc(u.delay, func() {
+			if err := write(b); err != nil {
+				log.Printf("failed writing delayed datagram: %v", err)
+			}
+		})
+		return nil
+	}
+	return write(b)
+}
+
+// request says how a received request relates to the earlier requests from its client.
+type request int
+
+const (
+	newRequest   request = iota // not seen before; it must be handled
+	retransmit                  // the latest request again; its response must be sent again
+	staleRequest                // older than the latest request; the client has given up on it
+)
+
+// idleClient is how long the server remembers a client after its latest request. The
+// client has given up retransmitting it long before.
+const idleClient = time.Minute
+
+// client is what the server remembers about the latest request from a client.
+type client struct {
+	latest   header
+	response []byte    // the response to latest, or nil while it is being handled
+	seen     time.Time // when the client sent a request last
+}
+
+// sessions recognizes retransmitted requests, so that each request is only handled,
+// and logged, once, and a retransmission gets the same response as the original.
+type sessions struct {
+	clients map[string]*client
+	lock    sync.Mutex
+}
+
+func newSessions() *sessions {
+	return &sessions{clients: make(map[string]*client)}
+}
+
+// check classifies the request with header h from the client at addr, received at
+// now. For a retransmission, it also returns the response to send again, or nil if the
+// original request is still being handled.
+func (s *sessions) check(addr string, h header, now time.Time) (request, []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	c, ok := s.clients[addr]
+	switch {
+	case !ok || c.latest.session != h.session || c.latest.seq < h.seq:
+		// a new client, a restarted client, or the next request
+		s.clients[addr] = &client{latest: h, seen: now}
+		return newRequest, nil
+	case c.latest.seq == h.seq:
+		c.seen = now
+		return retransmit, c.response
+	default:
+		return staleRequest, nil
+	}
+}
+
+// respond remembers the response to the request with header h from the client at addr,
+// unless a later request has arrived in the meantime.
+func (s *sessions) respond(addr string, h header, response []byte) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if c, ok := s.clients[addr]; ok && c.latest == h {
+		c.response = response
+	}
+}
+
+// forget forgets the request with header h from the client at addr, if it is still
+// the latest, so that its retransmission is handled as a new request. It is used when
+// the server is too busy to handle the request.
+func (s *sessions) forget(addr string, h header) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if c, ok := s.clients[addr]; ok && c.latest == h && c.response == nil {
+		delete(s.clients, addr)
+	}
+}
+
+// prune forgets the clients that haven't sent a request since before `t`, and the
+// responses they would get
+func (s *sessions) prune(t time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for addr, c := range s.clients {
+		if c.seen.Before(t) {
+			delete(s.clients, addr)
+		}
+	}
+}
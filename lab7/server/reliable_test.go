@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// declarations returns the source of the top level declarations in the Go file at path,
+// without their comments, by name. Methods are named by their receiver type and name.
+func declarations(t *testing.T, path string) map[string]string {
+	t.Helper()
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decls := make(map[string]string)
+	add := func(name string, node ast.Node) {
+		var b bytes.Buffer
+		if err := printer.Fprint(&b, fset, node); err != nil {
+			t.Fatal(err)
+		}
+		decls[name] = b.String()
+	}
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := decl.Name.Name
+			if decl.Recv != nil {
+				if recv, ok := decl.Recv.List[0].Type.(*ast.Ident); ok {
+					name = recv.Name + "." + name
+				}
+			}
+			add(name, decl)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					add(spec.Name.Name, spec)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						add(name.Name, spec)
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+func TestDatagramCopiesMatch(t *testing.T) {
+	// the client, the server and the load generator are built separately, so each has a
+	// copy of the definitions of the datagrams they exchange
+	server := declarations(t, "reliable.go")
+	client := declarations(t, "../client/reliable.go")
+	for _, name := range []string{"headerSize", "maxDatagram", "header", "header.datagram", "parseDatagram", "unreliable", "unreliable.send"} {
+		if server[name] == "" || server[name] != client[name] {
+			t.Errorf("%s differs between server/reliable.go and client/reliable.go:\n%s\n%s", name, server[name], client[name])
+		}
+	}
+	if loadgen := declarations(t, "../loadgen/main.go"); loadgen["headerSize"] != server["headerSize"] {
+		t.Errorf("headerSize differs between server/reliable.go and loadgen/main.go:\n%s\n%s", server["headerSize"], loadgen["headerSize"])
+	}
+}
+
+func TestDatagram(t *testing.T) {
+	h := header{0xdeadbeef, 42}
+	got, payload, err := parseDatagram(h.datagram([]byte("Lorem ipsum")))
+	if err != nil || got != h || string(payload) != "Lorem ipsum" {
+		t.Errorf("parseDatagram(datagram())=%v, %q, %v, expected %v, %q", got, payload, err, h, "Lorem ipsum")
+	}
+	if _, _, err := parseDatagram(make([]byte, headerSize-1)); err == nil {
+		t.Errorf("parseDatagram() of a datagram shorter than the header succeeded")
+	}
+}
+
+func TestSessions(t *testing.T) {
+	start := time.Now()
+	const addr = "192.168.1.2:45678"
+	response := []byte("LoREm IpsUm")
+
+	s := newSessions()
+	steps := []struct {
+		name     string
+		h        header
+		want     request
+		response []byte // the response returned by check
+		respond  bool   // whether the request is answered after check
+	}{
+		{"first request", header{1, 1}, newRequest, nil, true},
+		{"next request", header{1, 2}, newRequest, nil, false},
+		{"retransmit while handled", header{1, 2}, retransmit, nil, true},
+		{"retransmit when answered", header{1, 2}, retransmit, response, false},
+		{"late first request", header{1, 1}, staleRequest, nil, false},
+		{"skipped requests", header{1, 5}, newRequest, nil, false},
+		{"stale after skip", header{1, 4}, staleRequest, nil, false},
+		{"restarted client", header{2, 1}, newRequest, nil, false},
+		{"old session", header{1, 6}, newRequest, nil, false},
+	}
+	for i, step := range steps {
+		kind, res := s.check(addr, step.h, start.Add(time.Duration(i)*time.Second))
+		if kind != step.want || !bytes.Equal(res, step.response) {
+			t.Errorf("%s: check(%v)=%v, %q, expected %v, %q", step.name, step.h, kind, res, step.want, step.response)
+		}
+		if step.respond {
+			s.respond(addr, step.h, response)
+		}
+	}
+
+	// a response to a request that is no longer the latest is not remembered
+	s.respond(addr, header{1, 5}, []byte("late"))
+	if _, res := s.check(addr, header{1, 6}, start); res != nil {
+		t.Errorf("check() returned the response %q to an earlier request", res)
+	}
+
+	// a forgotten request is new when it is retransmitted, but not once it is answered
+	s.forget(addr, header{1, 6})
+	if kind, _ := s.check(addr, header{1, 6}, start); kind != newRequest {
+		t.Errorf("check() of a forgotten request=%v, expected %v", kind, newRequest)
+	}
+	s.respond(addr, header{1, 6}, response)
+	s.forget(addr, header{1, 6})
+	if kind, res := s.check(addr, header{1, 6}, start); kind != retransmit || !bytes.Equal(res, response) {
+		t.Errorf("check() of an answered request after forget()=%v, %q, expected %v, %q", kind, res, retransmit, response)
+	}
+}
+
+func TestSessionsPrune(t *testing.T) {
+	start := time.Now()
+	s := newSessions()
+	s.check("a:1", header{1, 1}, start)
+	s.check("b:1", header{1, 1}, start)
+	s.check("b:1", header{1, 1}, start.Add(2*idleClient)) // retransmitted later
+	s.check("c:1", header{1, 1}, start.Add(2*idleClient))
+
+	s.prune(start.Add(idleClient))
+	if len(s.clients) != 2 || s.clients["a:1"] != nil {
+		t.Errorf("prune() kept the clients %v, expected b:1 and c:1", s.clients)
+	}
+	if kind, _ := s.check("a:1", header{1, 1}, start.Add(2*idleClient)); kind != newRequest {
+		t.Errorf("check() of a pruned client=%v, expected %v", kind, newRequest)
+	}
+}
+
+func TestUnreliable(t *testing.T) {
+	tests := []struct {
+		name       string
+		net        unreliable
+		now, later int64 // datagrams written at once, and after the delay
+	}{
+		{"reliable", unreliable{}, 100, 100},
+		{"lost", unreliable{loss: 1}, 0, 0},
+		{"reordered", unreliable{reorder: 1, delay: 20 * time.Millisecond}, 0, 100},
+	}
+	for _, test := range tests {
+		var written atomic.Int64
+		write := func([]byte) error {
+			written.Add(1)
+			return nil
+		}
+		for i := 0; i < 100; i++ {
+			if err := test.net.send([]byte("x"), write); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n := written.Load(); n != test.now {
+			t.Errorf("%s: %d datagrams written at once, expected %d", test.name, n, test.now)
+		}
+		time.Sleep(100 * time.Millisecond)
+		if n := written.Load(); n != test.later {
+			t.Errorf("%s: %d datagrams written after the delay, expected %d", test.name, n, test.later)
+		}
+	}
+
+	// a fraction of about loss of the datagrams is dropped
+	var written int
+	lossy := unreliable{loss: 0.3}
+	for i := 0; i < 10000; i++ {
+		lossy.send(nil, func([]byte) error { written++; return nil })
+	}
+	if written < 6500 || written > 7500 {
+		t.Errorf("%d of 10000 datagrams were written with a loss of 0.3", written)
+	}
+}